@@ -15,8 +15,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/bmc-toolbox/dora/connectors"
 	"github.com/spf13/cobra"
@@ -38,8 +40,6 @@ usage: dora collect
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		configItems := []string{
-			"bmc_pass",
-			"bmc_user",
 			"collector.concurrency",
 			"collector.dump_invalid_payloads",
 			"collector.dump_invalid_payload_path",
@@ -64,10 +64,18 @@ usage: dora collect
 			scanType = "cli-with-force"
 		}
 
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		if timeout := time.Duration(viper.GetInt("collector.run_timeout")) * time.Second; timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
 		if len(args) == 0 {
-			connectors.DataCollection([]string{"all"}, scanType)
+			connectors.DataCollection(ctx, []string{"all"}, scanType)
 		} else {
-			connectors.DataCollection(args, scanType)
+			connectors.DataCollection(ctx, args, scanType)
 		}
 	},
 }
@@ -75,4 +83,6 @@ usage: dora collect
 func init() {
 	RootCmd.AddCommand(collectCmd)
 	collectCmd.Flags().BoolVarP(&force, "force", "f", false, "force blade scan")
+	collectCmd.Flags().IntP("timeout", "t", 0, "stop the collection after the given number of seconds, hosts still running are reported as timeout")
+	viper.BindPFlag("collector.run_timeout", collectCmd.Flags().Lookup("timeout"))
 }
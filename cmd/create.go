@@ -34,10 +34,29 @@ url: http://service.example.com/v1
 database_type: postgres
 database_options: host=0.0.0.0 user=postgres port=32768 dbname=postgres password=mysecretpassword
 database_max_connections: 10
+database_auto_migrate: false
 
 api:
   http_server_port: 8000
 
+retention:
+  # hours between the purges run by the server, 0 disables them
+  interval: 0
+  archive_path: /var/lib/dora/archive
+  # rows not updated for max_age hours are purged, 0 keeps them forever
+  scanned_ports:
+    max_age: 720
+    archive: false
+  chassis:
+    max_age: 0
+    archive: true
+  blades:
+    max_age: 0
+    archive: true
+  discretes:
+    max_age: 2160
+    archive: true
+
 notification:
   enabled: false
   script: /usr/local/bin/notify-on-dora-change
@@ -54,6 +73,43 @@ metrics:
 
 collector:
   concurrency: 60
+  # seconds we wait for a single host and for the whole cli run, 0 means no limit
+  host_timeout: 300
+  run_timeout: 0
+
+  # raw snapshots of bmcs returning data we can't use, max_age is in hours and max_size in megabytes
+  dump_invalid_payloads: false
+  dump_invalid_payload_path: /tmp/dora/dumps
+  dump_max_files: 1000
+  dump_max_age: 168
+  dump_max_size: 100
+
+  # collect the bmcs bmclib doesn't recognise through the standard redfish api
+  redfish:
+    enabled: true
+  # collect the bmcs only answering ipmi on udp 623, reading their fru, sensors and power state
+  ipmi:
+    enabled: true
+
+  # history of the temperature, power and fan speed readings, ages are in hours and 0 for max_age keeps it forever.
+  # Readings older than raw_max_age are averaged into one reading per resolution hours
+  readings:
+    enabled: true
+    raw_max_age: 168
+    resolution: 1
+    max_age: 8760
+
+  # components a collection stops reporting are kept with removed_at set, purge_after is in hours and 0 keeps them forever
+  removed_components:
+    purge_after: 0
+
+  # refuse removals that look like a broken collection, eg: a firmware hiccup reporting a chassis without blades.
+  # Dropping to no components at all, or losing more than max_percent of them when there are at least min_components,
+  # only goes through when the next collection reports the same
+  removal_guard:
+    enabled: true
+    max_percent: 50
+    min_components: 4
 
   worker:
     enabled: false
@@ -76,12 +132,119 @@ collector:
       username: Priest
       password: Wololo
 
+  # credentials are tried in the order returned by the providers, the one that worked last time for a bmc goes first
+  credentials:
+    providers:
+      - static
+    map:
+      - name: ams4-dell
+        site: ams4
+        cidr: 10.0.0.0/16
+        vendor: dell
+        username: Priest
+        password: Wololo
+    file:
+      path: /etc/bmc-toolbox/credentials.enc
+      key_file: /etc/bmc-toolbox/credentials.key
+    command:
+      path: /usr/local/bin/dora-credentials
+      timeout: 10
+
 scanner:
   scanned_by: anomalia
+  # the subnets scanned at the same time, and the hosts of each of them. The ports of a host
+  # are probed together
   concurrency: 100
+  host_concurrency: 16
+  # the scan results are written by batches of batch_size rows, what is left once a subnet is
+  # scanned is written then
+  batch_size: 500
   kea_config: /etc/kea/kea-dhcp4.conf
+  # where the subnets to scan come from: kea, file and dhcpd, as a list or separated by commas. A
+  # subnet given by several sources is scanned as the first one gives it
   subnet_source: kea
+  # the static list of the file source, a csv of cidr,site[,profile] or a yaml, json or toml file
+  # with a subnets list of cidr, site and profile
+  subnet_file: /etc/bmc-toolbox/dora-subnets.yaml
+  # the isc dhcpd config of the dhcpd source, its subnets are selected by dhcpd_rules like the kea
+  # ones by kea_rules, only on options
+  dhcpd_config: /etc/dhcp/dhcpd.conf
+  dhcpd_rules:
+    - option: domain-name
+      regex: ^(.+)\.bmc\.example\.com$
+  dhcpd_profile_option: dora-scan-profile
+  # the ipv6 subnets are only scanned on their candidate hosts: their kea reservations, the
+  # addresses leased in kea_leases6, their hosts in subnet_file and the addresses of
+  # ipv6_host_files, lists of addresses or the output of ip -6 neigh show
+  kea_leases6: /var/lib/kea/kea-leases6.csv
+  ipv6_host_files:
+    - /var/lib/dora/neighbors6
   kea_domain_name_suffix: bmc.example.com
+  # the kea subnets to scan, the first rule selecting a subnet gives its site. The value of the option
+  # or user context key must match regex and the site is its first group. Without rules the subnets
+  # with a domain-name ending with kea_domain_name_suffix are scanned
+  kea_rules:
+    - user_context: dora-site
+    - option: domain-name
+      regex: ^(.+)\.bmc\.example\.com$
+  # publish the bmcs found open for the first time to the collectors
+  collect_new_ports: false
+  # the ports probed by profile, a subnet uses the profile named by its kea option kea_profile_option,
+  # then the one of its site in site_profiles, then default. The service of a port (ssh, web or ipmi)
+  # sets the reachability flags of the assets and the collectors go through the web and ipmi ones.
+  # timeout is in seconds
+  profiles:
+    default:
+      - protocol: tcp
+        port: 22
+        timeout: 1
+        service: ssh
+      - protocol: tcp
+        port: 443
+        timeout: 1
+        service: web
+      - protocol: ipmi
+        port: 623
+        timeout: 1
+        service: ipmi
+    kvm:
+      - protocol: tcp
+        port: 443
+        timeout: 1
+        service: web
+      - protocol: ipmi
+        port: 623
+        timeout: 1
+        service: ipmi
+      - protocol: tcp
+        port: 5900
+        timeout: 2
+  site_profiles:
+    ams4: kvm
+  kea_profile_option: dora-scan-profile
+
+# fake bmcs started by dora simulate, they accept bmc_user and bmc_pass
+simulator:
+  devices:
+    - address: 127.0.0.10
+      type: ilo
+      serial: CZ00000010
+      nics: 4
+      disks: 2
+      psus: 2
+    - address: 127.0.0.20
+      type: c7000
+      serial: CZ00000020
+      psus: 6
+      fans: 10
+      blades:
+        - address: 127.0.0.21
+          disks: 2
+        - address: 127.0.0.22
+          disks: 2
+    - address: 127.0.0.30
+      type: ipmi
+      serial: S349007109
 `)
 
 // createCmd represents the create command
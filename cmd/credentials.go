@@ -0,0 +1,100 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	credentialsInput  string
+	credentialsOutput string
+	credentialsKey    string
+)
+
+// credentialsCmd represents the credentials command
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manages the encrypted credentials file",
+	Long: `Manages the encrypted credentials file used by the file credential provider.
+
+usage: dora credentials encrypt -i credentials.json -o credentials.enc
+`,
+}
+
+// credentialsEncryptCmd represents the credentials encrypt command
+var credentialsEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypts a json list of credentials",
+	Long: `Encrypts a json list of credentials to be used by the file credential provider.
+The key file must contain 32 bytes encoded as hex, eg: openssl rand -hex 32
+
+Each credential is valid for the given site, cidr and vendor, empty fields match everything:
+[{"name": "ams4-dell", "username": "root", "password": "secret", "site": "ams4", "cidr": "10.0.0.0/16", "vendor": "dell"}]
+
+usage: dora credentials encrypt -i credentials.json -o credentials.enc -k /etc/bmc-toolbox/credentials.key
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if credentialsKey == "" {
+			credentialsKey = viper.GetString("collector.credentials.file.key_file")
+		}
+
+		key, err := credentials.ReadKey(credentialsKey)
+		if err != nil {
+			fmt.Printf("Failed to read the key %s: %s\n", credentialsKey, err)
+			os.Exit(1)
+		}
+
+		plaintext, err := ioutil.ReadFile(credentialsInput)
+		if err != nil {
+			fmt.Printf("Failed to read %s: %s\n", credentialsInput, err)
+			os.Exit(1)
+		}
+
+		var rules []credentials.Rule
+		if err = json.Unmarshal(plaintext, &rules); err != nil {
+			fmt.Printf("Invalid credentials in %s: %s\n", credentialsInput, err)
+			os.Exit(1)
+		}
+
+		payload, err := credentials.Encrypt(key, plaintext)
+		if err != nil {
+			fmt.Printf("Failed to encrypt %s: %s\n", credentialsInput, err)
+			os.Exit(1)
+		}
+
+		if err = ioutil.WriteFile(credentialsOutput, payload, 0600); err != nil {
+			fmt.Printf("Failed to write %s: %s\n", credentialsOutput, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(credentialsCmd)
+	credentialsCmd.AddCommand(credentialsEncryptCmd)
+	credentialsEncryptCmd.Flags().StringVarP(&credentialsInput, "input", "i", "", "json file with the credentials in plain text")
+	credentialsEncryptCmd.Flags().StringVarP(&credentialsOutput, "output", "o", "", "where to write the encrypted credentials")
+	credentialsEncryptCmd.Flags().StringVarP(&credentialsKey, "key-file", "k", "", "file with the hex encoded key, defaults to collector.credentials.file.key_file")
+	credentialsEncryptCmd.MarkFlagRequired("input")
+	credentialsEncryptCmd.MarkFlagRequired("output")
+}
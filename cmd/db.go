@@ -0,0 +1,112 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the dora database",
+	Long: `Manage the dora database, at this point it's only possible
+to migrate its schema.
+
+usage: dora db migrate [up|down|status]
+`,
+}
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [up|down|status]",
+	Short: "Migrate the database schema",
+	Long: `Migrate the database schema to the version this binary expects.
+The server and the workers refuse to start until it's done.
+
+  up      applies all the pending migrations (default)
+  down    reverts the last applied migration
+  status  lists the migrations and when they were applied
+
+usage: dora db migrate
+       dora db migrate status
+`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"up", "down", "status"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if !viper.IsSet("database_type") || !viper.IsSet("database_options") {
+			fmt.Println("Parameters database_type and database_options are required in the config file")
+			os.Exit(1)
+		}
+
+		action := "up"
+		if len(args) == 1 {
+			action = args[0]
+		}
+
+		db := storage.Connect(viper.GetString("database_options"))
+		defer db.Close()
+
+		switch action {
+		case "up":
+			applied, err := storage.MigrateUp(db)
+			if err != nil {
+				fmt.Printf("Failed to migrate the database: %s\n", err)
+				os.Exit(1)
+			}
+			for _, m := range applied {
+				fmt.Printf("applied %s\n", m)
+			}
+			fmt.Printf("schema at version %d\n", storage.LatestVersion())
+		case "down":
+			reverted, err := storage.MigrateDown(db)
+			if err != nil {
+				fmt.Printf("Failed to revert the last migration: %s\n", err)
+				os.Exit(1)
+			}
+			if reverted == "" {
+				fmt.Println("nothing to revert")
+				return
+			}
+			fmt.Printf("reverted %s\n", reverted)
+		case "status":
+			status, err := storage.Migrations(db)
+			if err != nil {
+				fmt.Printf("Failed to read the migrations: %s\n", err)
+				os.Exit(1)
+			}
+			for _, m := range status {
+				applied := "pending"
+				if m.Applied {
+					applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("%4d %-30s %s\n", m.Version, m.Name, applied)
+			}
+		default:
+			fmt.Printf("Unknown action %s, use up, down or status\n", action)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(migrateCmd)
+}
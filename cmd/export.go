@@ -0,0 +1,78 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+)
+
+var exportOutput string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the whole inventory as json lines",
+	Long: `Export the whole inventory as json lines, one asset with its
+components or one scanned port, collection attempt, reading or change per
+line. The export doesn't depend on the database, dora import restores it
+into any database dora supports.
+
+usage: dora export > dora.ndjson
+       dora export -o dora.ndjson
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var w io.Writer = os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			f, err := os.Create(exportOutput)
+			if err != nil {
+				fmt.Printf("Failed to create %s: %s\n", exportOutput, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		counts, err := storage.Export(storage.InitDB(), w)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export the inventory: %s\n", err)
+			os.Exit(1)
+		}
+		printCounts("exported", counts)
+	},
+}
+
+// printCounts writes how many records of each type were handled to stderr, stdout may be the export itself
+func printCounts(action string, counts map[string]int) {
+	types := make([]string, 0, len(counts))
+	for recordType := range counts {
+		types = append(types, recordType)
+	}
+	sort.Strings(types)
+
+	for _, recordType := range types {
+		fmt.Fprintf(os.Stderr, "%s %d %s records\n", action, counts[recordType], recordType)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write the export to, stdout by default")
+}
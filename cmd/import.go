@@ -0,0 +1,61 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import an inventory exported by dora export",
+	Long: `Import an inventory exported by dora export, the records replace
+the assets and rows with the same keys and the rest of the database is
+left alone. The database must be migrated to the current schema first.
+
+usage: dora import dora.ndjson
+       dora import < dora.ndjson
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var r io.Reader = os.Stdin
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Printf("Failed to open %s: %s\n", args[0], err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		counts, err := storage.Import(storage.InitDB(), r)
+		printCounts("imported", counts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import the inventory: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+}
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/spf13/cobra"
@@ -38,7 +39,17 @@ usage: dora scan list
 			args = append(args, "all")
 		}
 
-		for _, subnet := range scanner.ListSubnets(args, viper.GetStringSlice("site")) {
+		subnets, err := scanner.ListSubnets(args, viper.GetStringSlice("site"))
+		if err != nil {
+			fmt.Printf("Unable to load the subnets: %s\n", err)
+			os.Exit(1)
+		}
+
+		for _, subnet := range subnets {
+			if len(subnet.Hosts) > 0 {
+				fmt.Printf("subnet:%s site:%s hosts:%d\n", subnet.CIDR, subnet.Site, len(subnet.Hosts))
+				continue
+			}
 			fmt.Printf("subnet:%s site:%s\n", subnet.CIDR, subnet.Site)
 		}
 	},
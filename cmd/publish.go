@@ -20,6 +20,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/bmc-toolbox/dora/connectors"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/bmc-toolbox/dora/storage"
@@ -70,7 +71,10 @@ usage: dora publish 192.168.0.0/24 -q dora -s scan
 		switch subject {
 		case "scan":
 			subject = "dora::scan"
-			subnets := scanner.LoadSubnets(viper.GetString("scanner.subnet_source"), args, viper.GetStringSlice("site"))
+			subnets, err := scanner.LoadSubnets(args, viper.GetStringSlice("site"))
+			if err != nil {
+				log.WithFields(log.Fields{"operation": "loading subnets"}).Fatal(err)
+			}
 			for _, subnet := range subnets {
 				s, err := json.Marshal(subnet)
 				if err != nil {
@@ -93,14 +97,22 @@ usage: dora publish 192.168.0.0/24 -q dora -s scan
 		case "collect":
 			subject = "dora::collect"
 			if args[0] == "all" {
-				db := storage.InitDB()
+				collectablePorts, err := connectors.Collectable(storage.InitDB())
+				if err != nil {
+					log.WithFields(log.Fields{"operation": "loading scan profiles"}).Fatal(err)
+				}
+
 				var hosts []model.ScannedPort
-				if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open'").Find(&hosts).Error; err != nil {
+				if err := collectablePorts.Find(&hosts).Error; err != nil {
 					log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "retrieving scanned hosts", "ip": "all"}).Error(err)
 				} else {
 					args = []string{}
+					seen := make(map[string]bool)
 					for _, host := range hosts {
-						args = append(args, host.IP)
+						if !seen[host.IP] {
+							seen[host.IP] = true
+							args = append(args, host.IP)
+						}
 					}
 				}
 			}
@@ -0,0 +1,102 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bmc-toolbox/dora/internal/retention"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+)
+
+var dryRun bool
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge [scanned_ports|chassis|blades|discretes]",
+	Short: "Purge the assets and scan results that weren't updated for too long",
+	Long: `Purge the scanned ports, chassis, blades and discretes that
+weren't updated for longer than retention.<resource>.max_age hours, assets
+are purged with all their components. With retention.<resource>.archive
+they are written to retention.archive_path first, dora import brings them
+back. Use --dry-run to see how many would be purged by site and vendor.
+
+usage: dora purge --dry-run
+       dora purge
+       dora purge scanned_ports
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, resource := range args {
+			known := false
+			for _, r := range storage.RetentionResources {
+				known = known || r == resource
+			}
+			if !known {
+				fmt.Printf("Unknown resource %s\n", resource)
+				os.Exit(1)
+			}
+		}
+
+		policies := retention.Policies()
+		if len(args) > 0 {
+			selected := make([]retention.Policy, 0)
+			for _, policy := range policies {
+				for _, resource := range args {
+					if policy.Resource == resource {
+						selected = append(selected, policy)
+					}
+				}
+			}
+			policies = selected
+		}
+
+		if len(policies) == 0 {
+			fmt.Println("No retention policy configured, set retention.<resource>.max_age")
+			os.Exit(1)
+		}
+
+		results, err := retention.Run(storage.InitDB(), policies, dryRun)
+		for _, result := range results {
+			total := 0
+			fmt.Printf("%s not updated since %s:\n", result.Resource, result.Before.Format("2006-01-02 15:04:05"))
+			for _, stale := range result.Stale {
+				fmt.Printf("  site:%s vendor:%s count:%d\n", stale.Site, stale.Vendor, stale.Total)
+				total += stale.Total
+			}
+
+			if dryRun {
+				fmt.Printf("  %d would be purged\n", total)
+				continue
+			}
+
+			fmt.Printf("  %d purged\n", result.Purged)
+			if result.ArchiveFile != "" {
+				fmt.Printf("  archived in %s\n", result.ArchiveFile)
+			}
+		}
+
+		if err != nil {
+			fmt.Printf("Failed to purge: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(purgeCmd)
+	purgeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only count what would be purged")
+}
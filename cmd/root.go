@@ -15,8 +15,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
@@ -89,10 +92,40 @@ func initConfig() {
 	viper.SetDefault("site", []string{"all"})
 	viper.SetDefault("noop", false)
 	viper.SetDefault("database_max_connections", 10)
+	viper.SetDefault("database_auto_migrate", false)
 
 	// Collector
 	viper.SetDefault("collector.dump_invalid_payloads", false)
 	viper.SetDefault("collector.dump_invalid_payload_path", "/tmp/dora/dumps")
+	viper.SetDefault("collector.dump_max_files", 1000)
+	viper.SetDefault("collector.dump_max_age", 168)
+	viper.SetDefault("collector.dump_max_size", 100)
+	viper.SetDefault("collector.host_timeout", 300)
+	viper.SetDefault("collector.run_timeout", 0)
+	viper.SetDefault("collector.redfish.enabled", true)
+	viper.SetDefault("collector.ipmi.enabled", true)
+	viper.SetDefault("collector.readings.enabled", true)
+	viper.SetDefault("collector.readings.raw_max_age", 168)
+	viper.SetDefault("collector.readings.resolution", 1)
+	viper.SetDefault("collector.readings.max_age", 8760)
+	viper.SetDefault("collector.removed_components.purge_after", 0)
+	viper.SetDefault("collector.removal_guard.enabled", true)
+	viper.SetDefault("collector.removal_guard.max_percent", 50)
+	viper.SetDefault("collector.removal_guard.min_components", 4)
+	viper.SetDefault("collector.credentials.providers", []string{"static"})
+	viper.SetDefault("collector.credentials.command.timeout", 10)
+
+	// Retention
+	viper.SetDefault("retention.interval", 0)
+	viper.SetDefault("retention.archive_path", "/var/lib/dora/archive")
+	viper.SetDefault("retention.scanned_ports.max_age", 0)
+	viper.SetDefault("retention.scanned_ports.archive", false)
+	viper.SetDefault("retention.chassis.max_age", 0)
+	viper.SetDefault("retention.chassis.archive", false)
+	viper.SetDefault("retention.blades.max_age", 0)
+	viper.SetDefault("retention.blades.archive", false)
+	viper.SetDefault("retention.discretes.max_age", 0)
+	viper.SetDefault("retention.discretes.archive", false)
 
 	// Api
 	viper.SetDefault("api.http_server_port", 8000)
@@ -107,7 +140,16 @@ func initConfig() {
 	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
 	viper.SetDefault("scanner.kea_config", "/etc/kea/kea-dhcp4.conf")
 	viper.SetDefault("scanner.subnet_source", "kea")
+	viper.SetDefault("scanner.subnet_file", "/etc/bmc-toolbox/dora-subnets.yaml")
+	viper.SetDefault("scanner.dhcpd_config", "/etc/dhcp/dhcpd.conf")
+	viper.SetDefault("scanner.dhcpd_profile_option", "")
+	viper.SetDefault("scanner.kea_leases6", "")
+	viper.SetDefault("scanner.ipv6_host_files", []string{})
 	viper.SetDefault("scanner.concurrency", 100)
+	viper.SetDefault("scanner.host_concurrency", 16)
+	viper.SetDefault("scanner.batch_size", 500)
+	viper.SetDefault("scanner.collect_new_ports", false)
+	viper.SetDefault("scanner.kea_profile_option", "")
 
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -128,3 +170,22 @@ func initConfig() {
 		log.SetLevel(log.DebugLevel)
 	}
 }
+
+// signalContext returns a context that is cancelled when we receive SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.WithFields(log.Fields{"signal": sig}).Warning("stopping, hosts still running will be reported as cancelled")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
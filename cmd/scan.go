@@ -27,8 +27,8 @@ import (
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
 	Use:   "scan",
-	Short: "scan networks found in kea config or a list of given networks",
-	Long: `scan networks found in kea config or a list of given networks and search 
+	Short: "scan networks found in the subnet sources or a list of given networks",
+	Long: `scan networks found in the subnet sources or a list of given networks and search 
 for the required tcp and udp ports for the hardware discovery. It will build a list of 
 discoverable assets to be later used by dora collector
 
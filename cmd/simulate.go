@@ -0,0 +1,100 @@
+// Copyright © 2017 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bmc-toolbox/dora/internal/simulator"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	simulateStart     string
+	simulateDiscretes int
+	simulateChassis   int
+	simulateBlades    int
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Starts fake bmcs on loopback addresses for local end-to-end runs",
+	Long: `Starts fake bmcs on loopback addresses for local end-to-end runs. Each device
+listens on its own address with ssh (22), https (443) and rmcp (623), so it needs
+root or CAP_NET_BIND_SERVICE and nothing else bound to these ports on all addresses.
+
+The devices are read from simulator.devices in the config file, or generated when
+any of the count flags is given. They accept bmc_user and bmc_pass as credentials.
+
+usage: dora simulate
+       dora simulate --discretes 10 --chassis 2 --blades 16 --start 127.0.0.10
+       dora scan 127.0.0.0/24 && dora collect
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configItems := []string{
+			"bmc_user",
+			"bmc_pass",
+		}
+
+		for _, item := range configItems {
+			if !viper.IsSet(item) {
+				fmt.Printf("Parameter %s is missing in the config file\n", item)
+				os.Exit(1)
+			}
+		}
+
+		var devices []*simulator.Device
+		var err error
+		if cmd.Flags().Changed("discretes") || cmd.Flags().Changed("chassis") || cmd.Flags().Changed("blades") || !viper.IsSet("simulator.devices") {
+			devices, err = simulator.Generate(simulateStart, simulateDiscretes, simulateChassis, simulateBlades)
+		} else {
+			err = viper.UnmarshalKey("simulator.devices", &devices)
+		}
+		if err != nil {
+			fmt.Printf("Invalid devices: %s\n", err)
+			os.Exit(1)
+		}
+
+		sim, err := simulator.New(viper.GetString("bmc_user"), viper.GetString("bmc_pass"), devices)
+		if err != nil {
+			fmt.Printf("Invalid devices: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err = sim.Start(); err != nil {
+			fmt.Printf("Failed to start the simulator: %s\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		log.WithFields(log.Fields{"operation": "simulate", "devices": len(sim.Devices())}).Info("simulator running, press ctrl+c to stop")
+		<-ctx.Done()
+		sim.Close()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().StringVarP(&simulateStart, "start", "s", "127.0.0.10", "first address used by the generated devices")
+	simulateCmd.Flags().IntVarP(&simulateDiscretes, "discretes", "d", 4, "number of discretes to generate")
+	simulateCmd.Flags().IntVarP(&simulateChassis, "chassis", "c", 1, "number of chassis to generate")
+	simulateCmd.Flags().IntVarP(&simulateBlades, "blades", "b", 4, "number of blades to generate in each chassis")
+}
@@ -17,7 +17,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"runtime"
 	"time"
 
 	"github.com/bmc-toolbox/dora/connectors"
@@ -53,9 +52,11 @@ usage: dora worker
 			go metrics.Scheduler(time.Minute, metrics.GoRuntimeStats, []string{})
 			go metrics.Scheduler(time.Minute, metrics.MeasureRuntime, []string{"uptime"}, time.Now())
 		}
+		ctx, cancel := signalContext()
+		defer cancel()
+
 		scanner.ScanNetworksWorker()
-		connectors.DataCollectionWorker()
-		runtime.Goexit()
+		connectors.DataCollectionWorker(ctx)
 	},
 }
 
@@ -0,0 +1,263 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclib/discover"
+	"github.com/bmc-toolbox/bmclib/errors"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+// bmclibCollector collects the bmcs and chassis recognised by the bmclib vendor probes
+type bmclibCollector struct{}
+
+// Detect runs the bmclib probes, they don't need credentials since we log in afterwards with the ones of the vendor
+func (c *bmclibCollector) Detect(ctx context.Context, db *gorm.DB, host string) (*Device, error) {
+	conn, err := discover.ScanAndConnect(urlHost(host), "", "")
+	if err == errors.ErrVendorUnknown {
+		return nil, ErrDeviceNotMatched
+	} else if err != nil {
+		return nil, err
+	}
+
+	if bmc, ok := conn.(devices.Bmc); ok {
+		return &Device{Host: host, Vendor: bmc.Vendor(), HardwareType: bmc.HardwareType(), Kind: "bmc", Conn: bmc}, nil
+	} else if bmc, ok := conn.(devices.Cmc); ok {
+		return &Device{Host: host, Vendor: bmc.Vendor(), HardwareType: bmc.HardwareType(), Kind: "cmc", Conn: bmc}, nil
+	}
+
+	return nil, ErrDeviceNotSupported
+}
+
+// Collect reads the server or chassis behind the connection opened by Detect
+func (c *bmclibCollector) Collect(ctx context.Context, device *Device, creds []credentials.Credential) (snapshot *Snapshot, err error) {
+	snapshot = &Snapshot{}
+
+	if bmc, ok := device.Conn.(devices.Bmc); ok {
+		if snapshot.Credential, err = login(bmc, creds); err != nil {
+			return snapshot, err
+		}
+		defer bmc.Close()
+
+		snapshot.Discrete, snapshot.Blade, err = collectBmc(device, bmc)
+		return snapshot, err
+	} else if bmc, ok := device.Conn.(devices.Cmc); ok {
+		if snapshot.Credential, err = login(bmc, creds); err != nil {
+			return snapshot, err
+		}
+		defer bmc.Close()
+
+		snapshot.Chassis, err = collectCmc(ctx, device, bmc)
+		return snapshot, err
+	}
+
+	return snapshot, ErrDeviceNotSupported
+}
+
+// collectBmc reads the discrete or blade, blades of a managed chassis are left to the chassis unless forced
+func collectBmc(device *Device, bmc devices.Bmc) (discrete *model.Discrete, blade *model.Blade, err error) {
+	isBlade, err := bmc.IsBlade()
+	if err != nil {
+		return discrete, blade, &Error{Stage: StageBladeDetection, Err: err}
+	}
+
+	if isBlade && !device.Force {
+		chassisSerial, err := bmc.ChassisSerial()
+		if err != nil {
+			return discrete, blade, &Error{Stage: StageBladeDetection, Err: err}
+		}
+
+		chassis := model.Chassis{}
+		device.DB.Where("serial = ?", chassisSerial).First(&chassis)
+		if chassis.Managed {
+			return discrete, blade, ErrManagedBlade
+		}
+	}
+
+	serial, err := bmc.Serial()
+	if err != nil {
+		return discrete, blade, err
+	}
+
+	if invalidSerial(serial) {
+		if viper.GetBool("collector.dump_invalid_payloads") {
+			snapshot, _ := bmc.ServerSnapshot()
+			dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, snapshot, ErrInvalidSerial)
+		}
+		return discrete, blade, ErrInvalidSerial
+	}
+
+	server, err := bmc.ServerSnapshot()
+	if err == errors.ErrUnableToReadData || err == ErrUnableToReadData {
+		dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, server, err)
+	}
+	if err != nil {
+		return discrete, blade, err
+	}
+
+	// We read the server over https, so its web interface is reachable whatever the scanner found. bmclib gives the
+	// address we connected to, which has brackets for ipv6, so the one we scanned is kept
+	if b, ok := server.(*devices.Blade); ok {
+		blade = model.NewBladeFromDevice(b)
+		blade.BmcAddress = device.Host
+		blade.BmcWEBReachable = true
+		return discrete, blade, nil
+	} else if d, ok := server.(*devices.Discrete); ok {
+		discrete = model.NewDiscreteFromDevice(d)
+		discrete.BmcAddress = device.Host
+		discrete.BmcWEBReachable = true
+		return discrete, blade, nil
+	}
+
+	err = fmt.Errorf("unable to read devices.Blade or devices.Discrete from %T", server)
+	dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, server, err)
+	return discrete, blade, err
+}
+
+// collectCmc reads the chassis and completes its blades with what only their own bmc knows
+func collectCmc(ctx context.Context, device *Device, bmc devices.Cmc) (chassis *model.Chassis, err error) {
+	if !bmc.IsActive() {
+		return chassis, ErrStandbyCmc
+	}
+
+	ch, err := bmc.ChassisSnapshot()
+	if err == errors.ErrUnableToReadData || err == ErrUnableToReadData {
+		dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, ch, err)
+	}
+	if err != nil {
+		return chassis, err
+	}
+
+	chassis = model.NewChassisFromDevice(ch)
+	chassis.BmcAddress = device.Host
+	chassis.Managed = true
+
+	for _, blade := range chassis.Blades {
+		if err = ctx.Err(); err != nil {
+			return chassis, err
+		}
+
+		// the blade addresses are written the way the scanner stores them, they are looked up in the scanned ports
+		if ip := net.ParseIP(blade.BmcAddress); ip != nil {
+			blade.BmcAddress = ip.String()
+		}
+
+		collectChassisBlade(device.DB, blade)
+	}
+
+	return chassis, nil
+}
+
+// collectChassisBlade logs into the bmc of a blade to read the cpu, memory, license, nics and disks the chassis doesn't report
+func collectChassisBlade(db *gorm.DB, blade *model.Blade) {
+	preferred, err := storage.NewCollectionAttemptStorage(db).LastWorkingCredential(blade.Serial, blade.BmcAddress)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retrieving last working credential", "ip": blade.BmcAddress}).Warning(err)
+	}
+
+	target := credentials.Target{Host: blade.BmcAddress, Site: siteOf(db, blade.BmcAddress)}
+	creds, err := candidates(target, preferred)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retrieving credentials", "ip": blade.BmcAddress}).Error(err)
+		return
+	}
+
+	var username, password string
+	if len(creds) > 0 {
+		username, password = creds[0].Username, creds[0].Password
+	}
+
+	conn, err := discover.ScanAndConnect(urlHost(blade.BmcAddress), username, password)
+	if err != nil {
+		return
+	}
+
+	b, ok := conn.(devices.Bmc)
+	if !ok {
+		return
+	}
+
+	target.Vendor = b.Vendor()
+	creds, err = candidates(target, preferred)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retrieving credentials", "ip": blade.BmcAddress}).Error(err)
+		return
+	}
+
+	if _, err = login(b, creds); err != nil {
+		log.WithFields(log.Fields{"operation": "connection", "ip": blade.BmcAddress}).Error(err)
+		return
+	}
+
+	blade.BmcAuth = true
+	blade.BmcWEBReachable = true
+	blade.BmcSSHReachable, _, blade.BmcIpmiReachable = reachability(db, blade.BmcAddress)
+	blade.BmcType = b.HardwareType()
+
+	blade.Processor, blade.ProcessorCount, blade.ProcessorCoreCount, blade.ProcessorThreadCount, err = b.CPU()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading cpu data", "ip": blade.BmcAddress, "name": blade.Name, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	blade.Memory, err = b.Memory()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading memory data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	blade.BmcLicenceType, blade.BmcLicenceStatus, err = b.License()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading license data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	if len(blade.Nics) == 0 {
+		nics, err := b.Nics()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "reading nice", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+		} else {
+			for _, nic := range nics {
+				blade.Nics = append(blade.Nics, &model.Nic{
+					MacAddress:  nic.MacAddress,
+					Name:        nic.Name,
+					BladeSerial: blade.Serial,
+				})
+			}
+		}
+	}
+
+	if len(blade.Disks) == 0 {
+		disks, err := b.Disks()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "reading disks", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+		} else {
+			for pos, disk := range disks {
+				if disk.Serial == "" {
+					disk.Serial = fmt.Sprintf("%s-failed-%d", blade.Serial, pos)
+				}
+
+				blade.Disks = append(blade.Disks, &model.Disk{
+					Serial:      disk.Serial,
+					Size:        disk.Size,
+					Status:      disk.Status,
+					Model:       disk.Model,
+					Location:    disk.Location,
+					Type:        disk.Type,
+					FwVersion:   disk.FwVersion,
+					BladeSerial: blade.Serial,
+				})
+			}
+		}
+	}
+
+	if err == nil {
+		log.WithFields(log.Fields{"operation": "collection", "ip": blade.BmcAddress}).Info("success")
+	}
+}
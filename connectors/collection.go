@@ -1,147 +1,357 @@
 package connectors
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/bmc-toolbox/bmclib/devices"
-	"github.com/bmc-toolbox/bmclib/discover"
 	"github.com/bmc-toolbox/bmclib/errors"
-	"github.com/hashicorp/go-multierror"
 	"github.com/jinzhu/gorm"
 	"github.com/nats-io/go-nats"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/bmc-toolbox/dora/internal/credentials"
 	"github.com/bmc-toolbox/dora/internal/notification"
 	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/bmc-toolbox/dora/storage"
 	metrics "github.com/bmc-toolbox/gin-go-metrics"
 )
 
-func collect(input <-chan string, source *string, db *gorm.DB) {
-	bmcUser := viper.GetString("bmc_user")
-	bmcPass := viper.GetString("bmc_pass")
+func collect(ctx context.Context, input <-chan string, source *string, db *gorm.DB) {
+	attemptStorage := storage.NewCollectionAttemptStorage(db)
+
+	for {
+		var host string
+		select {
+		case <-ctx.Done():
+			return
+		case ip, ok := <-input:
+			if !ok {
+				return
+			}
+			host = ip
+		}
 
-	for host := range input {
 		log.WithFields(log.Fields{"operation": "scan", "ip": host}).Debug("collection started")
 
-		graphiteKey := "collect.collected_successfully"
+		attempt := &model.CollectionAttempt{
+			IP:        host,
+			Site:      siteOf(db, host),
+			Source:    *source,
+			StartedAt: time.Now(),
+		}
+
+		// without a host_timeout a host can take as long as the run
+		var hostCtx context.Context
+		var cancel context.CancelFunc
+		if timeout := time.Duration(viper.GetInt("collector.host_timeout")) * time.Second; timeout > 0 {
+			hostCtx, cancel = context.WithTimeout(ctx, timeout)
+		} else {
+			hostCtx, cancel = context.WithCancel(ctx)
+		}
+		graphiteKey, err := collectHost(hostCtx, host, source, db, attempt)
+		cancel()
+
+		attempt.FinishedAt = time.Now()
+		if err == context.DeadlineExceeded {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error("collection timed out")
+			attempt.Outcome = model.CollectionTimeout
+			graphiteKey = "collect.timeout"
+		} else if err == context.Canceled {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Warning("collection cancelled")
+			attempt.Outcome = model.CollectionCancelled
+			graphiteKey = "collect.cancelled"
+		}
 
-		conn, err := discover.ScanAndConnect(host, bmcUser, bmcPass)
 		if err != nil {
-			log.WithFields(log.Fields{"operation": "scan", "ip": host}).Error(err)
-			graphiteKey = "collect.bmc_scan_failed"
-			if viper.GetBool("metrics.enabled") {
-				metrics.IncrCounter([]string{graphiteKey}, 1)
-			}
-			continue
+			attempt.Error = err.Error()
 		}
 
-		if bmc, ok := conn.(devices.Bmc); ok {
-			err = bmc.CheckCredentials()
-			if err == errors.ErrLoginFailed {
-				bmc.UpdateCredentials(
-					viper.GetString(fmt.Sprintf("collector.default.%s.username", bmc.Vendor())),
-					viper.GetString(fmt.Sprintf("collector.default.%s.password", bmc.Vendor())),
-				)
-				err = bmc.CheckCredentials()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-					graphiteKey = "collect.bmc_wrong_credentials"
-					if viper.GetBool("metrics.enabled") {
-						metrics.IncrCounter([]string{graphiteKey}, 1)
-					}
-					continue
-				}
-			} else if err != nil {
-				log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_connection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
-			}
+		if _, err := attemptStorage.Create(attempt); err != nil {
+			log.WithFields(log.Fields{"operation": "storing collection attempt", "ip": host}).Error(err)
+		}
 
-			isBlade, err := bmc.IsBlade()
-			if err != nil {
-				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_is_blade_detection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
-			}
+		if graphiteKey != "" && viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{graphiteKey}, 1)
+		}
+	}
+}
 
-			if isBlade && *source != "cli-with-force" {
-				chassisSerial, err := bmc.ChassisSerial()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-					continue
-				}
+// collectHost detects the host with the registered collectors, collects and stores it, recording the outcome in the attempt
+func collectHost(ctx context.Context, host string, source *string, db *gorm.DB, attempt *model.CollectionAttempt) (graphiteKey string, err error) {
+	if err = ctx.Err(); err != nil {
+		return graphiteKey, err
+	}
 
-				chassis := model.Chassis{}
-				db.Where("serial = ?", chassisSerial).First(&chassis)
+	device, err := detect(ctx, db, host)
+	if err == ErrDeviceNotSupported || err == ErrNoCollector {
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Debug("unknown hardware skipping")
+		attempt.Outcome = model.CollectionUnknownDevice
+		return "collect.unknown_device", nil
+	} else if err != nil {
+		log.WithFields(log.Fields{"operation": "scan", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionScanFailed
+		return "collect.bmc_scan_failed", err
+	}
 
-				if chassis.Managed {
-					log.WithFields(log.Fields{"operation": "detection", "ip": host}).Debug("we don't want to scan blades directly since the chassis does it for us")
-					continue
-				}
-			}
+	device.Force = *source == "cli-with-force"
+	attempt.Vendor = device.Vendor
+	attempt.HardwareType = device.HardwareType
 
-			err = collectBmc(bmc)
-			if err != nil {
-				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_collection_failed"
-			}
+	preferred, err := storage.NewCollectionAttemptStorage(db).LastWorkingCredential(serialOf(db, host), host)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retrieving last working credential", "ip": host}).Warning(err)
+	}
 
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
-		} else if bmc, ok := conn.(devices.Cmc); ok {
-			err = bmc.CheckCredentials()
-			if err == errors.ErrLoginFailed {
-				bmc.UpdateCredentials(
-					viper.GetString(fmt.Sprintf("collector.default.%s.username", bmc.Vendor())),
-					viper.GetString(fmt.Sprintf("collector.default.%s.password", bmc.Vendor())),
-				)
-				err = bmc.CheckCredentials()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-					graphiteKey = "collect.cmc_wrong_credentials"
-					if viper.GetBool("metrics.enabled") {
-						metrics.IncrCounter([]string{graphiteKey}, 1)
-					}
-					continue
-				}
-			} else if err != nil {
-				log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-				graphiteKey = "collect.cmc_connection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
-			}
+	creds, err := candidates(credentials.Target{Host: host, Site: attempt.Site, Vendor: device.Vendor}, preferred)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retrieving credentials", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionCredentialsUnavailable
+		return "collect.credentials_unavailable", err
+	}
 
-			err := collectCmc(bmc)
-			if err != nil {
-				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-				graphiteKey = "collect.cmc_collection_failed"
+	var snapshot *Snapshot
+	err = runWithContext(ctx, func() (err error) {
+		snapshot, err = device.collector.Collect(ctx, device, creds)
+		return err
+	}, func() { abortConn(device.Conn) })
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return graphiteKey, err
+	}
+
+	// the vendor of some devices is only known once collected
+	attempt.Vendor = device.Vendor
+	if snapshot != nil {
+		attempt.Credential = snapshot.Credential
+		attempt.Serial = snapshot.Serial()
+	}
+
+	if e, ok := err.(*Error); ok && e.Stage == StageBladeDetection {
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionBladeDetectionFailed
+		return "collect.bmc_is_blade_detection_failed", err
+	} else if ok {
+		log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionConnectionFailed
+		return fmt.Sprintf("collect.%s_connection_failed", device.Kind), err
+	} else if err == errors.ErrLoginFailed {
+		log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionWrongCredentials
+		return fmt.Sprintf("collect.%s_wrong_credentials", device.Kind), err
+	} else if err == ErrManagedBlade {
+		log.WithFields(log.Fields{"operation": "detection", "ip": host}).Debug("we don't want to scan blades directly since the chassis does it for us")
+		attempt.Outcome = model.CollectionSkipped
+		return graphiteKey, nil
+	} else if err == ErrStandbyCmc {
+		log.WithFields(log.Fields{"operation": "detection", "ip": host}).Debug("standby chassis manager, the chassis is collected through the active one")
+		attempt.Outcome = model.CollectionStandby
+		return fmt.Sprintf("collect.%s_standby", device.Kind), nil
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = storeSnapshot(db, device, snapshot)
+	}
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return graphiteKey, err
+	} else if _, ok := err.(*storage.RemovalRefusedError); ok {
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Warning(err)
+		attempt.Outcome = model.CollectionRemovalRefused
+		return fmt.Sprintf("collect.%s_removal_refused", device.Kind), err
+	} else if err != nil {
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
+		attempt.Outcome = model.CollectionFailed
+		return fmt.Sprintf("collect.%s_collection_failed", device.Kind), err
+	}
+
+	log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
+	attempt.Outcome = model.CollectionSucceeded
+	return "collect.collected_successfully", nil
+}
+
+// runWithContext runs fn in its own goroutine and returns as soon as it finishes or the context is done, a panic
+// of fn is returned as an error. bmclib doesn't support contexts, so a wedged call is abandoned and whatever it
+// returns later is discarded, abort is called then to stop what it can of it and can be nil
+func runWithContext(ctx context.Context, fn func() error, abort func()) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithFields(log.Fields{"operation": "collection"}).Errorf("panic: %v\n%s", r, debug.Stack())
+				done <- fmt.Errorf("collector panic: %v", r)
 			}
+		}()
+		done <- fn()
+	}()
 
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
-		} else {
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Debug("unknown hardware skipping")
-			graphiteKey = "collect.unknown_device"
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if abort != nil {
+			abort()
 		}
-		// send metric which is not protected by "continue"
-		if viper.GetBool("metrics.enabled") {
-			metrics.IncrCounter([]string{graphiteKey}, 1)
+		return ctx.Err()
+	}
+}
+
+// abortConn stops the requests in progress on the connection of a device abandoned by its context when it
+// supports it, like the ipmi client. The others are closed by their collector once the call it's in returns
+func abortConn(conn interface{}) {
+	if a, ok := conn.(interface{ Abort() }); ok {
+		a.Abort()
+	}
+}
+
+// closeConn closes the connection of a device that won't be collected
+func closeConn(conn interface{}) {
+	if c, ok := conn.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.WithFields(log.Fields{"operation": "closing connection"}).Debug(err)
+		}
+	}
+}
+
+// loginChecker is implemented by the bmcs and chassis we are able to log into
+type loginChecker interface {
+	CheckCredentials() error
+	UpdateCredentials(string, string)
+}
+
+// candidates returns the credentials we should try for the target, starting by the one that worked last time
+func candidates(target credentials.Target, preferred string) (creds []credentials.Credential, err error) {
+	provider, err := credentials.FromConfig()
+	if err != nil {
+		return creds, err
+	}
+
+	creds, err = provider.Credentials(target)
+	if err != nil {
+		return creds, err
+	}
+
+	return credentials.Prefer(creds, preferred), err
+}
+
+// login tries the credentials in order until the device accepts one of them and returns its name
+func login(device loginChecker, creds []credentials.Credential) (name string, err error) {
+	for _, cred := range creds {
+		device.UpdateCredentials(cred.Username, cred.Password)
+		err = device.CheckCredentials()
+		if err == nil {
+			return cred.Name, nil
+		}
+
+		if err != errors.ErrLoginFailed {
+			return name, &Error{Stage: StageConnection, Err: err}
+		}
+	}
+
+	return name, errors.ErrLoginFailed
+}
+
+// siteOf returns the site where the scanner found the given ip
+func siteOf(db *gorm.DB, ip string) (site string) {
+	var sites []string
+	db.Model(&model.ScannedPort{}).Where("ip = ?", ip).Pluck("site", &sites)
+	if len(sites) > 0 {
+		site = sites[0]
+	}
+	return site
+}
+
+// serialOf returns the serial of the asset last stored with the given bmc address, empty when there's none
+func serialOf(db *gorm.DB, ip string) (serial string) {
+	var updatedAt time.Time
+	for _, asset := range []interface{}{&model.Discrete{}, &model.Blade{}, &model.Chassis{}} {
+		var assets []struct {
+			Serial    string
+			UpdatedAt time.Time
+		}
+		db.Model(asset).Select("serial, updated_at").Where("bmc_address = ?", ip).Order("updated_at desc").Limit(1).Scan(&assets)
+		if len(assets) > 0 && assets[0].UpdatedAt.After(updatedAt) {
+			serial, updatedAt = assets[0].Serial, assets[0].UpdatedAt
+		}
+	}
+	return serial
+}
+
+// urlHost returns the host the way it goes in an url, the ipv6 addresses between brackets
+func urlHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// resolve returns the address to collect of host, an ip or a name. The address is written the way the scanner
+// stores it, and of the addresses of a name, which can have both an ipv4 and an ipv6 one, the first with a
+// collectable port is picked. The error tells when none is collectable, the first address is returned anyway
+func resolve(collectablePorts *gorm.DB, host string) (ip string, err error) {
+	var addresses []string
+	if parsedIP := net.ParseIP(host); parsedIP != nil {
+		addresses = append(addresses, parsedIP.String())
+	} else {
+		lookup, err := net.LookupHost(host)
+		if err != nil {
+			return ip, err
+		}
+		for _, address := range lookup {
+			if parsedIP := net.ParseIP(address); parsedIP != nil {
+				addresses = append(addresses, parsedIP.String())
+			}
 		}
+		if len(addresses) == 0 {
+			return ip, fmt.Errorf("no address found for %s", host)
+		}
+	}
+
+	var scanned []model.ScannedPort
+	if err = collectablePorts.Where("ip in (?)", addresses).Find(&scanned).Error; err != nil {
+		return addresses[0], err
 	}
+	for _, address := range addresses {
+		for _, port := range scanned {
+			if port.IP == address {
+				return address, nil
+			}
+		}
+	}
+	return addresses[0], gorm.ErrRecordNotFound
 }
 
-// DataCollection collects the data of all given ips
-func DataCollection(ips []string, source string) {
+// Collectable selects the open scanned ports we are able to collect through, the web services of the scan profiles
+// for bmclib and redfish and the ipmi ones for the bmcs without https
+func Collectable(db *gorm.DB) (*gorm.DB, error) {
+	profiles, err := scanner.LoadProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, port := range profiles.Ports(scanner.ServiceWeb, scanner.ServiceIpmi) {
+		conditions = append(conditions, "(port = ? and protocol = ?)")
+		args = append(args, port.Port, port.Protocol)
+	}
+	return db.Where("state = ?", model.PortOpen).Where(strings.Join(conditions, " or "), args...), nil
+}
+
+// DataCollection collects the data of all given ips, it stops feeding hosts to the collectors once the context is done
+func DataCollection(ctx context.Context, ips []string, source string) {
+	if _, err := credentials.FromConfig(); err != nil {
+		log.WithFields(log.Fields{"operation": "loading credentials"}).Fatal(err)
+	}
+
 	concurrency := viper.GetInt("collector.concurrency")
 
 	cc := make(chan string, concurrency)
@@ -152,46 +362,67 @@ func DataCollection(ips []string, source string) {
 	for i := 0; i < concurrency; i++ {
 		go func(input <-chan string, source *string, db *gorm.DB, wg *sync.WaitGroup) {
 			defer wg.Done()
-			collect(input, source, db)
+			collect(ctx, input, source, db)
 		}(cc, &source, db, &wg)
 	}
 
+	enqueue := func(ip string) bool {
+		select {
+		case cc <- ip:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	collectablePorts, err := Collectable(db)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading scan profiles"}).Fatal(err)
+	}
+
 	if ips[0] == "all" {
 		var hosts []model.ScannedPort
-		if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open'").Find(&hosts).Error; err != nil {
+		if err := collectablePorts.Find(&hosts).Error; err != nil {
 			log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": "all"}).Error(err)
 		} else {
+			seen := make(map[string]bool)
 			for _, host := range hosts {
-				cc <- host.IP
+				if seen[host.IP] {
+					continue
+				}
+				seen[host.IP] = true
+
+				if !enqueue(host.IP) {
+					break
+				}
 			}
 		}
 	} else {
-		for _, ip := range ips {
-			host := model.ScannedPort{}
-			parsedIP := net.ParseIP(ip)
-			if parsedIP == nil {
-				lookup, err := net.LookupHost(ip)
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": ip}).Error(err)
-					continue
-				}
-				ip = lookup[0]
+		for _, host := range ips {
+			ip, err := resolve(collectablePorts, host)
+			if err != nil {
+				log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": host}).Error(err)
+				continue
 			}
 
-			if err := db.Where("ip = ? and port = 443 and protocol = 'tcp' and state = 'open'", ip).Find(&host).Error; err != nil {
-				log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": ip}).Error(err)
-				continue
+			if !enqueue(ip) {
+				break
 			}
-			cc <- host.IP
 		}
 	}
 
 	close(cc)
 	wg.Wait()
+
+	maintain(db)
 }
 
-// DataCollectionWorker collects the data of all given ips
-func DataCollectionWorker() {
+// DataCollectionWorker collects the data of all ips received from the queue until the context is done
+func DataCollectionWorker(ctx context.Context) {
+	if _, err := credentials.FromConfig(); err != nil {
+		log.WithFields(log.Fields{"operation": "loading credentials"}).Fatal(err)
+	}
+
 	nc, err := nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
 	if err != nil {
 		log.Fatalf("Subscriber unable to connect: %v\n", err)
@@ -202,24 +433,32 @@ func DataCollectionWorker() {
 	db := storage.InitDB()
 	source := "worker"
 
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
 	for i := 0; i < concurrency; i++ {
-		go func(input <-chan string, source *string, db *gorm.DB) {
-			collect(input, source, db)
-		}(cc, &source, db)
+		go func(input <-chan string, source *string, db *gorm.DB, wg *sync.WaitGroup) {
+			defer wg.Done()
+			collect(ctx, input, source, db)
+		}(cc, &source, db, &wg)
 	}
 
-	_, err = nc.QueueSubscribe("dora::collect", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
-		ip := string(msg.Data)
-		parsedIP := net.ParseIP(ip)
-		if parsedIP == nil {
-			lookup, err := net.LookupHost(ip)
-			if err != nil {
-				log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": ip}).Error(err)
-				return
-			}
-			ip = lookup[0]
+	collectablePorts, err := Collectable(db)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading scan profiles"}).Fatal(err)
+	}
+
+	sub, err := nc.QueueSubscribe("dora::collect", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
+		// the worker collects what it's asked for, even when it wasn't scanned
+		ip, err := resolve(collectablePorts, string(msg.Data))
+		if ip == "" {
+			log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": string(msg.Data)}).Error(err)
+			return
+		}
+
+		select {
+		case cc <- ip:
+		case <-ctx.Done():
 		}
-		cc <- ip
 	})
 	if err != nil {
 		log.WithFields(log.Fields{"operation": "error subscribing to the queue"}).Fatal(err)
@@ -229,282 +468,281 @@ func DataCollectionWorker() {
 	}
 
 	log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": "dora::collect"}).Info("subscribed to queue")
-}
 
-func collectBmc(bmc devices.Bmc) (err error) {
-	defer bmc.Close()
+	// The worker never finishes a run, so the maintenance runs every hour instead
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				maintain(db)
+			}
+		}
+	}()
 
-	serial, err := bmc.Serial()
-	if err != nil {
-		return err
+	<-ctx.Done()
+	if err := sub.Unsubscribe(); err != nil {
+		log.WithFields(log.Fields{"operation": "unsubscribing from the queue"}).Error(err)
 	}
+	wg.Wait()
+	nc.Close()
+}
 
-	if serial == "" || serial == "[unknown]" || serial == "0000000000" || serial == "_" {
-		return ErrInvalidSerial
-	}
+// invalidSerial tells whether the bmc gave us one of the placeholders some vendors use when the serial is not set
+func invalidSerial(serial string) bool {
+	return serial == "" || serial == "[unknown]" || serial == "0000000000" || serial == "_"
+}
 
-	isBlade, err := bmc.IsBlade()
+// reachability tells which services of the bmc the scanner found open, the scan profiles give the service of each port
+func reachability(db *gorm.DB, ip string) (ssh bool, web bool, ipmi bool) {
+	profiles, err := scanner.LoadProfiles()
 	if err != nil {
-		return err
+		log.WithFields(log.Fields{"operation": "loading scan profiles", "ip": ip}).Error(err)
+		return ssh, web, ipmi
 	}
 
-	db := storage.InitDB()
-	if isBlade {
-		server, err := bmc.ServerSnapshot()
-		if err != nil {
-			return err
+	var scans []model.ScannedPort
+	db.Where("ip = ? and state = ?", ip, model.PortOpen).Find(&scans)
+	for _, scan := range scans {
+		switch profiles.Service(scan.Protocol, scan.Port) {
+		case scanner.ServiceSSH:
+			ssh = true
+		case scanner.ServiceWeb:
+			web = true
+		case scanner.ServiceIpmi:
+			ipmi = true
 		}
+	}
+	return ssh, web, ipmi
+}
 
-		b, ok := server.(*devices.Blade)
-		if !ok {
-			return fmt.Errorf("unable to read devices.Blade")
-		}
+// storeSnapshot stores whatever the collector read, tagging discretes with the collector they came from
+func storeSnapshot(db *gorm.DB, device *Device, snapshot *Snapshot) error {
+	if snapshot.Discrete != nil {
+		discrete := snapshot.Discrete
+		ssh, web, ipmi := reachability(db, discrete.BmcAddress)
+		discrete.BmcAuth = true
+		discrete.BmcSSHReachable = discrete.BmcSSHReachable || ssh
+		discrete.BmcWEBReachable = discrete.BmcWEBReachable || web
+		discrete.BmcIpmiReachable = discrete.BmcIpmiReachable || ipmi
+		discrete.DataSource = device.source
 
-		blade := model.NewBladeFromDevice(b)
+		discreteStorage := storage.NewDiscreteStorage(db)
+		existingData, err := discreteStorage.GetOne(discrete.Serial)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		return storeAsset(db, &discreteAsset{discreteStorage, discrete, &existingData}, device.source)
+	} else if snapshot.Blade != nil {
+		blade := snapshot.Blade
+		ssh, web, ipmi := reachability(db, blade.BmcAddress)
 		blade.BmcAuth = true
-		blade.BmcWEBReachable = true
-
+		blade.BmcSSHReachable = blade.BmcSSHReachable || ssh
+		blade.BmcWEBReachable = blade.BmcWEBReachable || web
+		blade.BmcIpmiReachable = blade.BmcIpmiReachable || ipmi
 		db.Where(model.Chassis{Serial: blade.ChassisSerial}).FirstOrCreate(&model.Chassis{})
 
-		var scans []model.ScannedPort
-		db.Where("ip = ?", blade.BmcAddress).Find(&scans)
-		for _, scan := range scans {
-			if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
-				blade.BmcSSHReachable = true
-			} else if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
-				blade.BmcWEBReachable = true
-			} else if scan.Port == 623 && scan.Protocol == "ipmi" && scan.State == "open" {
-				blade.BmcIpmiReachable = true
-			}
-		}
-
 		bladeStorage := storage.NewBladeStorage(db)
 		existingData, err := bladeStorage.GetOne(blade.Serial)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			return err
 		}
-
-		_, err = bladeStorage.UpdateOrCreate(blade)
-		if err != nil {
+		return storeAsset(db, &bladeAsset{bladeStorage, blade, &existingData}, device.source)
+	} else if snapshot.Chassis != nil {
+		chassis := snapshot.Chassis
+		ssh, web, _ := reachability(db, chassis.BmcAddress)
+		chassis.BmcAuth = true
+		chassis.BmcSSHReachable = chassis.BmcSSHReachable || ssh
+		chassis.BmcWEBReachable = chassis.BmcWEBReachable || web
+
+		chassisStorage := storage.NewChassisStorage(db)
+		existingData, err := chassisStorage.GetOne(chassis.Serial)
+		if err != nil && err != gorm.ErrRecordNotFound {
 			return err
 		}
+		return storeAsset(db, &chassisAsset{chassisStorage, chassis, &existingData}, device.source)
+	}
 
-		if len(blade.Diff(&existingData)) != 0 {
-			url := fmt.Sprintf("%s/%s/%s", viper.GetString("url"), "blades", blade.Serial)
-			notification.NotifyChange(url)
-		}
+	return nil
+}
 
-		err = bladeStorage.RemoveOldRefs(blade)
-		if err != nil {
-			return err
-		}
-	} else {
-		server, err := bmc.ServerSnapshot()
-		if err != nil {
-			return err
-		}
+// asset is a collected discrete, blade or chassis with what the previous collection stored of it
+type asset interface {
+	// ApplySnapshot stores the collected asset and returns its serial
+	ApplySnapshot() (string, error)
+	Readings(collectedAt time.Time) []*model.Reading
+	// Changes and Diff compare the collected asset to the stored one, they are empty when nothing was stored
+	Changes() model.FieldChanges
+	Diff() []string
+	// Kind is the asset type of the changes and Path the one of its url
+	Kind() string
+	Path() string
+}
 
-		b, ok := server.(*devices.Discrete)
-		if !ok {
-			return fmt.Errorf("unable to read devices.Discrete")
-		}
+// storeAsset stores the asset, records its changes and notifies when it changed. Removals refused by the guard
+// don't prevent storing the rest and are returned at the end. The changes are recorded anyway, the next collection
+// compares to what is stored now, but the refused components are still stored and not recorded as gone
+func storeAsset(db *gorm.DB, asset asset, source string) error {
+	serial, err := asset.ApplySnapshot()
+	if _, refused := err.(*storage.RemovalRefusedError); err != nil && !refused {
+		return err
+	}
+	storeReadings(db, serial, asset.Readings(time.Now()))
+	storeChanges(db, asset.Kind(), serial, source, withoutRefused(asset.Changes(), serial, err))
 
-		discrete := model.NewDiscreteFromDevice(b)
-		discrete.BmcAuth = true
-		discrete.BmcWEBReachable = true
-
-		var scans []model.ScannedPort
-		db.Where("ip = ?", discrete.BmcAddress).Find(&scans)
-		for _, scan := range scans {
-			if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
-				discrete.BmcSSHReachable = true
-			} else if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
-				discrete.BmcWEBReachable = true
-			} else if scan.Port == 623 && scan.Protocol == "ipmi" && scan.State == "open" {
-				discrete.BmcIpmiReachable = true
-			}
-		}
+	if len(asset.Diff()) != 0 {
+		url := fmt.Sprintf("%s/%s/%s", viper.GetString("url"), asset.Path(), serial)
+		notification.NotifyChange(url)
+	}
 
-		discreteStorage := storage.NewDiscreteStorage(db)
-		existingData, err := discreteStorage.GetOne(discrete.Serial)
-		if err != nil && err != gorm.ErrRecordNotFound {
-			return err
-		}
+	return err
+}
 
-		_, err = discreteStorage.UpdateOrCreate(discrete)
-		if err != nil {
-			return err
-		}
+// discreteAsset, bladeAsset and chassisAsset are the assets of each type
+type discreteAsset struct {
+	storage   *storage.DiscreteStorage
+	collected *model.Discrete
+	stored    *model.Discrete
+}
 
-		if len(discrete.Diff(&existingData)) != 0 {
-			url := fmt.Sprintf("%s/%s/%s", viper.GetString("url"), "discretes", discrete.Serial)
-			notification.NotifyChange(url)
-		}
+func (a *discreteAsset) ApplySnapshot() (string, error) {
+	return a.storage.ApplySnapshot(a.collected)
+}
 
-		err = discreteStorage.RemoveOldRefs(discrete)
-		if err != nil {
-			return err
-		}
-	}
+func (a *discreteAsset) Readings(collectedAt time.Time) []*model.Reading {
+	return a.collected.Readings(collectedAt)
+}
 
-	return nil
+func (a *discreteAsset) Diff() []string {
+	return a.collected.Diff(a.stored)
 }
 
-func collectCmc(bmc devices.Cmc) (err error) {
-	defer bmc.Close()
+func (a *discreteAsset) Kind() string {
+	return "discrete"
+}
 
-	if !bmc.IsActive() {
-		return err
+func (a *discreteAsset) Path() string {
+	return "discretes"
+}
+
+func (a *discreteAsset) Changes() model.FieldChanges {
+	if a.stored.Serial == "" {
+		return nil
 	}
+	return a.collected.Changes(a.stored)
+}
 
-	db := storage.InitDB()
+type bladeAsset struct {
+	storage   *storage.BladeStorage
+	collected *model.Blade
+	stored    *model.Blade
+}
 
-	ch, err := bmc.ChassisSnapshot()
-	if err != nil {
-		return err
-	}
+func (a *bladeAsset) ApplySnapshot() (string, error) {
+	return a.storage.ApplySnapshot(a.collected)
+}
 
-	chassis := model.NewChassisFromDevice(ch)
-	chassis.BmcAuth = true
-	chassis.Managed = true
-	var scans []model.ScannedPort
-	db.Where("ip = ?", chassis.BmcAddress).Find(&scans)
-	for _, scan := range scans {
-		if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
-			chassis.BmcWEBReachable = true
-		} else if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
-			chassis.BmcSSHReachable = true
-		}
-	}
+func (a *bladeAsset) Readings(collectedAt time.Time) []*model.Reading {
+	return a.collected.Readings(collectedAt)
+}
 
-	for _, blade := range chassis.Blades {
-		if conn, err := discover.ScanAndConnect(blade.BmcAddress, viper.GetString("bmc_user"), viper.GetString("bmc_pass")); err == nil {
-			if b, ok := conn.(devices.Bmc); ok {
-				err = b.CheckCredentials()
-				if err == errors.ErrLoginFailed {
-					b.UpdateCredentials(
-						viper.GetString(fmt.Sprintf("collector.default.%s.username", b.Vendor())),
-						viper.GetString(fmt.Sprintf("collector.default.%s.password", b.Vendor())),
-					)
-					err = b.CheckCredentials()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "connection", "ip": blade.BmcAddress}).Error(err)
-						continue
-					}
-				} else if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": blade.BmcAddress}).Error(err)
-					continue
-				}
+func (a *bladeAsset) Diff() []string {
+	return a.collected.Diff(a.stored)
+}
 
-				blade.BmcAuth = true
-				blade.BmcWEBReachable = true
-
-				var scans []model.ScannedPort
-				db.Where("ip = ?", blade.BmcAddress).Find(&scans)
-				for _, scan := range scans {
-					if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
-						blade.BmcSSHReachable = true
-					} else if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
-						blade.BmcWEBReachable = true
-					} else if scan.Port == 623 && scan.Protocol == "ipmi" && scan.State == "open" {
-						blade.BmcIpmiReachable = true
-					}
-				}
+func (a *bladeAsset) Kind() string {
+	return "blade"
+}
 
-				blade.BmcType = b.HardwareType()
+func (a *bladeAsset) Path() string {
+	return "blades"
+}
 
-				blade.Processor, blade.ProcessorCount, blade.ProcessorCoreCount, blade.ProcessorThreadCount, err = b.CPU()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading cpu data", "ip": blade.BmcAddress, "name": blade.Name, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
+func (a *bladeAsset) Changes() model.FieldChanges {
+	if a.stored.Serial == "" {
+		return nil
+	}
+	return a.collected.Changes(a.stored)
+}
 
-				blade.Memory, err = b.Memory()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading memory data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
+type chassisAsset struct {
+	storage   *storage.ChassisStorage
+	collected *model.Chassis
+	stored    *model.Chassis
+}
 
-				blade.BmcLicenceType, blade.BmcLicenceStatus, err = b.License()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading license data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
+func (a *chassisAsset) ApplySnapshot() (string, error) {
+	return a.storage.ApplySnapshot(a.collected)
+}
 
-				if len(blade.Nics) == 0 {
-					nics, err := b.Nics()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "reading nice", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-					} else {
-						for _, nic := range nics {
-							blade.Nics = append(blade.Nics, &model.Nic{
-								MacAddress:  nic.MacAddress,
-								Name:        nic.Name,
-								BladeSerial: blade.Serial,
-							})
-						}
-					}
-				}
+func (a *chassisAsset) Readings(collectedAt time.Time) []*model.Reading {
+	return a.collected.Readings(collectedAt)
+}
 
-				if len(blade.Disks) == 0 {
-					disks, err := b.Disks()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "reading disks", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-					} else {
-						for pos, disk := range disks {
-							if disk.Serial == "" {
-								disk.Serial = fmt.Sprintf("%s-failed-%d", blade.Serial, pos)
-							}
-
-							blade.Disks = append(blade.Disks, &model.Disk{
-								Serial:      disk.Serial,
-								Size:        disk.Size,
-								Status:      disk.Status,
-								Model:       disk.Model,
-								Location:    disk.Location,
-								Type:        disk.Type,
-								FwVersion:   disk.FwVersion,
-								BladeSerial: blade.Serial,
-							})
-						}
-					}
-				}
+func (a *chassisAsset) Diff() []string {
+	return a.collected.Diff(a.stored)
+}
 
-				if err == nil {
-					log.WithFields(log.Fields{"operation": "collection", "ip": blade.BmcAddress}).Info("success")
-				}
-			}
-		}
-	}
+func (a *chassisAsset) Kind() string {
+	return "chassis"
+}
 
-	chassisStorage := storage.NewChassisStorage(db)
-	existingData, err := chassisStorage.GetOne(chassis.Serial)
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return err
-	}
+func (a *chassisAsset) Path() string {
+	return "chassis"
+}
 
-	_, err = chassisStorage.UpdateOrCreate(chassis)
-	if err != nil {
-		return err
+func (a *chassisAsset) Changes() model.FieldChanges {
+	if a.stored.Serial == "" {
+		return nil
 	}
+	return a.collected.Changes(a.stored)
+}
 
-	if len(chassis.Diff(&existingData)) != 0 {
-		url := fmt.Sprintf("%s/%s/%s", viper.GetString("url"), "chassis", chassis.Serial)
-		notification.NotifyChange(url)
+// withoutRefused drops the components reported gone whose removal the guard refused, they are still stored. The
+// components are addressed like the changes do, eg: disks[s3yjnx0k123456] or blades[cz3720001].nics[7c:d3:0a:1d:ba:d4]
+func withoutRefused(changes model.FieldChanges, serial string, err error) model.FieldChanges {
+	refused, ok := err.(*storage.RemovalRefusedError)
+	if !ok {
+		return changes
 	}
 
-	var merror *multierror.Error
+	kept := make(model.FieldChanges, 0, len(changes))
+	for _, change := range changes {
+		id := "[" + change.Before + "]"
+		if change.After != "" || change.Before == "" || !strings.HasSuffix(change.Field, id) {
+			kept = append(kept, change)
+			continue
+		}
 
-	bladeStorage := storage.NewBladeStorage(db)
-	for _, blade := range chassis.Blades {
-		merror = multierror.Append(merror, bladeStorage.RemoveOldRefs(blade))
+		parent, component := serial, strings.TrimSuffix(change.Field, id)
+		if i := strings.LastIndex(component, "]."); i >= 0 {
+			parent, component = component[strings.LastIndex(component[:i], "[")+1:i], component[i+2:]
+		}
+		if !refused.Refused(parent, component) {
+			kept = append(kept, change)
+		}
 	}
+	return kept
+}
 
-	err = chassisStorage.RemoveOldRefs(chassis)
-	if err != nil {
-		merror = multierror.Append(merror, err)
+// storeChanges records the fields that changed since the previous collection, a failure here must not fail the collection
+func storeChanges(db *gorm.DB, assetType string, serial string, source string, changes model.FieldChanges) {
+	if len(changes) == 0 {
+		return
 	}
 
-	if err != nil {
-		return merror.ErrorOrNil()
+	change := &model.AssetChange{
+		AssetType:   assetType,
+		AssetSerial: serial,
+		Source:      source,
+		ChangedAt:   time.Now(),
+		Changes:     changes,
 	}
 
-	return nil
+	if _, err := storage.NewAssetChangeStorage(db).Create(change); err != nil {
+		log.WithFields(log.Fields{"operation": "storing asset changes", "serial": serial, "type": assetType}).Error(err)
+	}
 }
@@ -0,0 +1,535 @@
+package connectors
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/dora/internal/fixtures"
+	"github.com/bmc-toolbox/dora/internal/ipmi"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+var (
+	record  = flag.String("record", "", "address of a real bmc to record, the credentials are read from BMC_USER and BMC_PASS")
+	fixture = flag.String("fixture", "", "name of the fixture to write in testdata when recording")
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	dir, err := ioutil.TempDir("", "dora-connectors")
+	if err != nil {
+		panic(err)
+	}
+
+	viper.Set("database_type", "sqlite3")
+	viper.Set("database_options", filepath.Join(dir, "dora.db"))
+	viper.Set("database_max_connections", 2)
+	viper.Set("database_auto_migrate", true)
+	viper.Set("bmc_user", "Priest")
+	viper.Set("bmc_pass", "Wololo")
+	viper.Set("url", "http://service.example.com/v1")
+	viper.Set("collector.host_timeout", 60)
+	viper.Set("collector.credentials.providers", []string{"static"})
+	viper.Set("collector.redfish.enabled", true)
+	viper.Set("collector.ipmi.enabled", true)
+	viper.Set("collector.readings.enabled", true)
+	viper.Set("collector.removal_guard.enabled", true)
+	viper.Set("collector.removal_guard.max_percent", 50)
+	viper.Set("collector.removal_guard.min_components", 4)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// resetDB removes everything the previous tests collected
+func resetDB(t *testing.T) *gorm.DB {
+	db := storage.InitDB()
+	for _, table := range []interface{}{&model.Chassis{}, &model.Blade{}, &model.Discrete{}, &model.Nic{}, &model.Disk{}, &model.Psu{}, &model.Fan{}, &model.StorageBlade{}, &model.CollectionAttempt{}, &model.Reading{}, &model.AssetChange{}, &model.PendingRemoval{}} {
+		if err := db.Delete(table).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// collectFixture runs a collection against the replayed fixture and returns the attempt it recorded
+func collectFixture(t *testing.T, db *gorm.DB, name string, source string) (attempt model.CollectionAttempt) {
+	f, err := fixtures.Load(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer := fixtures.NewReplayer(f)
+	defer replayer.Close()
+
+	input := make(chan string, 1)
+	input <- replayer.Host()
+	close(input)
+	collect(context.Background(), input, &source, db)
+
+	if missed := replayer.Missed(); len(missed) > 0 {
+		t.Logf("requests without a recorded response: %v", missed)
+	}
+
+	if err := db.Where("ip = ?", replayer.Host()).First(&attempt).Error; err != nil {
+		t.Fatalf("collection attempt not found: %s", err)
+	}
+
+	return attempt
+}
+
+func TestCollectDiscrete(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_ilo_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	if attempt.Vendor != "HP" || attempt.Credential != "default" || attempt.Serial != "cz3605020d" {
+		t.Errorf("unexpected vendor %q, credential %q or serial %q", attempt.Vendor, attempt.Credential, attempt.Serial)
+	}
+
+	discrete, err := storage.NewDiscreteStorage(db).GetOne("cz3605020d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(discrete.Disks) != 2 || len(discrete.Psus) != 2 {
+		t.Errorf("expected 2 disks and 2 psus, got %d and %d", len(discrete.Disks), len(discrete.Psus))
+	}
+
+	var blades int
+	db.Model(&model.Blade{}).Count(&blades)
+	if blades != 0 {
+		t.Errorf("a discrete must not be stored as blade, found %d blades", blades)
+	}
+}
+
+func TestCollectChanges(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_ilo_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	var changes []model.AssetChange
+	if db.Find(&changes); len(changes) != 0 {
+		t.Fatalf("the first collection of an asset isn't a change, got %+v", changes)
+	}
+
+	// Pretend the previous collection had another bios and a disk that is now gone
+	if err := db.Model(&model.Discrete{}).Where("serial = ?", "cz3605020d").Update("bios_version", "P89 v2.40").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.Disk{Serial: "gone_disk", BladeSerial: "cz3605020d"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	attempt = collectFixture(t, db, "hp_ilo_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	if err := db.Find(&changes).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].AssetType != "discrete" || changes[0].AssetSerial != "cz3605020d" || changes[0].Source != "bmclib" {
+		t.Fatalf("expected a single change of the discrete, got %+v", changes)
+	}
+
+	// The replayed bmc listens on a new port on every run, so the bmc address changes too
+	expected := map[string]string{"bios_version": "P89 v2.40", "disks[gone_disk]": "gone_disk", "bmc_address": ""}
+	for _, change := range changes[0].Changes {
+		before, found := expected[change.Field]
+		if !found || (before != "" && change.Before != before) || change.Before == change.After {
+			t.Errorf("unexpected change %+v", change)
+		}
+		delete(expected, change.Field)
+	}
+	if len(expected) != 0 {
+		t.Errorf("missing changes %+v", expected)
+	}
+}
+
+func TestCollectRemovedComponents(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_ilo_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	discrete, err := storage.NewDiscreteStorage(db).GetOne("cz3605020d")
+	if err != nil || len(discrete.Psus) == 0 {
+		t.Fatalf("expected the discrete with its psus: %v", err)
+	}
+
+	// A psu the bmc stopped reporting and one it reports again after being removed
+	if err = db.Create(&model.Psu{Serial: "pulled_psu", DiscreteSerial: discrete.Serial}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Model(discrete.Psus[0]).UpdateColumn("removed_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	attempt = collectFixture(t, db, "hp_ilo_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	psuStorage := storage.NewPsuStorage(db)
+	if _, err = psuStorage.GetOne("pulled_psu"); err != gorm.ErrRecordNotFound {
+		t.Errorf("removed psus must be hidden by default: %v", err)
+	}
+
+	pulled, err := psuStorage.Unscoped().GetOne("pulled_psu")
+	if err != nil || pulled.DeletedAt == nil {
+		t.Fatalf("expected the pulled psu to be kept with removed_at set: %v", err)
+	}
+
+	back, err := psuStorage.GetOne(discrete.Psus[0].Serial)
+	if err != nil || back.DeletedAt != nil || back.LastSeenAt.Before(attempt.StartedAt) {
+		t.Errorf("expected the psu reported again to be back and seen by the last collection: %+v %v", back, err)
+	}
+
+	viper.Set("collector.removed_components.purge_after", 1)
+	defer viper.Set("collector.removed_components.purge_after", 0)
+
+	purgeRemoved(db)
+	if _, err = psuStorage.Unscoped().GetOne("pulled_psu"); err != nil {
+		t.Errorf("the psu was removed less than purge_after ago: %v", err)
+	}
+
+	if err = db.Unscoped().Model(&pulled).UpdateColumn("removed_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	purgeRemoved(db)
+	if _, err = psuStorage.Unscoped().GetOne("pulled_psu"); err != gorm.ErrRecordNotFound {
+		t.Errorf("expected the psu to be purged: %v", err)
+	}
+}
+
+func TestCollectBlade(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_ilo_blade", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	blade, err := storage.NewBladeStorage(db).GetOne("cz3605020d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blade.ChassisSerial != "cz37018fym" {
+		t.Errorf("expected chassis serial cz37018fym, got %q", blade.ChassisSerial)
+	}
+}
+
+func TestCollectBladeOfManagedChassis(t *testing.T) {
+	db := resetDB(t)
+
+	if err := db.Create(&model.Chassis{Serial: "cz37018fym", Managed: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	attempt := collectFixture(t, db, "hp_ilo_blade", "cli")
+	if attempt.Outcome != model.CollectionSkipped {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSkipped, attempt.Outcome, attempt.Error)
+	}
+
+	if _, err := storage.NewBladeStorage(db).GetOne("cz3605020d"); err != gorm.ErrRecordNotFound {
+		t.Errorf("the blade of a managed chassis must not be collected directly: %v", err)
+	}
+
+	attempt = collectFixture(t, db, "hp_ilo_blade", "cli-with-force")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s with force, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+}
+
+func TestCollectChassis(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_c7000", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	chassis, err := storage.NewChassisStorage(db).GetOne("cz372137h3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !chassis.Managed {
+		t.Error("chassis collected directly must be managed")
+	}
+
+	var fans int
+	db.Model(&model.Fan{}).Where("chassis_serial = ?", chassis.Serial).Count(&fans)
+	if len(chassis.Blades) == 0 || len(chassis.Psus) == 0 || fans == 0 {
+		t.Errorf("expected blades, psus and fans, got %d, %d and %d", len(chassis.Blades), len(chassis.Psus), fans)
+	}
+}
+
+func TestCollectChassisRemovalGuard(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "hp_c7000", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	// More fans than the chassis reports vanishing at once looks like a broken collection
+	var fans int
+	db.Model(&model.Fan{}).Where("chassis_serial = ?", "cz372137h3").Count(&fans)
+	for i := 0; i <= fans; i++ {
+		if err := db.Create(&model.Fan{Serial: fmt.Sprintf("vanished_fan_%d", i), ChassisSerial: "cz372137h3"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The rest of the collection is stored and recorded anyway
+	if err := db.Model(&model.Chassis{}).Where("serial = ?", "cz372137h3").Update("fw_version", "4.50").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	attempt = collectFixture(t, db, "hp_c7000", "cli")
+	if attempt.Outcome != model.CollectionRemovalRefused {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionRemovalRefused, attempt.Outcome, attempt.Error)
+	}
+
+	var remaining int
+	db.Model(&model.Fan{}).Where("chassis_serial = ?", "cz372137h3").Count(&remaining)
+	if remaining != 2*fans+1 {
+		t.Errorf("the refused fans must be kept, expected %d fans, got %d", 2*fans+1, remaining)
+	}
+
+	var changes []model.AssetChange
+	if db.Find(&changes); len(changes) != 1 {
+		t.Fatalf("expected the changes of the refused collection to be recorded, got %+v", changes)
+	}
+	recorded := false
+	for _, change := range changes[0].Changes {
+		if strings.HasPrefix(change.Field, "fans[vanished_fan_") {
+			t.Errorf("the refused fans aren't gone, got %+v", change)
+		}
+		recorded = recorded || (change.Field == "fw_version" && change.Before == "4.50")
+	}
+	if !recorded {
+		t.Errorf("expected the firmware change to be recorded, got %+v", changes[0].Changes)
+	}
+
+	// The next collection reporting the same confirms the removal
+	attempt = collectFixture(t, db, "hp_c7000", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	db.Model(&model.Fan{}).Where("chassis_serial = ?", "cz372137h3").Count(&remaining)
+	if remaining != fans {
+		t.Errorf("expected %d fans once confirmed, got %d", fans, remaining)
+	}
+}
+
+func TestCollectRedfish(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "redfish_discrete", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	if attempt.Vendor != "Lenovo" || attempt.HardwareType != "redfish" {
+		t.Errorf("unexpected vendor %q or hardware type %q", attempt.Vendor, attempt.HardwareType)
+	}
+
+	discrete, err := storage.NewDiscreteStorage(db).GetOne("j300abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(discrete.Nics) != 3 || len(discrete.Disks) != 2 || len(discrete.Psus) != 2 {
+		t.Errorf("expected 3 nics, 2 disks and 2 psus, got %d, %d and %d", len(discrete.Nics), len(discrete.Disks), len(discrete.Psus))
+	}
+}
+
+func TestCollectRedfishEnclosure(t *testing.T) {
+	db := resetDB(t)
+
+	attempt := collectFixture(t, db, "redfish_enclosure", "cli")
+	if attempt.Outcome != model.CollectionSucceeded {
+		t.Fatalf("expected outcome %s, got %s: %s", model.CollectionSucceeded, attempt.Outcome, attempt.Error)
+	}
+
+	chassis, err := storage.NewChassisStorage(db).GetOne("j302cccc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !chassis.Managed || len(chassis.Blades) != 2 || len(chassis.Psus) != 6 {
+		t.Errorf("expected a managed chassis with 2 blades and 6 psus, got %t, %d and %d", chassis.Managed, len(chassis.Blades), len(chassis.Psus))
+	}
+}
+
+func TestCollectIpmi(t *testing.T) {
+	db := resetDB(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	responder := &ipmi.Responder{
+		Username: "Priest",
+		Password: "Wololo",
+		DeviceID: ipmi.DeviceID{ManufacturerID: 10876, Firmware: "3.88"},
+		PowerOn:  true,
+		FRU:      ipmi.FRU{ProductManufacturer: "Supermicro", ProductName: "SYS-1029U-TR4", ProductSerial: "S349007109"},
+		Sensors: []ipmi.Sensor{
+			{Name: "CPU1 Temp", Type: ipmi.SensorTemperature, Unit: ipmi.UnitCelsius, Value: 48},
+			{Name: "Inlet Temp", Type: ipmi.SensorTemperature, Unit: ipmi.UnitCelsius, Value: 23},
+			{Name: "PW Consumption", Type: ipmi.SensorPowerSupply, Unit: ipmi.UnitWatts, Value: 310},
+		},
+	}
+	go responder.Serve(conn)
+
+	// The collector only picks the hosts the scanner found with ipmi and without https
+	host := conn.LocalAddr().String()
+	scan := &model.ScannedPort{IP: host, Port: 623, Protocol: "ipmi", State: "open"}
+	scan.ID = scan.GenID()
+	if err = db.Create(scan).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer db.Delete(scan)
+
+	source := "cli"
+	input := make(chan string, 1)
+	input <- host
+	close(input)
+	collect(context.Background(), input, &source, db)
+
+	var attempt model.CollectionAttempt
+	if err = db.Where("ip = ?", host).First(&attempt).Error; err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Outcome != model.CollectionSucceeded || attempt.HardwareType != "ipmi" || attempt.Vendor != "Supermicro" {
+		t.Fatalf("expected outcome %s for hardware ipmi of Supermicro, got %s for %q of %q: %s", model.CollectionSucceeded, attempt.Outcome, attempt.HardwareType, attempt.Vendor, attempt.Error)
+	}
+
+	discrete, err := storage.NewDiscreteStorage(db).GetOne("s349007109")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if discrete.DataSource != "ipmi" || discrete.Vendor != "Supermicro" || discrete.PowerState != "on" || discrete.BmcVersion != "3.88" {
+		t.Errorf("unexpected source %q, vendor %q, power state %q or bmc version %q", discrete.DataSource, discrete.Vendor, discrete.PowerState, discrete.BmcVersion)
+	}
+
+	if discrete.TempC != 23 || discrete.PowerKw != 0.31 || !discrete.BmcIpmiReachable || discrete.BmcWEBReachable {
+		t.Errorf("unexpected temperature %d, power %f or reachability ipmi %t web %t", discrete.TempC, discrete.PowerKw, discrete.BmcIpmiReachable, discrete.BmcWEBReachable)
+	}
+
+	readings, err := storage.NewReadingStorage(db).Series(discrete.Serial, model.MetricPowerKw, attempt.StartedAt, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 1 || readings[0].Value != 0.31 || readings[0].AssetType != "discrete" {
+		t.Errorf("expected a single power reading of 0.31 for the discrete, got %+v", readings)
+	}
+}
+
+func TestRunWithContext(t *testing.T) {
+	if err := runWithContext(context.Background(), func() error { panic("broken bmc") }, nil); err == nil || !strings.Contains(err.Error(), "broken bmc") {
+		t.Errorf("expected the panic as an error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stop := make(chan struct{})
+	err := runWithContext(ctx, func() error {
+		<-stop
+		return nil
+	}, func() { close(stop) })
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	select {
+	case <-stop:
+	default:
+		t.Error("expected the call to be aborted once the context is done")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	db := resetDB(t)
+	for _, scan := range []*model.ScannedPort{
+		{Site: "ams4", CIDR: "2001:db8::/64", IP: "2001:db8::5", Port: 443, Protocol: "tcp", State: model.PortOpen},
+		{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.5", Port: 443, Protocol: "tcp", State: model.PortClosed},
+	} {
+		scan.ID = scan.GenID()
+		if err := db.Create(scan).Error; err != nil {
+			t.Fatal(err)
+		}
+		defer db.Delete(scan)
+	}
+
+	collectablePorts, err := Collectable(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip, err := resolve(collectablePorts, "2001:DB8:0::5"); ip != "2001:db8::5" || err != nil {
+		t.Errorf("expected the ipv6 bmc to be collectable as 2001:db8::5, got %q: %v", ip, err)
+	}
+	if ip, err := resolve(collectablePorts, "10.0.0.5"); ip != "10.0.0.5" || err != gorm.ErrRecordNotFound {
+		t.Errorf("expected the closed bmc not to be collectable, got %q: %v", ip, err)
+	}
+
+	for host, expected := range map[string]string{"2001:db8::5": "[2001:db8::5]", "10.0.0.5": "10.0.0.5", "127.0.0.1:8443": "127.0.0.1:8443", "bmc.example.com": "bmc.example.com"} {
+		if urlHost(host) != expected {
+			t.Errorf("expected %s in urls, got %s", expected, urlHost(host))
+		}
+	}
+}
+
+// TestRecord records a new fixture from a real bmc, eg: go test ./connectors -run TestRecord -record 10.0.0.1 -fixture dell_idrac9
+func TestRecord(t *testing.T) {
+	if *record == "" || *fixture == "" {
+		t.Skip("use -record and -fixture to record a fixture from a real bmc")
+	}
+
+	viper.Set("bmc_user", os.Getenv("BMC_USER"))
+	viper.Set("bmc_pass", os.Getenv("BMC_PASS"))
+
+	recorder := fixtures.NewRecorder(*fixture, *record)
+	defer recorder.Close()
+
+	db := resetDB(t)
+	source := "cli-with-force"
+	input := make(chan string, 1)
+	input <- recorder.Host()
+	close(input)
+	collect(context.Background(), input, &source, db)
+
+	if err := recorder.Fixture().Save(filepath.Join("testdata", *fixture+".json")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// Collector is a source of hardware data, eg: bmclib or redfish. The collection loop asks every registered
+// collector in order to detect a host and the first one recognising it collects the host. Metrics, credentials,
+// notifications and storage are handled by the loop, so a collector only has to read the device
+type Collector interface {
+	// Detect identifies the device behind host, it returns ErrDeviceNotMatched when the host is not for this collector
+	// and ErrDeviceNotSupported when it knows the device but has nothing to collect from it. db is the one of the loop
+	Detect(ctx context.Context, db *gorm.DB, host string) (*Device, error)
+	// Collect logs into the device with the first working credential and reads its snapshot. Errors
+	// happening before the collection itself should be wrapped in an *Error with the stage they happened at
+	Collect(ctx context.Context, device *Device, creds []credentials.Credential) (*Snapshot, error)
+}
+
+// Device is a host recognised by a collector
+type Device struct {
+	Host         string
+	Vendor       string
+	HardwareType string
+	// Kind prefixes the metrics of the device, eg: bmc, cmc
+	Kind string
+	// Force asks to collect blades even when their chassis is managed
+	Force bool
+	// Conn is kept by the collector between Detect and Collect, eg: the bmclib connection
+	Conn interface{}
+	// DB is the database of the collection loop, set once the device is detected
+	DB *gorm.DB
+
+	collector Collector
+	source    string
+}
+
+// Snapshot is what a collector read from a device, only one of Discrete, Blade or Chassis is set. The
+// reachability flags set by the collector are kept, the loop adds the ones found open by the scanner
+type Snapshot struct {
+	// Credential is the name of the credential accepted by the device
+	Credential string
+	Discrete   *model.Discrete
+	Blade      *model.Blade
+	Chassis    *model.Chassis
+}
+
+// Serial returns the serial of the asset read, empty when none was
+func (s *Snapshot) Serial() string {
+	switch {
+	case s.Discrete != nil:
+		return s.Discrete.Serial
+	case s.Blade != nil:
+		return s.Blade.Serial
+	case s.Chassis != nil:
+		return s.Chassis.Serial
+	}
+	return ""
+}
+
+// Stages of a collection an *Error can be returned from
+const (
+	StageConnection     = "connection"
+	StageBladeDetection = "blade detection"
+)
+
+// Error is returned by collectors when a collection fails before reading the device
+type Error struct {
+	Stage string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+type registration struct {
+	name      string
+	collector Collector
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []registration
+)
+
+func init() {
+	Register("ipmi", &ipmiCollector{})
+	Register("bmclib", &bmclibCollector{})
+	Register("redfish", &redfishCollector{})
+}
+
+// Register adds a collector after the ones already registered, it panics if the name is taken
+func Register(name string, collector Collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if collector == nil {
+		panic("connectors: Register collector is nil")
+	}
+
+	for _, r := range registry {
+		if r.name == name {
+			panic(fmt.Sprintf("connectors: Register called twice for collector %s", name))
+		}
+	}
+
+	registry = append(registry, registration{name: name, collector: collector})
+}
+
+// Collectors returns the names of the registered collectors in the order they detect hosts
+func Collectors() (names []string) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, r := range registry {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// detect asks the registered collectors in order to identify the host, it returns ErrNoCollector when none does
+func detect(ctx context.Context, db *gorm.DB, host string) (device *Device, err error) {
+	registryMu.RLock()
+	collectors := append([]registration(nil), registry...)
+	registryMu.RUnlock()
+
+	for _, r := range collectors {
+		var detected *Device
+		collector := r.collector
+		err = runWithContext(ctx, func() (err error) {
+			detected, err = collector.Detect(ctx, db, host)
+			// nobody collects a device detected once the context is done
+			if err == nil && ctx.Err() != nil {
+				closeConn(detected.Conn)
+			}
+			return err
+		}, nil)
+		if err == ErrDeviceNotMatched {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		detected.DB = db
+		detected.collector = collector
+		detected.source = r.name
+		return detected, nil
+	}
+
+	return nil, ErrNoCollector
+}
@@ -0,0 +1,102 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+)
+
+// fakeCollector recognises a single host and reads a discrete from it without any request
+type fakeCollector struct {
+	host string
+}
+
+func (c *fakeCollector) Detect(ctx context.Context, db *gorm.DB, host string) (*Device, error) {
+	if host != c.host {
+		return nil, ErrDeviceNotMatched
+	}
+	return &Device{Host: host, Vendor: "Fake", HardwareType: "fake", Kind: "fake"}, nil
+}
+
+func (c *fakeCollector) Collect(ctx context.Context, device *Device, creds []credentials.Credential) (*Snapshot, error) {
+	return &Snapshot{
+		Credential: creds[0].Name,
+		Discrete:   &model.Discrete{Serial: "fake0001", BmcAddress: device.Host, Vendor: device.Vendor},
+	}, nil
+}
+
+var (
+	fake         = &fakeCollector{}
+	registerFake sync.Once
+)
+
+func TestRegisteredCollector(t *testing.T) {
+	db := resetDB(t)
+
+	// Neither bmclib nor redfish recognise a server answering 404 to everything, so it falls to the fake collector
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	fake.host = host
+	registerFake.Do(func() { Register("fake", fake) })
+	if names := Collectors(); len(names) != 4 || names[len(names)-1] != "fake" {
+		t.Errorf("expected the fake collector after the in-tree ones, got %v", names)
+	}
+
+	source := "cli"
+	input := make(chan string, 1)
+	input <- host
+	close(input)
+	collect(context.Background(), input, &source, db)
+
+	var attempt model.CollectionAttempt
+	if err := db.Where("ip = ?", host).First(&attempt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if attempt.Outcome != model.CollectionSucceeded || attempt.Vendor != "Fake" || attempt.Credential != "default" {
+		t.Fatalf("unexpected outcome %s, vendor %q or credential %q: %s", attempt.Outcome, attempt.Vendor, attempt.Credential, attempt.Error)
+	}
+
+	discrete, err := storage.NewDiscreteStorage(db).GetOne("fake0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !discrete.BmcAuth || discrete.DataSource != "fake" {
+		t.Errorf("the collection loop must flag the bmc as authenticated and tag the source, got %q", discrete.DataSource)
+	}
+}
+
+func TestUnknownDevice(t *testing.T) {
+	db := resetDB(t)
+
+	// Nothing recognises a server answering 404 to everything once it isn't the fake collector's host
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	source := "cli"
+	input := make(chan string, 1)
+	input <- host
+	close(input)
+	collect(context.Background(), input, &source, db)
+
+	var attempt model.CollectionAttempt
+	if err := db.Where("ip = ?", host).First(&attempt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if attempt.Outcome != model.CollectionUnknownDevice || attempt.Error != "" {
+		t.Errorf("expected outcome %s without error, got %s: %s", model.CollectionUnknownDevice, attempt.Outcome, attempt.Error)
+	}
+}
@@ -0,0 +1,113 @@
+package connectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const dumpPrefix = "dora-dump-"
+
+var dumpLock sync.Mutex
+
+// invalidPayload is what we write to disk when a bmc gives us data we are unable to use
+type invalidPayload struct {
+	Host         string      `json:"host"`
+	Vendor       string      `json:"vendor"`
+	HardwareType string      `json:"hardware_type"`
+	Error        string      `json:"error"`
+	DumpedAt     time.Time   `json:"dumped_at"`
+	Snapshot     interface{} `json:"snapshot"`
+}
+
+// dumpInvalidPayload writes the snapshot and the error to a timestamped file under collector.dump_invalid_payload_path,
+// so we are able to report broken firmwares. It does nothing unless collector.dump_invalid_payloads is enabled
+func dumpInvalidPayload(host string, vendor string, hardwareType string, snapshot interface{}, cause error) {
+	if !viper.GetBool("collector.dump_invalid_payloads") {
+		return
+	}
+
+	payload := invalidPayload{
+		Host:         host,
+		Vendor:       vendor,
+		HardwareType: hardwareType,
+		Error:        cause.Error(),
+		DumpedAt:     time.Now(),
+		Snapshot:     snapshot,
+	}
+
+	content, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "dumping invalid payload", "ip": host}).Error(err)
+		return
+	}
+
+	dumpLock.Lock()
+	defer dumpLock.Unlock()
+
+	path := viper.GetString("collector.dump_invalid_payload_path")
+	if err = os.MkdirAll(path, 0755); err != nil {
+		log.WithFields(log.Fields{"operation": "dumping invalid payload", "ip": host}).Error(err)
+		return
+	}
+
+	name := fmt.Sprintf("%s%s-%s.json", dumpPrefix, payload.DumpedAt.UTC().Format("20060102T150405.000000000"), strings.Replace(host, ":", "_", -1))
+	file := filepath.Join(path, name)
+	if err = ioutil.WriteFile(file, content, 0640); err != nil {
+		log.WithFields(log.Fields{"operation": "dumping invalid payload", "ip": host}).Error(err)
+		return
+	}
+
+	log.WithFields(log.Fields{"operation": "dumping invalid payload", "ip": host, "file": file}).Info("invalid payload dumped")
+
+	if err = pruneDumps(path); err != nil {
+		log.WithFields(log.Fields{"operation": "pruning invalid payload dumps"}).Error(err)
+	}
+}
+
+// pruneDumps removes dumps older than collector.dump_max_age hours, then the oldest ones until we have at most
+// collector.dump_max_files files using at most collector.dump_max_size megabytes. A zero value disables the limit
+func pruneDumps(path string) (err error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	var dumps []os.FileInfo
+	for _, file := range files {
+		if !file.IsDir() && strings.HasPrefix(file.Name(), dumpPrefix) {
+			dumps = append(dumps, file)
+		}
+	}
+
+	// Newest first, so everything past the limits is the oldest data
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].Name() > dumps[j].Name() })
+
+	maxAge := time.Duration(viper.GetInt("collector.dump_max_age")) * time.Hour
+	maxFiles := viper.GetInt("collector.dump_max_files")
+	maxSize := viper.GetInt64("collector.dump_max_size") * 1024 * 1024
+
+	var kept int
+	var size int64
+	for _, dump := range dumps {
+		if (maxAge > 0 && time.Since(dump.ModTime()) > maxAge) || (maxFiles > 0 && kept >= maxFiles) || (maxSize > 0 && size+dump.Size() > maxSize) {
+			if err = os.Remove(filepath.Join(path, dump.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+		kept++
+		size += dump.Size()
+	}
+
+	return nil
+}
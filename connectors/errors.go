@@ -19,6 +19,16 @@ var (
 	ErrRedFishEndPoint500 = errors.New("we've received 500 calling this endpoint")
 	// ErrUnableToReadData is returned when we fail to read data from a chassis or bmc
 	ErrUnableToReadData = errors.New("unable to read data from this device")
+	// ErrDeviceNotMatched is returned by a collector when the device is not for it
+	ErrDeviceNotMatched = errors.New("device not matched by the collector")
+	// ErrDeviceNotSupported is returned by a collector when it knows the device but has nothing to collect from it
+	ErrDeviceNotSupported = errors.New("device not supported by the collector")
+	// ErrNoCollector is returned when none of the registered collectors recognises the device
+	ErrNoCollector = errors.New("no collector recognises the device")
+	// ErrStandbyCmc is returned when the chassis manager is the standby one, it has nothing to collect
+	ErrStandbyCmc = errors.New("chassis manager on standby")
+	// ErrManagedBlade is returned when a blade is collected through its managed chassis instead of directly
+	ErrManagedBlade = errors.New("blade collected through its managed chassis")
 	// ErrVendorNotSupported is returned when we are able to identify a vendor but we won't support it
 	ErrVendorNotSupported = errors.New("vendor not supported")
 )
@@ -0,0 +1,131 @@
+package connectors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/bmc-toolbox/dora/internal/ipmi"
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// ipmiCollector reads the fru, sensors and power state of the bmcs the scanner only found listening on udp 623
+type ipmiCollector struct{}
+
+// Detect only matches hosts without https, the others are better served by bmclib and redfish. The vendor
+// is only known from the fru after logging in, it's left empty until then so all the credentials are tried
+func (c *ipmiCollector) Detect(ctx context.Context, db *gorm.DB, host string) (*Device, error) {
+	if !viper.GetBool("collector.ipmi.enabled") {
+		return nil, ErrDeviceNotMatched
+	}
+
+	_, web, reachable := reachability(db, host)
+	if web || !reachable {
+		return nil, ErrDeviceNotMatched
+	}
+
+	client := ipmi.New(host, "", "")
+	supported, err := client.SupportsRMCPPlus()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if !supported {
+		client.Close()
+		return nil, ErrDeviceNotSupported
+	}
+
+	return &Device{Host: host, HardwareType: ipmi.HardwareType, Kind: "ipmi", Conn: client}, nil
+}
+
+// Collect builds a discrete from the fru, the bmc firmware, the power state and the temperature and power sensors
+func (c *ipmiCollector) Collect(ctx context.Context, device *Device, creds []credentials.Credential) (snapshot *Snapshot, err error) {
+	snapshot = &Snapshot{}
+
+	client, ok := device.Conn.(*ipmi.Client)
+	if !ok {
+		return snapshot, ErrDeviceNotSupported
+	}
+	defer client.Close()
+
+	if snapshot.Credential, err = login(client, creds); err != nil {
+		return snapshot, err
+	}
+
+	id, err := client.DeviceID()
+	if err != nil {
+		return snapshot, err
+	}
+
+	fru, err := client.FRU()
+	if err != nil {
+		return snapshot, err
+	}
+
+	discrete := &model.Discrete{
+		Serial:           strings.ToLower(first(fru.ProductSerial, fru.BoardSerial, fru.ChassisSerial)),
+		Model:            first(fru.ProductName, fru.BoardProduct),
+		Vendor:           first(fru.ProductManufacturer, fru.BoardManufacturer, id.Manufacturer()),
+		BmcType:          ipmi.HardwareType,
+		BmcAddress:       device.Host,
+		BmcVersion:       id.Firmware,
+		BmcIpmiReachable: true,
+		Nics:             make([]*model.Nic, 0),
+		Disks:            make([]*model.Disk, 0),
+		Psus:             make([]*model.Psu, 0),
+	}
+
+	device.Vendor = discrete.Vendor
+
+	if invalidSerial(discrete.Serial) {
+		dumpInvalidPayload(device.Host, discrete.Vendor, device.HardwareType, fru, ErrInvalidSerial)
+		return snapshot, ErrInvalidSerial
+	}
+
+	discrete.PowerState, err = client.PowerState()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading power state", "ip": device.Host, "serial": discrete.Serial, "type": "discrete"}).Warning(err)
+	}
+
+	sensors, err := client.Sensors()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading sensors", "ip": device.Host, "serial": discrete.Serial, "type": "discrete"}).Warning(err)
+	}
+	discrete.TempC, discrete.PowerKw = readings(sensors)
+
+	snapshot.Discrete = discrete
+	return snapshot, nil
+}
+
+// readings picks the inlet temperature, or the first one when there's no inlet sensor, and the first power consumption
+func readings(sensors []ipmi.Sensor) (tempC int, powerKw float64) {
+	var inlet, found bool
+	for _, sensor := range sensors {
+		if sensor.Type == ipmi.SensorTemperature && sensor.Unit == ipmi.UnitCelsius && !inlet {
+			name := strings.ToLower(sensor.Name)
+			if isInlet := strings.Contains(name, "inlet") || strings.Contains(name, "ambient"); isInlet || tempC == 0 {
+				tempC, inlet = int(sensor.Value), isInlet
+			}
+		}
+
+		if sensor.Unit == ipmi.UnitWatts && !found {
+			powerKw, found = sensor.Value/1000, true
+		}
+	}
+	return tempC, powerKw
+}
+
+// first returns the first non empty value
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
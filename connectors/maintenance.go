@@ -0,0 +1,32 @@
+package connectors
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+// maintain runs the housekeeping that follows the collections
+func maintain(db *gorm.DB) {
+	compactReadings(db)
+	purgeRemoved(db)
+}
+
+// purgeRemoved deletes the components marked as removed for longer than collector.removed_components.purge_after
+// hours, with 0 they are kept forever
+func purgeRemoved(db *gorm.DB) {
+	purgeAfter := time.Duration(viper.GetInt("collector.removed_components.purge_after")) * time.Hour
+	if purgeAfter <= 0 {
+		return
+	}
+
+	purged, err := storage.PurgeRemoved(db, time.Now().Add(-purgeAfter))
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "purging removed components"}).Error(err)
+	}
+	log.WithFields(log.Fields{"operation": "purging removed components", "purged": purged}).Debug("removed components purged")
+}
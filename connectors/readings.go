@@ -0,0 +1,55 @@
+package connectors
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+// storeReadings appends the sensor values of a collection to the history, a failure here must not fail the collection
+func storeReadings(db *gorm.DB, serial string, readings []*model.Reading) {
+	if !viper.GetBool("collector.readings.enabled") {
+		return
+	}
+
+	if err := storage.NewReadingStorage(db).Append(readings); err != nil {
+		log.WithFields(log.Fields{"operation": "storing readings", "serial": serial}).Error(err)
+	}
+}
+
+// compactReadings averages the readings older than collector.readings.raw_max_age and removes the ones
+// older than collector.readings.max_age, both in hours. A max_age of 0 keeps the history forever
+func compactReadings(db *gorm.DB) {
+	if !viper.GetBool("collector.readings.enabled") {
+		return
+	}
+
+	readingStorage := storage.NewReadingStorage(db)
+	now := time.Now()
+
+	if rawMaxAge := time.Duration(viper.GetInt("collector.readings.raw_max_age")) * time.Hour; rawMaxAge > 0 {
+		resolution := time.Duration(viper.GetInt("collector.readings.resolution")) * time.Hour
+		if resolution <= 0 {
+			resolution = time.Hour
+		}
+
+		replaced, err := readingStorage.Downsample(now.Add(-rawMaxAge), resolution)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "downsampling readings"}).Error(err)
+		}
+		log.WithFields(log.Fields{"operation": "downsampling readings", "replaced": replaced}).Debug("readings downsampled")
+	}
+
+	if maxAge := time.Duration(viper.GetInt("collector.readings.max_age")) * time.Hour; maxAge > 0 {
+		removed, err := readingStorage.Prune(now.Add(-maxAge))
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "pruning readings"}).Error(err)
+		}
+		log.WithFields(log.Fields{"operation": "pruning readings", "removed": removed}).Debug("readings pruned")
+	}
+}
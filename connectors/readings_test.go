@@ -0,0 +1,63 @@
+package connectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+func TestCompactReadings(t *testing.T) {
+	db := resetDB(t)
+	viper.Set("collector.readings.raw_max_age", 24)
+	viper.Set("collector.readings.resolution", 1)
+	viper.Set("collector.readings.max_age", 24*30)
+
+	hour := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	chassis := &model.Chassis{Serial: "cz372137h3", PowerKw: 2, Fans: []*model.Fan{{Serial: "cz372137h3_fan_1", CurrentRPM: 5000}}}
+
+	var readings []*model.Reading
+	for minute, power := range []float64{2, 3, 4} {
+		chassis.PowerKw = power
+		readings = append(readings, chassis.Readings(hour.Add(time.Duration(minute*20)*time.Minute))...)
+	}
+	chassis.PowerKw = 8
+	readings = append(readings, chassis.Readings(hour.Add(time.Hour))...)
+	readings = append(readings, chassis.Readings(time.Now().Add(-time.Hour))...)
+	readings = append(readings, chassis.Readings(time.Now().Add(-24*60*time.Hour))...)
+
+	readingStorage := storage.NewReadingStorage(db)
+	if err := readingStorage.Append(readings); err != nil {
+		t.Fatal(err)
+	}
+
+	compactReadings(db)
+
+	series, err := readingStorage.Series(chassis.Serial, model.MetricPowerKw, time.Now().Add(-90*24*time.Hour), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(series) != 3 {
+		t.Fatalf("expected 2 averages and the recent raw reading, got %+v", series)
+	}
+
+	if series[0].Value != 3 || series[0].Samples != 3 || series[0].Resolution != 3600 || !series[0].CollectedAt.Equal(hour) {
+		t.Errorf("unexpected average of the first hour %+v", series[0])
+	}
+
+	if series[1].Value != 8 || series[1].Samples != 1 || series[2].Resolution != 0 {
+		t.Errorf("unexpected average of the second hour %+v or recent reading %+v", series[1], series[2])
+	}
+
+	fans, err := readingStorage.Series("cz372137h3_fan_1", model.MetricRPM, hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fans) != 2 || fans[0].Value != 5000 || fans[0].AssetType != "fan" {
+		t.Errorf("expected the fan speed averaged per hour, got %+v", fans)
+	}
+}
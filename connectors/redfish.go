@@ -0,0 +1,82 @@
+package connectors
+
+import (
+	"context"
+
+	"github.com/bmc-toolbox/bmclib/errors"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/internal/credentials"
+	"github.com/bmc-toolbox/dora/internal/redfish"
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// redfishCollector collects the bmcs bmclib doesn't recognise through the standard redfish api
+type redfishCollector struct{}
+
+// Detect looks for the redfish service root, it's registered after bmclib so it only sees the bmcs bmclib doesn't know
+func (c *redfishCollector) Detect(ctx context.Context, db *gorm.DB, host string) (*Device, error) {
+	if !viper.GetBool("collector.redfish.enabled") {
+		return nil, ErrDeviceNotMatched
+	}
+
+	r, err := redfish.New(host, "", "")
+	if err == errors.ErrDeviceNotMatched {
+		return nil, ErrDeviceNotMatched
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &Device{Host: host, Vendor: r.Vendor(), HardwareType: r.HardwareType(), Kind: "redfish", Conn: r}, nil
+}
+
+// Collect reads enclosures as managed chassis with their blades and everything else as a discrete
+func (c *redfishCollector) Collect(ctx context.Context, device *Device, creds []credentials.Credential) (snapshot *Snapshot, err error) {
+	snapshot = &Snapshot{}
+
+	r, ok := device.Conn.(*redfish.Redfish)
+	if !ok {
+		return snapshot, ErrDeviceNotSupported
+	}
+
+	if snapshot.Credential, err = login(r, creds); err != nil {
+		return snapshot, err
+	}
+	defer r.Close()
+
+	isEnclosure, err := r.IsEnclosure()
+	if err != nil {
+		return snapshot, err
+	}
+
+	if !isEnclosure {
+		server, err := r.ServerSnapshot()
+		if err != nil {
+			return snapshot, err
+		}
+
+		if invalidSerial(server.Serial) {
+			dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, server, ErrInvalidSerial)
+			return snapshot, ErrInvalidSerial
+		}
+
+		snapshot.Discrete = model.NewDiscreteFromDevice(server)
+		snapshot.Discrete.BmcWEBReachable = true
+		return snapshot, nil
+	}
+
+	enclosure, err := r.ChassisSnapshot()
+	if err != nil {
+		return snapshot, err
+	}
+
+	if invalidSerial(enclosure.Serial) {
+		dumpInvalidPayload(device.Host, device.Vendor, device.HardwareType, enclosure, ErrInvalidSerial)
+		return snapshot, ErrInvalidSerial
+	}
+
+	snapshot.Chassis = model.NewChassisFromDevice(enclosure)
+	snapshot.Chassis.Managed = true
+	return snapshot, nil
+}
@@ -2,9 +2,12 @@ package filter
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 
@@ -17,25 +20,47 @@ var (
 	extendedFiltering = regexp.MustCompile(`filter\[(.+)\]\[(.+)\]`)
 )
 
+// operation builds the condition for field, which must be already quoted for the dialect in use
 func operation(field string, o string) string {
 	switch o {
 	case "ne":
-		return fmt.Sprintf("\"%s\" not in (?)", field)
+		return fmt.Sprintf("%s not in (?)", field)
 	case "gt":
-		return fmt.Sprintf("\"%s\" > ?", field)
+		return fmt.Sprintf("%s > ?", field)
 	case "ge":
-		return fmt.Sprintf("\"%s\" >= ?", field)
+		return fmt.Sprintf("%s >= ?", field)
 	case "lt":
-		return fmt.Sprintf("\"%s\" < ?", field)
+		return fmt.Sprintf("%s < ?", field)
 	case "le":
-		return fmt.Sprintf("\"%s\" <= ?", field)
+		return fmt.Sprintf("%s <= ?", field)
 	case "eq":
-		return fmt.Sprintf("\"%s\" in (?)", field)
+		return fmt.Sprintf("%s in (?)", field)
 	default:
 		return ""
 	}
 }
 
+// argument converts the values of time fields, not every dialect compares dates stored as text with RFC3339. The
+// ipv6 addresses are written the way they are stored, since they can be written in several ways
+func argument(fieldType reflect.Type, value string) (interface{}, error) {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	if fieldType != reflect.TypeOf(time.Time{}) {
+		if ip := net.ParseIP(value); ip != nil && strings.Contains(value, ":") {
+			return ip.String(), nil
+		}
+		return value, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid date %s, expected RFC3339", value), 400)
+	}
+	return t, nil
+}
+
 // Filter is meant to store the filters of requested via api
 type Filter struct {
 	Filter   map[string][]string
@@ -50,7 +75,16 @@ type Filters struct {
 // NewFilterSet returns an empty new filter structure
 func NewFilterSet(r *api2go.Request) (f *Filters, hasFilters bool) {
 	f = &Filters{}
-	for key, values := range r.QueryParams {
+
+	// the params are sorted, so the same request always builds the same query
+	keys := make([]string, 0, len(r.QueryParams))
+	for key := range r.QueryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := r.QueryParams[key]
 		filter := extendedFiltering.FindStringSubmatch(key)
 		if len(filter) == 0 {
 			filter = simpleFiltering.FindStringSubmatch(key)
@@ -98,10 +132,12 @@ func (f *Filters) BuildQuery(m interface{}, db *gorm.DB) (q *gorm.DB, err error)
 			rfctType := rfct.Type()
 
 			var structJSONMemberName string
+			var fieldType reflect.Type
 			for i := 0; i < rfctType.NumField(); i++ {
 				jsondName := rfctType.Field(i).Tag.Get("json")
 				if key == jsondName {
 					structJSONMemberName = jsondName
+					fieldType = rfctType.Field(i).Type
 					break
 				}
 			}
@@ -110,16 +146,25 @@ func (f *Filters) BuildQuery(m interface{}, db *gorm.DB) (q *gorm.DB, err error)
 				return q, err
 			}
 
-			op := operation(structJSONMemberName, filter.Operator)
+			op := operation(db.Dialect().Quote(structJSONMemberName), filter.Operator)
 			if op == "" {
 				return nil, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid filter operation: %s", filter.Operator), 400)
 			}
 
+			args := make([]interface{}, 0, len(values))
+			for _, value := range values {
+				arg, err := argument(fieldType, value)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+
 			if filter.Operator == "eq" || filter.Operator == "ne" {
-				q = q.Where(op, values)
+				q = q.Where(op, args)
 			} else {
-				for _, value := range values {
-					q = q.Where(op, value)
+				for _, arg := range args {
+					q = q.Where(op, arg)
 				}
 			}
 		}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jinzhu/gorm"
 	mocket "github.com/selvatico/go-mocket"
 
@@ -17,15 +19,15 @@ var testSet = []struct {
 	urlString string
 	sqlQuery  string
 }{
-	{"filter[model]=dell", "&{SELECT * FROM \"\"  WHERE (\"model\" = ?) [dell]}"},
-	{"filter[status]!=bad", "&{SELECT * FROM \"\"  WHERE (\"status\" != ?) [bad]}"},
-	{"filter[model][eq]=dell", "&{SELECT * FROM \"\"  WHERE (\"model\" = ?) [dell]}"},
-	{"filter[status][ne]=bad", "&{SELECT * FROM \"\"  WHERE (\"status\" != ?) [bad]}"},
+	{"filter[model]=dell", "&{SELECT * FROM \"\"  WHERE (\"model\" in (?)) [dell]}"},
+	{"filter[status]!=bad", "&{SELECT * FROM \"\"  WHERE (\"status\" not in (?)) [bad]}"},
+	{"filter[model][eq]=dell", "&{SELECT * FROM \"\"  WHERE (\"model\" in (?)) [dell]}"},
+	{"filter[status][ne]=bad", "&{SELECT * FROM \"\"  WHERE (\"status\" not in (?)) [bad]}"},
 	{"filter[temp_c][le]=3", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" <= ?) [3]}"},
 	{"filter[temp_c][lt]=3", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" < ?) [3]}"},
 	{"filter[temp_c][ge]=3", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" >= ?) [3]}"},
 	{"filter[temp_c][gt]=3", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" > ?) [3]}"},
-	{"filter[temp_c][gt]=3&filter[vendor]=Dell", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" > ?) AND (\"vendor\" = ?) [3 Dell]}"},
+	{"filter[temp_c][gt]=3&filter[vendor]=Dell", "&{SELECT * FROM \"\"  WHERE (\"temp_c\" > ?) AND (\"vendor\" in (?)) [3 Dell]}"},
 }
 
 func setupDB() *gorm.DB {
@@ -56,3 +58,88 @@ func TestEqualSignAndExclamationMark(t *testing.T) {
 		assert.Equal(t, testPair.sqlQuery, fmt.Sprintf("%s", q.QueryExpr()))
 	}
 }
+
+func TestDialectQuoting(t *testing.T) {
+	quoted := map[string]string{
+		"postgres": "\"temp_c\" > ?",
+		"mysql":    "`temp_c` > ?",
+		"sqlite3":  "\"temp_c\" > ?",
+	}
+
+	for dialect, expected := range quoted {
+		conn, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, err := gorm.Open(dialect, conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		filters := &Filters{}
+		filters.Add("temp_c", []string{"3"}, "gt")
+		q, err := filters.BuildQuery(model.Chassis{}, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Contains(t, fmt.Sprintf("%s", q.QueryExpr()), expected, dialect)
+		db.Close()
+	}
+}
+
+func TestTimeFilter(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("sqlite3", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// sqlite stores dates as text in its own format, they have to be sent as dates to compare them
+	mock.ExpectQuery("updated_at").WithArgs(time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)).WillReturnRows(sqlmock.NewRows([]string{"serial"}))
+
+	filters := &Filters{}
+	filters.Add("updated_at", []string{"2019-04-01T10:00:00Z"}, "lt")
+	q, err := filters.BuildQuery(model.Chassis{}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Find(&[]model.Chassis{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	filters.Clean()
+	filters.Add("updated_at", []string{"yesterday"}, "lt")
+	if _, err = filters.BuildQuery(model.Chassis{}, db); err == nil {
+		t.Error("expected an invalid date to be refused")
+	}
+}
+
+func TestIPv6Filter(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("sqlite3", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("ip").WithArgs("2001:db8::1", "10.0.0.1").WillReturnRows(sqlmock.NewRows([]string{"ip"}))
+
+	filters := &Filters{}
+	filters.Add("ip", []string{"2001:0DB8:0::1", "10.0.0.1"}, "eq")
+	q, err := filters.BuildQuery(model.ScannedPort{}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Find(&[]model.ScannedPort{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,38 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// CommandProvider runs an external command that prints the credentials for the target as a json list of
+// {"name": "", "username": "", "password": ""}. The command receives the host, site and vendor as arguments
+type CommandProvider struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewCommandProvider returns a provider running the given command, killed once the timeout is over
+func NewCommandProvider(path string, timeout time.Duration) *CommandProvider {
+	return &CommandProvider{path: path, timeout: timeout}
+}
+
+// Credentials runs the command and parses its output
+func (c *CommandProvider) Credentials(target Target) (creds []Credential, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.path, target.Host, target.Site, target.Vendor).Output()
+	if err != nil {
+		return creds, fmt.Errorf("credential command %s: %s", c.path, err)
+	}
+
+	if err = json.Unmarshal(output, &creds); err != nil {
+		return creds, fmt.Errorf("credential command %s: %s", c.path, err)
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,114 @@
+package credentials
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Credential is a named username and password pair used to log into a bmc
+type Credential struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Target describes the bmc we are looking credentials for, Vendor is empty until we are able to identify the device
+type Target struct {
+	Host   string
+	Site   string
+	Vendor string
+}
+
+// CredentialProvider returns the credentials that should be tried for a given target, in the order they should be tried
+type CredentialProvider interface {
+	Credentials(target Target) ([]Credential, error)
+}
+
+var (
+	provider CredentialProvider
+	once     sync.Once
+	setupErr error
+)
+
+// FromConfig builds the chain of providers listed in collector.credentials.providers
+func FromConfig() (CredentialProvider, error) {
+	once.Do(func() {
+		var chain Chain
+		for _, name := range viper.GetStringSlice("collector.credentials.providers") {
+			var p CredentialProvider
+			switch name {
+			case "static":
+				p = NewStaticProvider()
+			case "map":
+				p, setupErr = NewMapProvider()
+			case "file":
+				p, setupErr = NewFileProvider(viper.GetString("collector.credentials.file.path"), viper.GetString("collector.credentials.file.key_file"))
+			case "command":
+				p = NewCommandProvider(viper.GetString("collector.credentials.command.path"), time.Duration(viper.GetInt("collector.credentials.command.timeout"))*time.Second)
+			default:
+				setupErr = fmt.Errorf("unknown credential provider: %s", name)
+			}
+			if setupErr != nil {
+				return
+			}
+			chain = append(chain, p)
+		}
+		provider = chain
+	})
+
+	return provider, setupErr
+}
+
+// Chain queries all providers in order and returns their credentials, skipping the username and password pairs
+// that were already seen
+type Chain []CredentialProvider
+
+// Credentials returns the credentials of all providers of the chain. A provider failing doesn't prevent using
+// the others, the chain only fails when none of them found a credential
+func (c Chain) Credentials(target Target) (creds []Credential, err error) {
+	type pair struct{ username, password string }
+	seen := make(map[pair]bool)
+	var merror *multierror.Error
+	for _, p := range c {
+		found, err := p.Credentials(target)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "retrieving credentials", "ip": target.Host}).Warning(err)
+			merror = multierror.Append(merror, err)
+			continue
+		}
+
+		for _, cred := range found {
+			if seen[pair{cred.Username, cred.Password}] {
+				continue
+			}
+			seen[pair{cred.Username, cred.Password}] = true
+			creds = append(creds, cred)
+		}
+	}
+
+	if len(creds) > 0 {
+		return creds, nil
+	}
+	return creds, merror.ErrorOrNil()
+}
+
+// Prefer moves the credential with the given name to the front of the list
+func Prefer(creds []Credential, name string) []Credential {
+	if name == "" {
+		return creds
+	}
+
+	for i, cred := range creds {
+		if cred.Name == name {
+			sorted := append([]Credential{cred}, creds[:i]...)
+			return append(sorted, creds[i+1:]...)
+		}
+	}
+
+	return creds
+}
@@ -0,0 +1,110 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMapRules(t *testing.T) {
+	rules, err := parseRules([]Rule{
+		{Name: "site", Site: "ams4"},
+		{Name: "network", CIDR: "10.0.0.0/24"},
+		{Name: "dell", Vendor: "dell"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		target   Target
+		expected []string
+	}{
+		{Target{Host: "10.0.0.1", Site: "ams4"}, []string{"site", "network", "dell"}},
+		{Target{Host: "10.0.1.1", Site: "ams4", Vendor: "hp"}, []string{"site"}},
+		{Target{Host: "10.0.0.1", Site: "lhr4", Vendor: "dell"}, []string{"network", "dell"}},
+		{Target{Host: "10.0.1.1", Site: "lhr4", Vendor: "Dell"}, []string{"dell"}},
+	}
+
+	for _, tc := range tt {
+		creds := matching(rules, tc.target)
+		if len(creds) != len(tc.expected) {
+			t.Fatalf("%+v: expected %v, got %+v", tc.target, tc.expected, creds)
+		}
+		for i, cred := range creds {
+			if cred.Name != tc.expected[i] {
+				t.Errorf("%+v: expected %v, got %+v", tc.target, tc.expected, creds)
+			}
+		}
+	}
+
+	if _, err := parseRules([]Rule{{Name: "broken", CIDR: "10.0.0.0/33"}}); err == nil {
+		t.Error("expected an error for an invalid cidr")
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	creds := []Credential{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	sorted := Prefer(creds, "c")
+	if sorted[0].Name != "c" || sorted[1].Name != "a" || sorted[2].Name != "b" {
+		t.Errorf("unexpected order: %+v", sorted)
+	}
+
+	if sorted := Prefer(creds, "unknown"); sorted[0].Name != "a" {
+		t.Errorf("unexpected order: %+v", sorted)
+	}
+}
+
+// listProvider returns its credentials or its error
+type listProvider struct {
+	creds []Credential
+	err   error
+}
+
+func (p listProvider) Credentials(target Target) ([]Credential, error) {
+	return p.creds, p.err
+}
+
+func TestChain(t *testing.T) {
+	failing := listProvider{err: errors.New("command timed out")}
+	chain := Chain{
+		failing,
+		listProvider{creds: []Credential{{Name: "default", Username: "root", Password: "calvin"}}},
+		listProvider{creds: []Credential{{Name: "default", Username: "admin", Password: "admin"}, {Name: "dell", Username: "root", Password: "calvin"}}},
+	}
+
+	creds, err := chain.Credentials(Target{Host: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("a failing provider must not prevent using the others: %s", err)
+	}
+	if len(creds) != 2 || creds[0].Username != "root" || creds[1].Username != "admin" {
+		t.Errorf("expected the two distinct pairs in order, got %+v", creds)
+	}
+
+	if _, err := (Chain{failing, listProvider{}}).Credentials(Target{Host: "10.0.0.1"}); err == nil {
+		t.Error("expected an error when no provider found a credential")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	plaintext := []byte(`[{"name": "a", "username": "u", "password": "p"}]`)
+
+	payload, err := Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := Decrypt(key, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("expected %s, got %s", plaintext, decrypted)
+	}
+
+	if _, err := Decrypt(bytes.Repeat([]byte{2}, 32), payload); err == nil {
+		t.Error("expected an error decrypting with the wrong key")
+	}
+}
@@ -0,0 +1,111 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ErrInvalidKey is returned when the key isn't a hex encoded 32 bytes key
+var ErrInvalidKey = errors.New("the key must be 32 bytes encoded as hex")
+
+// ErrCiphertextTooShort is returned when the encrypted file is smaller than the nonce
+var ErrCiphertextTooShort = errors.New("encrypted credentials are too short")
+
+// FileProvider returns the credentials stored in an AES-GCM encrypted file, the file holds the same rules as the map provider
+type FileProvider struct {
+	rules []Rule
+}
+
+// NewFileProvider decrypts and loads the credential file using the key stored in keyFile
+func NewFileProvider(path string, keyFile string) (*FileProvider, error) {
+	key, err := ReadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := Decrypt(key, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err = json.Unmarshal(plaintext, &rules); err != nil {
+		return nil, err
+	}
+
+	rules, err = parseRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileProvider{rules: rules}, nil
+}
+
+// Credentials returns the credentials of the rules matching the target
+func (f *FileProvider) Credentials(target Target) ([]Credential, error) {
+	return matching(f.rules, target), nil
+}
+
+// ReadKey reads a hex encoded 32 bytes key from a file
+func ReadKey(keyFile string) (key []byte, err error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return key, err
+	}
+
+	key, err = hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != 32 {
+		return key, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// Encrypt seals the plaintext with AES-GCM, the nonce is prepended to the result
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a payload created by Encrypt
+func Decrypt(key []byte, payload []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < gcm.NonceSize() {
+		return nil, ErrCiphertextTooShort
+	}
+
+	return gcm.Open(nil, payload[:gcm.NonceSize()], payload[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Rule binds a credential to the sites, networks and vendors it's valid for, empty fields match everything
+type Rule struct {
+	Name     string `json:"name" mapstructure:"name"`
+	Username string `json:"username" mapstructure:"username"`
+	Password string `json:"password" mapstructure:"password"`
+	Site     string `json:"site" mapstructure:"site"`
+	CIDR     string `json:"cidr" mapstructure:"cidr"`
+	Vendor   string `json:"vendor" mapstructure:"vendor"`
+	network  *net.IPNet
+}
+
+// Matches tells whether the rule applies to the target. While the vendor is unknown we consider it a match, the
+// vendors are compared regardless of case since bmclib reports them as Dell or HP
+func (r *Rule) Matches(target Target) bool {
+	if r.Site != "" && r.Site != target.Site {
+		return false
+	}
+
+	if r.Vendor != "" && target.Vendor != "" && !strings.EqualFold(r.Vendor, target.Vendor) {
+		return false
+	}
+
+	if r.network != nil {
+		ip := net.ParseIP(target.Host)
+		if ip == nil || !r.network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseRules validates the rules and parses their networks
+func parseRules(rules []Rule) ([]Rule, error) {
+	for i := range rules {
+		if rules[i].Name == "" {
+			return rules, fmt.Errorf("credential rule %d has no name", i)
+		}
+
+		if rules[i].CIDR != "" {
+			_, network, err := net.ParseCIDR(rules[i].CIDR)
+			if err != nil {
+				return rules, fmt.Errorf("credential rule %s: %s", rules[i].Name, err)
+			}
+			rules[i].network = network
+		}
+	}
+
+	return rules, nil
+}
+
+// matching returns the credentials of all rules matching the target
+func matching(rules []Rule, target Target) (creds []Credential) {
+	for i := range rules {
+		if rules[i].Matches(target) {
+			creds = append(creds, Credential{
+				Name:     rules[i].Name,
+				Username: rules[i].Username,
+				Password: rules[i].Password,
+			})
+		}
+	}
+
+	return creds
+}
+
+// MapProvider returns the credentials configured per site and per network in collector.credentials.map
+type MapProvider struct {
+	rules []Rule
+}
+
+// NewMapProvider loads the rules from the config file
+func NewMapProvider() (*MapProvider, error) {
+	var rules []Rule
+	if err := viper.UnmarshalKey("collector.credentials.map", &rules); err != nil {
+		return nil, err
+	}
+
+	rules, err := parseRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MapProvider{rules: rules}, nil
+}
+
+// Credentials returns the credentials of the rules matching the target
+func (m *MapProvider) Credentials(target Target) ([]Credential, error) {
+	return matching(m.rules, target), nil
+}
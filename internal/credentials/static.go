@@ -0,0 +1,36 @@
+package credentials
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// StaticProvider returns the credentials set in bmc_user/bmc_pass followed by the vendor defaults in collector.default.<vendor>
+type StaticProvider struct{}
+
+// NewStaticProvider returns a provider for the credentials in the config file
+func NewStaticProvider() *StaticProvider {
+	return &StaticProvider{}
+}
+
+// Credentials returns the global credential and, once we know the vendor, the vendor default
+func (s *StaticProvider) Credentials(target Target) (creds []Credential, err error) {
+	if viper.IsSet("bmc_user") {
+		creds = append(creds, Credential{
+			Name:     "default",
+			Username: viper.GetString("bmc_user"),
+			Password: viper.GetString("bmc_pass"),
+		})
+	}
+
+	if target.Vendor != "" && viper.IsSet(fmt.Sprintf("collector.default.%s.username", target.Vendor)) {
+		creds = append(creds, Credential{
+			Name:     fmt.Sprintf("default.%s", target.Vendor),
+			Username: viper.GetString(fmt.Sprintf("collector.default.%s.username", target.Vendor)),
+			Password: viper.GetString(fmt.Sprintf("collector.default.%s.password", target.Vendor)),
+		})
+	}
+
+	return creds, err
+}
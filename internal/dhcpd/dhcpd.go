@@ -0,0 +1,283 @@
+// Package dhcpd reads the subnets of an isc dhcpd.conf. It follows the include statements and gives the subnets
+// the options of their shared network, group and the global scope they don't set themselves. Everything else of
+// the configuration, like hosts, pools and classes, is skipped.
+package dhcpd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth stops the include loops
+const maxIncludeDepth = 10
+
+// Subnet is a subnet or subnet6 declaration with the options it ends up with
+type Subnet struct {
+	Network       *net.IPNet
+	SharedNetwork string
+	Options       map[string]string
+}
+
+// IPv6 tells whether the subnet was declared with subnet6
+func (s *Subnet) IPv6() bool {
+	return s.Network.IP.To4() == nil
+}
+
+// token is a word, a quoted string or one of { } ; ,
+type token struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+// statement is a list of words ended by a semicolon or followed by a block of statements
+type statement struct {
+	words   []token
+	block   []statement
+	isBlock bool
+}
+
+// Read reads the subnets of the configuration file, relative includes are found from the directory of the file
+// including them
+func Read(path string) (subnets []*Subnet, err error) {
+	list, err := readFile(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return walk(list, nil, "", subnets)
+}
+
+// Parse reads the subnets of the configuration in content, relative includes are found from dir
+func Parse(content []byte, dir string) (subnets []*Subnet, err error) {
+	list, err := parse(content, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	return walk(list, nil, "", subnets)
+}
+
+// readFile parses a configuration file
+func readFile(path string, depth int) ([]statement, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := parse(content, filepath.Dir(path), depth)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return list, nil
+}
+
+// parse splits the configuration in statements and replaces the includes by the statements of their files
+func parse(content []byte, dir string, depth int) ([]statement, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("more than %d nested includes", maxIncludeDepth)
+	}
+
+	tokens, err := tokenize(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	list, rest, err := readStatements(tokens, dir, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected }", rest[0].line)
+	}
+	return list, nil
+}
+
+// readStatements reads statements until the end of the tokens or of the block and returns the tokens left
+func readStatements(tokens []token, dir string, depth int) (list []statement, rest []token, err error) {
+	var words []token
+	for len(tokens) > 0 {
+		t := tokens[0]
+		tokens = tokens[1:]
+
+		switch {
+		case t.text == ";" && !t.quoted:
+			if len(words) == 0 {
+				continue
+			}
+
+			if words[0].text == "include" && len(words) == 2 && words[1].quoted {
+				path := words[1].text
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				included, err := readFile(path, depth+1)
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %s", words[0].line, err)
+				}
+				list = append(list, included...)
+			} else {
+				list = append(list, statement{words: words})
+			}
+			words = nil
+		case t.text == "{" && !t.quoted:
+			var block []statement
+			if block, tokens, err = readStatements(tokens, dir, depth); err != nil {
+				return nil, nil, err
+			}
+			if len(tokens) == 0 {
+				return nil, nil, fmt.Errorf("line %d: block not closed", t.line)
+			}
+			tokens = tokens[1:]
+			list = append(list, statement{words: words, block: block, isBlock: true})
+			words = nil
+		case t.text == "}" && !t.quoted:
+			if len(words) > 0 {
+				return nil, nil, fmt.Errorf("line %d: missing ; before }", t.line)
+			}
+			return list, append([]token{t}, tokens...), nil
+		default:
+			words = append(words, t)
+		}
+	}
+
+	if len(words) > 0 {
+		return nil, nil, fmt.Errorf("line %d: missing ; at the end", words[len(words)-1].line)
+	}
+	return list, nil, nil
+}
+
+// tokenize splits the configuration in tokens, without its comments
+func tokenize(content string) (tokens []token, err error) {
+	line := 1
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			line++
+		case c == '"':
+			var text strings.Builder
+			start := line
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				text.WriteByte(content[i])
+			}
+			if i >= len(content) {
+				return nil, fmt.Errorf("line %d: string not closed", start)
+			}
+			tokens = append(tokens, token{text: text.String(), quoted: true, line: start})
+		case c == '{' || c == '}' || c == ';' || c == ',':
+			tokens = append(tokens, token{text: string(c), line: line})
+		default:
+			start := i
+			for i < len(content) && !strings.ContainsRune(" \t\r\n#\"{};,", rune(content[i])) {
+				i++
+			}
+			tokens = append(tokens, token{text: content[start:i], line: line})
+			i--
+		}
+	}
+	return tokens, nil
+}
+
+// walk appends the subnets declared in the statements, inherited are the options of the enclosing scopes
+func walk(list []statement, inherited map[string]string, sharedNetwork string, subnets []*Subnet) ([]*Subnet, error) {
+	options := scope(list, inherited)
+
+	var err error
+	for _, s := range list {
+		if !s.isBlock || len(s.words) == 0 {
+			continue
+		}
+
+		switch s.words[0].text {
+		case "subnet", "subnet6":
+			network, err := prefix(s.words)
+			if err != nil {
+				return nil, err
+			}
+
+			subnets = append(subnets, &Subnet{Network: network, SharedNetwork: sharedNetwork, Options: scope(s.block, options)})
+		case "shared-network":
+			if len(s.words) != 2 {
+				return nil, fmt.Errorf("line %d: shared-network needs a name", s.words[0].line)
+			}
+			if subnets, err = walk(s.block, options, s.words[1].text, subnets); err != nil {
+				return nil, err
+			}
+		case "group":
+			if subnets, err = walk(s.block, options, sharedNetwork, subnets); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return subnets, nil
+}
+
+// scope returns the inherited options overridden by the ones declared in the statements, wherever they are in
+// the scope. The pools and hosts in it keep theirs
+func scope(list []statement, inherited map[string]string) map[string]string {
+	options := make(map[string]string, len(inherited))
+	for name, value := range inherited {
+		options[name] = value
+	}
+	for _, s := range list {
+		// option definitions look like option foo code 224 = text;
+		if !s.isBlock && len(s.words) > 2 && s.words[0].text == "option" && s.words[2].text != "code" {
+			options[s.words[1].text] = value(s.words[2:])
+		}
+	}
+	return options
+}
+
+// prefix parses subnet 10.0.0.0 netmask 255.255.255.0 and subnet6 2001:db8::/64
+func prefix(words []token) (*net.IPNet, error) {
+	declaration := words[0]
+	if declaration.text == "subnet6" && len(words) == 2 {
+		_, network, err := net.ParseCIDR(words[1].text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid subnet6 %s", declaration.line, words[1].text)
+		}
+		return network, nil
+	}
+
+	if declaration.text == "subnet" && len(words) == 4 && words[2].text == "netmask" {
+		ip, mask := net.ParseIP(words[1].text).To4(), net.ParseIP(words[3].text).To4()
+		if ip == nil || mask == nil {
+			return nil, fmt.Errorf("line %d: invalid subnet %s netmask %s", declaration.line, words[1].text, words[3].text)
+		}
+
+		network := &net.IPNet{IP: ip.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+		if ones, bits := network.Mask.Size(); ones == 0 && bits == 0 {
+			return nil, fmt.Errorf("line %d: invalid netmask %s", declaration.line, words[3].text)
+		}
+		return network, nil
+	}
+
+	return nil, fmt.Errorf("line %d: invalid %s declaration", declaration.line, declaration.text)
+}
+
+// value joins the tokens of an option value, without the quotes of the strings
+func value(words []token) string {
+	var v strings.Builder
+	for i, w := range words {
+		if i > 0 && !(w.text == "," && !w.quoted) {
+			v.WriteByte(' ')
+		}
+		v.WriteString(w.text)
+	}
+	return v.String()
+}
@@ -0,0 +1,72 @@
+package dhcpd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	subnets, err := Read("testdata/dhcpd.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		network       string
+		sharedNetwork string
+		options       map[string]string
+	}{
+		{"192.168.0.0/24", "", map[string]string{"domain-name": "lhr4.bmc.example.com", "routers": "192.168.0.1", "domain-name-servers": "10.0.0.53, 10.0.1.53"}},
+		{"10.1.0.0/24", "ams4-bmc", map[string]string{"domain-name": "ams4.bmc.example.com", "routers": "10.1.0.1"}},
+		{"10.1.1.0/25", "ams4-bmc", map[string]string{"domain-name": "ams4.bmc.example.com", "dora-scan-profile": "kvm"}},
+		{"172.16.0.0/16", "", map[string]string{"domain-name": "group.bmc.example.com", "routers": "172.16.0.1"}},
+		{"2001:db8:1::/64", "", map[string]string{"domain-name": "example.com"}},
+	}
+
+	if len(subnets) != len(tt) {
+		t.Fatalf("expected %d subnets, got %d", len(tt), len(subnets))
+	}
+	for i, tc := range tt {
+		subnet := subnets[i]
+		if subnet.Network.String() != tc.network || subnet.SharedNetwork != tc.sharedNetwork {
+			t.Errorf("expected subnet %s of %q, got %s of %q", tc.network, tc.sharedNetwork, subnet.Network, subnet.SharedNetwork)
+		}
+		for name, value := range tc.options {
+			if subnet.Options[name] != value {
+				t.Errorf("subnet %s: expected option %s to be %q, got %q", tc.network, name, value, subnet.Options[name])
+			}
+		}
+	}
+
+	if !subnets[4].IPv6() || subnets[0].IPv6() {
+		t.Error("expected only the subnet6 to be ipv6")
+	}
+	if _, ok := subnets[0].Options["dora-scan-profile"]; ok {
+		t.Error("expected the option definition to be left out")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"missing semicolon", "subnet 10.0.0.0 netmask 255.0.0.0 {\n  option routers 10.0.0.1\n}\n", "line 3: missing ; before }"},
+		{"block not closed", "subnet 10.0.0.0 netmask 255.0.0.0 {\n  option routers 10.0.0.1;\n", "line 1: block not closed"},
+		{"unexpected brace", "}\n", "line 1: unexpected }"},
+		{"string not closed", "option domain-name \"example.com;\n", "line 1: string not closed"},
+		{"invalid subnet", "subnet 10.0.0.0 {\n}\n", "line 1: invalid subnet declaration"},
+		{"invalid netmask", "subnet 10.0.0.0 netmask 255.0.255.0 {\n}\n", "invalid netmask"},
+		{"invalid subnet6", "subnet6 2001:db8::/129 {\n}\n", "invalid subnet6"},
+		{"missing include", "include \"testdata/missing.conf\";\n", "no such file"},
+		{"include loop", "include \"testdata/loop.conf\";\n", "nested includes"},
+	}
+
+	for _, tc := range tt {
+		_, err := Parse([]byte(tc.content), ".")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error with %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package fixtures records the http exchanges between dora and a real bmc and replays them from a local tls server,
+// so the collectors can be tested without hardware. The bmclib providers we support only use ssh for actions like
+// power cycling and never during a collection, so http is all we need to capture.
+//
+// The recorder redacts the Authorization, Set-Cookie and X-Auth-Token headers but keeps the bodies as the bmc sent
+// them, check the bodies for session tokens, serial numbers you don't want to publish and the like before committing
+// a fixture.
+package fixtures
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// Exchange is a single request made to the bmc and the response it gave us
+type Exchange struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Fixture contains all the exchanges of a collection in the order they happened
+type Fixture struct {
+	Name      string      `json:"name"`
+	Exchanges []*Exchange `json:"exchanges"`
+}
+
+// Load reads a fixture from a json file
+func Load(path string) (fixture *Fixture, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixture, err
+	}
+
+	fixture = &Fixture{}
+	return fixture, json.Unmarshal(content, fixture)
+}
+
+// Save writes the fixture to a json file
+func (f *Fixture) Save(path string) (err error) {
+	content, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0644)
+}
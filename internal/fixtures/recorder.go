@@ -0,0 +1,126 @@
+package fixtures
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// Headers that describe the connection or change on every request, we don't want them in the fixtures
+var skippedHeaders = []string{"Connection", "Content-Length", "Date", "Keep-Alive", "Transfer-Encoding"}
+
+// Headers carrying credentials or session tokens of the bmc, their values are replaced before they reach the fixtures
+var redactedHeaders = []string{"Authorization", "Set-Cookie", "X-Auth-Token"}
+
+// redacted replaces the secret values in the fixtures
+const redacted = "redacted"
+
+// Recorder is a tls proxy in front of a real bmc that records every exchange going through it
+type Recorder struct {
+	server  *httptest.Server
+	fixture *Fixture
+	lock    sync.Mutex
+}
+
+// NewRecorder starts a proxy to the bmc listening on target, point the collector to Host() to record it
+func NewRecorder(name string, target string) *Recorder {
+	r := &Recorder{fixture: &Fixture{Name: name}}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "https"
+			req.URL.Host = target
+			req.Host = target
+		},
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		ModifyResponse: r.record,
+	}
+
+	r.server = httptest.NewTLSServer(proxy)
+	return r
+}
+
+// record stores the response and puts the body back for the client
+func (r *Recorder) record(resp *http.Response) (err error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	for _, name := range skippedHeaders {
+		header.Del(name)
+	}
+	redactHeader(header)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.fixture.Exchanges = append(r.fixture.Exchanges, &Exchange{
+		Method: resp.Request.Method,
+		URI:    resp.Request.URL.RequestURI(),
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   string(body),
+	})
+
+	return nil
+}
+
+// redactHeader replaces the values of the redacted headers, the cookies keep their name and attributes so the
+// replayed client still sends them back
+func redactHeader(header http.Header) {
+	for _, name := range redactedHeaders {
+		values := header[name]
+		if len(values) == 0 {
+			continue
+		}
+
+		header.Del(name)
+		for _, value := range values {
+			if name == "Set-Cookie" {
+				header.Add(name, redactCookie(value))
+			} else {
+				header.Add(name, redacted)
+			}
+		}
+	}
+}
+
+// redactCookie replaces the value of a Set-Cookie header, keeping the cookie name and its attributes
+func redactCookie(cookie string) string {
+	pair, attributes := cookie, ""
+	if i := strings.Index(cookie, ";"); i >= 0 {
+		pair, attributes = cookie[:i], cookie[i:]
+	}
+
+	if i := strings.Index(pair, "="); i >= 0 {
+		return pair[:i+1] + redacted + attributes
+	}
+	return redacted + attributes
+}
+
+// Host returns the address the collector should connect to
+func (r *Recorder) Host() string {
+	return strings.TrimPrefix(r.server.URL, "https://")
+}
+
+// Fixture returns everything recorded so far
+func (r *Recorder) Fixture() *Fixture {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.fixture
+}
+
+// Close stops the proxy
+func (r *Recorder) Close() {
+	r.server.Close()
+}
@@ -0,0 +1,28 @@
+package fixtures
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRedactHeader(t *testing.T) {
+	header := http.Header{
+		"Authorization": {"Basic YWRtaW46YWRtaW4="},
+		"Content-Type":  {"application/json"},
+		"Set-Cookie":    {"sessionKey=d2a9f3c1; Path=/; Secure", "lang=en"},
+		"X-Auth-Token":  {"b7c1e0aa"},
+	}
+
+	redactHeader(header)
+
+	expected := http.Header{
+		"Authorization": {"redacted"},
+		"Content-Type":  {"application/json"},
+		"Set-Cookie":    {"sessionKey=redacted; Path=/; Secure", "lang=redacted"},
+		"X-Auth-Token":  {"redacted"},
+	}
+	if !reflect.DeepEqual(header, expected) {
+		t.Errorf("expected %v, got %v", expected, header)
+	}
+}
@@ -0,0 +1,86 @@
+package fixtures
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Replayer is a tls server answering requests with the responses stored in a fixture
+type Replayer struct {
+	server    *httptest.Server
+	exchanges map[string][]*Exchange
+	served    map[string]int
+	missed    []string
+	lock      sync.Mutex
+}
+
+// NewReplayer starts a server for the fixture. Requests are matched by method and uri, falling back to the path
+// when the query differs. When the same request was recorded more than once the responses are given in the
+// recorded order and the last one is repeated after that
+func NewReplayer(fixture *Fixture) *Replayer {
+	r := &Replayer{
+		exchanges: make(map[string][]*Exchange),
+		served:    make(map[string]int),
+	}
+
+	for _, exchange := range fixture.Exchanges {
+		r.exchanges[exchange.Method+" "+exchange.URI] = append(r.exchanges[exchange.Method+" "+exchange.URI], exchange)
+		path := strings.SplitN(exchange.URI, "?", 2)[0]
+		if path != exchange.URI {
+			r.exchanges[exchange.Method+" "+path] = append(r.exchanges[exchange.Method+" "+path], exchange)
+		}
+	}
+
+	r.server = httptest.NewTLSServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+func (r *Replayer) serve(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	key := req.Method + " " + req.URL.RequestURI()
+	if _, found := r.exchanges[key]; !found {
+		key = req.Method + " " + req.URL.Path
+	}
+
+	exchanges, found := r.exchanges[key]
+	if !found {
+		r.missed = append(r.missed, req.Method+" "+req.URL.RequestURI())
+		r.lock.Unlock()
+		http.NotFound(w, req)
+		return
+	}
+
+	exchange := exchanges[len(exchanges)-1]
+	if r.served[key] < len(exchanges) {
+		exchange = exchanges[r.served[key]]
+	}
+	r.served[key]++
+	r.lock.Unlock()
+
+	for name, values := range exchange.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(exchange.Status)
+	w.Write([]byte(exchange.Body))
+}
+
+// Host returns the address the collector should connect to
+func (r *Replayer) Host() string {
+	return strings.TrimPrefix(r.server.URL, "https://")
+}
+
+// Missed returns the requests we had no recorded response for
+func (r *Replayer) Missed() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.missed
+}
+
+// Close stops the server
+func (r *Replayer) Close() {
+	r.server.Close()
+}
@@ -0,0 +1,363 @@
+package ipmi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+)
+
+// Sensor types we read, see the sensor type codes table of the ipmi spec
+const (
+	SensorTemperature = 0x01
+	SensorVoltage     = 0x02
+	SensorCurrent     = 0x03
+	SensorFan         = 0x04
+	SensorPowerSupply = 0x08
+)
+
+// Sensor units we read, see the sensor unit type codes table of the ipmi spec
+const (
+	UnitCelsius = 1
+	UnitVolts   = 4
+	UnitAmps    = 5
+	UnitWatts   = 6
+	UnitRPM     = 18
+)
+
+// Sizes of the chunks we read the fru and sdr with, small enough to fit any bmc buffer
+const (
+	fruChunk = 16
+	sdrChunk = 16
+)
+
+// manufacturers maps the iana enterprise numbers to the vendor names bmclib uses
+var manufacturers = map[uint32]string{
+	2:     "IBM",
+	11:    devices.HP,
+	343:   "Intel",
+	674:   devices.Dell,
+	2011:  "Huawei",
+	5771:  "Cisco",
+	7244:  devices.Quanta,
+	10368: "Fujitsu",
+	10876: devices.Supermicro,
+	19046: "Lenovo",
+}
+
+// DeviceID identifies the bmc
+type DeviceID struct {
+	ManufacturerID uint32
+	ProductID      uint16
+	Firmware       string
+}
+
+// Manufacturer returns the name of the bmc manufacturer or devices.Unknown
+func (d *DeviceID) Manufacturer() string {
+	if name, ok := manufacturers[d.ManufacturerID]; ok {
+		return name
+	}
+	return devices.Unknown
+}
+
+// FRU holds the text fields of the chassis, board and product areas of the fru 0, the one describing the server
+type FRU struct {
+	ChassisPartNumber   string
+	ChassisSerial       string
+	BoardManufacturer   string
+	BoardProduct        string
+	BoardSerial         string
+	BoardPartNumber     string
+	ProductManufacturer string
+	ProductName         string
+	ProductPartNumber   string
+	ProductVersion      string
+	ProductSerial       string
+	AssetTag            string
+}
+
+// Sensor is the converted reading of an analog sensor of the sdr repository
+type Sensor struct {
+	Number byte
+	Name   string
+	Type   byte
+	Unit   byte
+	Value  float64
+}
+
+// DeviceID reads the manufacturer and firmware of the bmc
+func (c *Client) DeviceID() (*DeviceID, error) {
+	data, err := c.request(netFnApp, 0x01, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 11 {
+		return nil, errShortPacket
+	}
+
+	return &DeviceID{
+		ManufacturerID: uint32(data[6]) | uint32(data[7])<<8 | uint32(data[8]&0x0f)<<16,
+		ProductID:      binary.LittleEndian.Uint16(data[9:11]),
+		Firmware:       fmt.Sprintf("%d.%02x", data[2]&0x7f, data[3]),
+	}, nil
+}
+
+// PowerState returns on or off
+func (c *Client) PowerState() (string, error) {
+	data, err := c.request(netFnChassis, 0x01, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < 1 {
+		return "", errShortPacket
+	}
+
+	if data[0]&0x01 != 0 {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+// FRU reads and parses the fru of the server
+func (c *Client) FRU() (*FRU, error) {
+	data, err := c.request(netFnStorage, 0x10, []byte{0x00})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 2 {
+		return nil, errShortPacket
+	}
+
+	size := int(binary.LittleEndian.Uint16(data[0:2]))
+	image := make([]byte, 0, size)
+	for len(image) < size {
+		count := fruChunk
+		if size-len(image) < count {
+			count = size - len(image)
+		}
+
+		offset := len(image)
+		data, err = c.request(netFnStorage, 0x11, []byte{0x00, byte(offset), byte(offset >> 8), byte(count)})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) < 2 || data[0] == 0 || len(data) < 1+int(data[0]) {
+			return nil, errShortPacket
+		}
+		image = append(image, data[1:1+int(data[0])]...)
+	}
+
+	return parseFRU(image)
+}
+
+// Sensors reads the analog sensors of the sdr repository, the ones without a reading are left out
+func (c *Client) Sensors() (sensors []Sensor, err error) {
+	data, err := c.request(netFnStorage, 0x22, nil)
+	if err != nil {
+		return sensors, err
+	}
+
+	if len(data) < 2 {
+		return sensors, errShortPacket
+	}
+	reservation := data[0:2]
+
+	// A broken repository linking records in a loop would be read forever, each record is read once
+	visited := make(map[uint16]bool)
+	for id := uint16(0); id != 0xffff && !visited[id]; {
+		visited[id] = true
+		record, next, err := c.readSDR(reservation, id, 0, 5)
+		if err != nil {
+			return sensors, err
+		}
+
+		for length := 5 + int(record[4]); len(record) < length; {
+			count := length - len(record)
+			if count > sdrChunk {
+				count = sdrChunk
+			}
+
+			chunk, _, err := c.readSDR(reservation, id, len(record), count)
+			if err != nil {
+				return sensors, err
+			}
+			record = append(record, chunk...)
+		}
+
+		if sensor, ok := parseFullSensor(record); ok {
+			data, err = c.request(netFnSensor, 0x2d, []byte{sensor.Number})
+			if err != nil {
+				return sensors, err
+			}
+
+			// Skip the sensors whose reading is unavailable or that aren't scanned
+			if len(data) >= 2 && data[1]&0x20 == 0 && data[1]&0x40 != 0 {
+				sensor.Value = convert(record, data[0])
+				sensors = append(sensors, sensor)
+			}
+		}
+
+		id = next
+	}
+
+	return sensors, nil
+}
+
+// readSDR reads part of a sdr record and returns the id of the next one
+func (c *Client) readSDR(reservation []byte, id uint16, offset int, count int) (data []byte, next uint16, err error) {
+	request := append([]byte(nil), reservation...)
+	request = append(request, byte(id), byte(id>>8), byte(offset), byte(count))
+	data, err = c.request(netFnStorage, 0x23, request)
+	if err != nil {
+		return data, next, err
+	}
+
+	if len(data) < 2+count {
+		return data, next, errShortPacket
+	}
+
+	return data[2:], binary.LittleEndian.Uint16(data[0:2]), nil
+}
+
+// parseFRU reads the text fields of the chassis, board and product areas
+func parseFRU(image []byte) (*FRU, error) {
+	if len(image) < 8 || image[0] != 0x01 || checksum(image[:7]) != image[7] {
+		return nil, fmt.Errorf("invalid fru common header")
+	}
+
+	fru := &FRU{}
+	area := func(offset byte, skip int) []string {
+		start := int(offset) * 8
+		if offset == 0 || start+2 > len(image) {
+			return nil
+		}
+		end := start + int(image[start+1])*8
+		if end > len(image) {
+			end = len(image)
+		}
+		// The fru comes from the bmc, an empty or cut short area is skipped
+		if start+skip > end {
+			return nil
+		}
+		return fields(image[start+skip : end])
+	}
+
+	if f := area(image[2], 3); len(f) >= 2 {
+		fru.ChassisPartNumber, fru.ChassisSerial = f[0], f[1]
+	}
+
+	if f := area(image[3], 6); len(f) >= 4 {
+		fru.BoardManufacturer, fru.BoardProduct, fru.BoardSerial, fru.BoardPartNumber = f[0], f[1], f[2], f[3]
+	}
+
+	if f := area(image[4], 3); len(f) >= 6 {
+		fru.ProductManufacturer, fru.ProductName, fru.ProductPartNumber, fru.ProductVersion, fru.ProductSerial, fru.AssetTag = f[0], f[1], f[2], f[3], f[4], f[5]
+	}
+
+	return fru, nil
+}
+
+// fields decodes the type/length encoded fields of an area until the end marker
+func fields(b []byte) (values []string) {
+	for len(b) > 0 && b[0] != 0xc1 {
+		kind, length := b[0]>>6, int(b[0]&0x3f)
+		if 1+length > len(b) {
+			break
+		}
+		value := b[1 : 1+length]
+		b = b[1+length:]
+
+		switch kind {
+		case 0:
+			values = append(values, hex.EncodeToString(value))
+		case 1:
+			values = append(values, bcdPlus(value))
+		case 2:
+			values = append(values, sixBitASCII(value))
+		default:
+			values = append(values, strings.TrimSpace(strings.TrimRight(string(value), "\x00")))
+		}
+	}
+	return values
+}
+
+func bcdPlus(b []byte) string {
+	const digits = "0123456789 -.:,_"
+	var s strings.Builder
+	for _, c := range b {
+		s.WriteByte(digits[c>>4])
+		s.WriteByte(digits[c&0x0f])
+	}
+	return strings.TrimSpace(s.String())
+}
+
+// sixBitASCII unpacks 4 characters from every 3 bytes, starting from the least significant bits
+func sixBitASCII(b []byte) string {
+	var s strings.Builder
+	var acc uint32
+	var bits uint
+	for _, c := range b {
+		acc |= uint32(c) << bits
+		bits += 8
+		for bits >= 6 {
+			s.WriteByte(byte(acc&0x3f) + 0x20)
+			acc >>= 6
+			bits -= 6
+		}
+	}
+	return strings.TrimSpace(s.String())
+}
+
+// parseFullSensor returns the identity of a threshold based full sensor record, the only ones with a linear reading
+func parseFullSensor(record []byte) (sensor Sensor, ok bool) {
+	if len(record) < 48 || record[3] != 0x01 || record[20]>>6 == 0x03 || record[23]&0x7f != 0 {
+		return sensor, false
+	}
+
+	sensor.Number = record[7]
+	sensor.Type = record[12]
+	sensor.Unit = record[21]
+
+	if length := int(record[47] & 0x1f); 48+length <= len(record) {
+		sensor.Name = strings.TrimRight(string(record[48:48+length]), "\x00")
+	}
+
+	return sensor, true
+}
+
+// convert applies y = (M*x + B*10^Bexp) * 10^Rexp to the raw reading of a full sensor record
+func convert(record []byte, raw byte) float64 {
+	x := float64(raw)
+	switch record[20] >> 6 {
+	case 0x01:
+		x = float64(int8(raw))
+		if int8(raw) < 0 {
+			x++
+		}
+	case 0x02:
+		x = float64(int8(raw))
+	}
+
+	m := signed(uint16(record[24])|uint16(record[25]>>6)<<8, 10)
+	b := signed(uint16(record[26])|uint16(record[27]>>6)<<8, 10)
+	rExp := signed(uint16(record[29]>>4), 4)
+	bExp := signed(uint16(record[29]&0x0f), 4)
+
+	value := (float64(m)*x + float64(b)*math.Pow10(bExp)) * math.Pow10(rExp)
+	return math.Round(value*1000) / 1000
+}
+
+// signed interprets the lowest bits of v as a two's complement number
+func signed(v uint16, bits uint) int {
+	if v&(1<<(bits-1)) != 0 {
+		return int(v) - 1<<bits
+	}
+	return int(v)
+}
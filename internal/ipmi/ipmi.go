@@ -0,0 +1,372 @@
+// Package ipmi is a minimal ipmi over lan client, enough to read the inventory of the bmcs that only expose
+// udp 623. It speaks rmcp+ (ipmi 2.0) with cipher suite 3, the one every bmc supports and most enable by
+// default, and opens user level sessions since we only read. The package also has a Responder playing the
+// bmc side of the protocol for tests and the simulator.
+package ipmi
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	bmcerrors "github.com/bmc-toolbox/bmclib/errors"
+)
+
+const (
+	// Port is where the bmcs listen for rmcp packets
+	Port = 623
+	// HardwareType is the bmc type of the servers collected through ipmi
+	HardwareType = "ipmi"
+)
+
+// Privilege levels, nameOnlyLookup asks the bmc to find the user by name only when opening the session
+const (
+	privilegeUser  = 0x02
+	nameOnlyLookup = 0x10
+)
+
+// RAKP status codes meaning the bmc refused the user
+const (
+	rakpInvalidRole      = 0x09
+	rakpUnauthorizedName = 0x0d
+	rakpInvalidIntegrity = 0x0f
+)
+
+var (
+	// ErrNoResponse is returned when the bmc doesn't answer any of the retries
+	ErrNoResponse = errors.New("no ipmi response from the bmc")
+	// ErrRMCPPlusUnsupported is returned when the bmc only speaks ipmi 1.5
+	ErrRMCPPlusUnsupported = errors.New("the bmc doesn't support rmcp+")
+	// ErrNoSession is returned when a command needing a session is sent before CheckCredentials
+	ErrNoSession = errors.New("no ipmi session, call CheckCredentials first")
+	// ErrAborted is returned by the requests of a client once it's aborted
+	ErrAborted = errors.New("ipmi client aborted")
+)
+
+// CompletionError is returned when the bmc answers a request with a completion code other than success
+type CompletionError struct {
+	NetFn byte
+	Cmd   byte
+	Code  byte
+}
+
+func (e *CompletionError) Error() string {
+	return fmt.Sprintf("ipmi command %#02x of netfn %#02x failed with completion code %#02x", e.Cmd, e.NetFn, e.Code)
+}
+
+// Client talks to a single bmc, it's not safe for concurrent use except for Abort
+type Client struct {
+	host     string
+	username string
+	password string
+	timeout  time.Duration
+	retries  int
+
+	// mu guards the socket against Abort
+	mu      sync.Mutex
+	conn    net.Conn
+	aborted bool
+	rqSeq   byte
+
+	// Filled once the session is established
+	sessionID uint32
+	sequence  uint32
+	keys      *keys
+}
+
+// New returns a client for the bmc, host may carry a port when the bmc doesn't listen on the standard one
+func New(host string, username string, password string) *Client {
+	return &Client{host: host, username: username, password: password, timeout: time.Second, retries: 3}
+}
+
+func (c *Client) address() string {
+	if _, _, err := net.SplitHostPort(c.host); err == nil {
+		return c.host
+	}
+	return net.JoinHostPort(c.host, strconv.Itoa(Port))
+}
+
+func (c *Client) dial() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.aborted {
+		return ErrAborted
+	}
+	if c.conn != nil {
+		return nil
+	}
+	c.conn, err = net.Dial("udp", c.address())
+	return err
+}
+
+// SupportsRMCPPlus asks the bmc for its authentication capabilities, it doesn't need a session
+func (c *Client) SupportsRMCPPlus() (bool, error) {
+	if err := c.dial(); err != nil {
+		return false, err
+	}
+
+	// Extended data of the current channel for a user level session
+	data, err := c.request(netFnApp, 0x38, []byte{0x8e, privilegeUser})
+	if err != nil {
+		return false, err
+	}
+
+	if len(data) < 4 {
+		return false, errShortPacket
+	}
+
+	return data[1]&0x80 != 0 && data[3]&0x02 != 0, nil
+}
+
+// UpdateCredentials changes the credentials used by the next CheckCredentials
+func (c *Client) UpdateCredentials(username string, password string) {
+	c.username = username
+	c.password = password
+}
+
+// CheckCredentials opens a session with the current credentials, it returns ErrLoginFailed when the bmc refuses them
+func (c *Client) CheckCredentials() error {
+	if c.keys != nil {
+		c.closeSession()
+	}
+
+	if err := c.dial(); err != nil {
+		return err
+	}
+
+	return c.openSession()
+}
+
+// Close ends the session and releases the socket
+func (c *Client) Close() error {
+	if c.keys != nil {
+		c.closeSession()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Abort closes the socket from another goroutine, the request in progress and the next ones fail right away
+func (c *Client) Abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.aborted = true
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// openSession runs the open session request and the four RAKP messages, deriving the keys of the session
+func (c *Client) openSession() error {
+	consoleID, err := randomUint32()
+	if err != nil {
+		return err
+	}
+
+	open := []byte{0x00, privilegeUser, 0x00, 0x00}
+	open = appendUint32(open, consoleID)
+	open = append(open,
+		0x00, 0x00, 0x00, 0x08, authRAKPHMACSHA1, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x08, integrityHMACSHA196, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x08, confidentialityAES, 0x00, 0x00, 0x00,
+	)
+	response, err := c.exchange(&packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadOpenSessionRequest, payload: open}, payloadOpenSessionResponse)
+	if err != nil {
+		return err
+	}
+	if len(response) < 12 {
+		return errShortPacket
+	}
+	if response[1] != 0 {
+		return fmt.Errorf("ipmi open session failed with status %#02x", response[1])
+	}
+	if binary.LittleEndian.Uint32(response[4:8]) != consoleID {
+		return fmt.Errorf("ipmi open session answered for another session")
+	}
+	sessionID := response[8:12]
+
+	rm := make([]byte, 16)
+	if _, err = rand.Read(rm); err != nil {
+		return err
+	}
+	role := byte(privilegeUser | nameOnlyLookup)
+	user := []byte(c.username)
+	kuid := userKey(c.password)
+
+	rakp1 := append([]byte{0x00, 0x00, 0x00, 0x00}, sessionID...)
+	rakp1 = append(rakp1, rm...)
+	rakp1 = append(rakp1, role, 0x00, 0x00, byte(len(user)))
+	rakp1 = append(rakp1, user...)
+	response, err = c.exchange(&packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP1, payload: rakp1}, payloadRAKP2)
+	if err != nil {
+		return err
+	}
+	if len(response) >= 2 && (response[1] == rakpUnauthorizedName || response[1] == rakpInvalidRole) {
+		return bmcerrors.ErrLoginFailed
+	}
+	if len(response) < 60 {
+		return errShortPacket
+	}
+	if response[1] != 0 {
+		return fmt.Errorf("ipmi rakp 2 failed with status %#02x", response[1])
+	}
+	consoleIDBytes, rc, guid := response[4:8], response[8:24], response[24:40]
+
+	expected := hmacSHA1(kuid, consoleIDBytes, sessionID, rm, rc, guid, []byte{role, byte(len(user))}, user)
+	if !hmac.Equal(expected, response[40:60]) {
+		return bmcerrors.ErrLoginFailed
+	}
+
+	rakp3 := append([]byte{0x00, 0x00, 0x00, 0x00}, sessionID...)
+	rakp3 = append(rakp3, hmacSHA1(kuid, rc, consoleIDBytes, []byte{role, byte(len(user))}, user)...)
+	response, err = c.exchange(&packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP3, payload: rakp3}, payloadRAKP4)
+	if err != nil {
+		return err
+	}
+	if len(response) >= 2 && response[1] == rakpInvalidIntegrity {
+		return bmcerrors.ErrLoginFailed
+	}
+	if len(response) < 8+integrityLength {
+		return errShortPacket
+	}
+	if response[1] != 0 {
+		return fmt.Errorf("ipmi rakp 4 failed with status %#02x", response[1])
+	}
+
+	sik := hmacSHA1(kuid, rm, rc, []byte{role, byte(len(user))}, user)
+	if !hmac.Equal(hmacSHA1(sik, rm, sessionID, guid)[:integrityLength], response[8:8+integrityLength]) {
+		return fmt.Errorf("ipmi rakp 4 integrity check failed")
+	}
+
+	c.sessionID = binary.LittleEndian.Uint32(sessionID)
+	c.sequence = 1
+	c.keys = newKeys(sik)
+	return nil
+}
+
+func (c *Client) closeSession() {
+	id := appendUint32(nil, c.sessionID)
+	c.request(netFnApp, 0x3c, id)
+	c.keys = nil
+	c.sessionID = 0
+}
+
+// request sends an ipmi command, inside the session when there's one, and returns the response data without the completion code
+func (c *Client) request(netFn byte, cmd byte, data []byte) ([]byte, error) {
+	if c.conn == nil {
+		return nil, ErrNoSession
+	}
+
+	c.rqSeq = (c.rqSeq + 1) & 0x3f
+	m := &message{netFn: netFn, cmd: cmd, seq: c.rqSeq, data: data}
+	p := &packet{class: rmcpClassIPMI, authType: authTypeNone, payload: m.marshal(bmcAddress, consoleAddress)}
+	if c.keys != nil {
+		p.authType = authTypeRMCPPlus
+		p.payloadType = payloadIPMI
+		p.sessionID = c.sessionID
+		p.sequence = c.sequence
+		c.sequence++
+	}
+
+	var response *message
+	_, err := c.roundTrip(p, func(r *packet) bool {
+		if r.payloadType != payloadIPMI {
+			return false
+		}
+		m, err := unmarshalMessage(r.payload)
+		if err != nil || m.netFn != netFn+1 || m.cmd != cmd || m.seq != c.rqSeq {
+			return false
+		}
+		response = m
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.data) == 0 {
+		return nil, errShortPacket
+	}
+
+	if response.data[0] != 0 {
+		return nil, &CompletionError{NetFn: netFn, Cmd: cmd, Code: response.data[0]}
+	}
+
+	return response.data[1:], nil
+}
+
+// exchange sends one of the session establishment messages and returns the payload of the expected answer
+func (c *Client) exchange(p *packet, payloadType byte) ([]byte, error) {
+	return c.roundTrip(p, func(r *packet) bool {
+		return r.authType == authTypeRMCPPlus && r.payloadType == payloadType
+	})
+}
+
+// roundTrip sends the packet until a response accepted by match arrives or the retries are exhausted
+func (c *Client) roundTrip(p *packet, match func(*packet) bool) ([]byte, error) {
+	b, err := p.marshal(c.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1024)
+	for try := 0; try < c.retries; try++ {
+		if _, err = c.conn.Write(b); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(c.timeout)
+		for {
+			if err = c.conn.SetReadDeadline(deadline); err != nil {
+				return nil, err
+			}
+
+			n, err := c.conn.Read(buf)
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			r, err := unmarshalPacket(append([]byte(nil), buf[:n]...), c.keys)
+			if err != nil || r.class != rmcpClassIPMI {
+				continue
+			}
+
+			if match(r) {
+				return r.payload, nil
+			}
+		}
+	}
+
+	return nil, ErrNoResponse
+}
+
+func randomUint32() (uint32, error) {
+	b := make([]byte, 4)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return 0, err
+		}
+		if id := binary.LittleEndian.Uint32(b); id != 0 {
+			return id, nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ipmi
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/errors"
+)
+
+func startResponder(t *testing.T) (host string, close func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responder := &Responder{
+		Username: "Priest",
+		Password: "Wololo",
+		DeviceID: DeviceID{ManufacturerID: 10876, ProductID: 0x0994, Firmware: "3.88"},
+		PowerOn:  true,
+		FRU: FRU{
+			ChassisSerial:       "C8150LK19A00012",
+			BoardManufacturer:   "Supermicro",
+			BoardProduct:        "X11DPU",
+			BoardSerial:         "ZM19AS011234",
+			ProductManufacturer: "Supermicro",
+			ProductName:         "SYS-1029U-TR4",
+			ProductSerial:       "S34900710912345",
+		},
+		Sensors: []Sensor{
+			{Name: "CPU1 Temp", Type: SensorTemperature, Unit: UnitCelsius, Value: 48},
+			{Name: "Inlet Temp", Type: SensorTemperature, Unit: UnitCelsius, Value: 23},
+			{Name: "FAN1", Type: SensorFan, Unit: UnitRPM, Value: 5600},
+			{Name: "PW Consumption", Type: SensorPowerSupply, Unit: UnitWatts, Value: 310},
+		},
+	}
+	go responder.Serve(conn)
+
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestCheckCredentials(t *testing.T) {
+	host, close := startResponder(t)
+	defer close()
+
+	c := New(host, "Priest", "Wrong")
+	defer c.Close()
+
+	supported, err := c.SupportsRMCPPlus()
+	if err != nil || !supported {
+		t.Fatalf("expected rmcp+ support: %v", err)
+	}
+
+	if err = c.CheckCredentials(); err != errors.ErrLoginFailed {
+		t.Errorf("expected %s with the wrong password, got %v", errors.ErrLoginFailed, err)
+	}
+
+	c.UpdateCredentials("Monk", "Wololo")
+	if err = c.CheckCredentials(); err != errors.ErrLoginFailed {
+		t.Errorf("expected %s with the wrong user, got %v", errors.ErrLoginFailed, err)
+	}
+
+	c.UpdateCredentials("Priest", "Wololo")
+	if err = c.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.DeviceID(); err != nil {
+		t.Errorf("an established session must accept commands: %s", err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	host, close := startResponder(t)
+	defer close()
+
+	c := New(host, "Priest", "Wololo")
+	defer c.Close()
+
+	if err := c.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Abort()
+	if _, err := c.DeviceID(); err == nil {
+		t.Error("expected the requests of an aborted client to fail")
+	}
+	if err := c.CheckCredentials(); err != ErrAborted {
+		t.Errorf("expected %s once aborted, got %v", ErrAborted, err)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	host, close := startResponder(t)
+	defer close()
+
+	c := New(host, "Priest", "Wololo")
+	defer c.Close()
+
+	if err := c.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := c.DeviceID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Manufacturer() != "Supermicro" || id.Firmware != "3.88" {
+		t.Errorf("unexpected manufacturer %q or firmware %q", id.Manufacturer(), id.Firmware)
+	}
+
+	state, err := c.PowerState()
+	if err != nil || state != "on" {
+		t.Errorf("expected power on, got %q: %v", state, err)
+	}
+
+	fru, err := c.FRU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fru.ProductSerial != "S34900710912345" || fru.ProductName != "SYS-1029U-TR4" || fru.BoardSerial != "ZM19AS011234" || fru.ChassisSerial != "C8150LK19A00012" {
+		t.Errorf("unexpected fru %+v", fru)
+	}
+
+	sensors, err := c.Sensors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sensors) != 4 {
+		t.Fatalf("expected 4 sensors, got %d", len(sensors))
+	}
+	if sensors[1].Name != "Inlet Temp" || sensors[1].Value != 23 || sensors[2].Value != 5600 || sensors[3].Value != 310 {
+		t.Errorf("unexpected sensors %+v", sensors)
+	}
+}
+
+func TestParseBrokenFRU(t *testing.T) {
+	fru := FRU{ChassisSerial: "C8150LK19A00012", BoardSerial: "ZM19AS011234", ProductSerial: "S34900710912345"}
+	image := fru.image()
+	board := int(image[3]) * 8
+
+	// A board area with a zero length is skipped, the others are still read
+	empty := append([]byte(nil), image...)
+	empty[board+1] = 0
+	parsed, err := parseFRU(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.BoardSerial != "" || parsed.ChassisSerial != "C8150LK19A00012" || parsed.ProductSerial != "S34900710912345" {
+		t.Errorf("expected the board area to be skipped, got %+v", parsed)
+	}
+
+	// An image cut short in the middle of the board area header
+	parsed, err = parseFRU(image[:board+3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.BoardSerial != "" || parsed.ProductSerial != "" {
+		t.Errorf("expected the missing areas to be skipped, got %+v", parsed)
+	}
+}
+
+func TestSixBitASCII(t *testing.T) {
+	// "IPMI" packed as 6 bits characters
+	if s := sixBitASCII([]byte{0x29, 0xdc, 0xa6}); s != "IPMI" {
+		t.Errorf("expected IPMI, got %q", s)
+	}
+}
@@ -0,0 +1,309 @@
+package ipmi
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// rmcp header, we never ask for acknowledgements
+const (
+	rmcpVersion   = 0x06
+	rmcpNoAck     = 0xff
+	rmcpClassASF  = 0x06
+	rmcpClassIPMI = 0x07
+)
+
+// asf presence ping and pong, answered by every bmc supporting ipmi over lan
+const (
+	asfIANA = 0x000011be
+	asfPing = 0x80
+	asfPong = 0x40
+)
+
+// Session authentication types, ipmi 1.5 session-less packets use none
+const (
+	authTypeNone     = 0x00
+	authTypeRMCPPlus = 0x06
+)
+
+// Payload types of the rmcp+ session header
+const (
+	payloadIPMI                = 0x00
+	payloadOpenSessionRequest  = 0x10
+	payloadOpenSessionResponse = 0x11
+	payloadRAKP1               = 0x12
+	payloadRAKP2               = 0x13
+	payloadRAKP3               = 0x14
+	payloadRAKP4               = 0x15
+
+	payloadEncrypted     = 0x80
+	payloadAuthenticated = 0x40
+	payloadTypeMask      = 0x3f
+)
+
+// Addresses of the bmc and the remote console in ipmi messages
+const (
+	bmcAddress     = 0x20
+	consoleAddress = 0x81
+)
+
+// Network functions of the requests we send, responses use the next odd number
+const (
+	netFnChassis = 0x00
+	netFnSensor  = 0x04
+	netFnApp     = 0x06
+	netFnStorage = 0x0a
+)
+
+// Cipher suite 3: RAKP-HMAC-SHA1 authentication, HMAC-SHA1-96 integrity and AES-CBC-128 confidentiality
+const (
+	authRAKPHMACSHA1    = 0x01
+	integrityHMACSHA196 = 0x01
+	confidentialityAES  = 0x01
+
+	integrityLength = 12
+)
+
+var (
+	errShortPacket = errors.New("ipmi packet too short")
+	errChecksum    = errors.New("ipmi message checksum mismatch")
+	errIntegrity   = errors.New("ipmi packet integrity check failed")
+)
+
+// message is an ipmi request or response, responses carry the completion code in data[0]
+type message struct {
+	netFn byte
+	cmd   byte
+	seq   byte
+	data  []byte
+}
+
+// marshal encodes the message going from src to dst with its two checksums
+func (m *message) marshal(dst byte, src byte) []byte {
+	b := []byte{dst, m.netFn << 2}
+	b = append(b, checksum(b))
+	b = append(b, src, m.seq<<2, m.cmd)
+	b = append(b, m.data...)
+	return append(b, checksum(b[3:]))
+}
+
+func unmarshalMessage(b []byte) (*message, error) {
+	if len(b) < 7 {
+		return nil, errShortPacket
+	}
+
+	if checksum(b[:2]) != b[2] || checksum(b[3:len(b)-1]) != b[len(b)-1] {
+		return nil, errChecksum
+	}
+
+	return &message{netFn: b[1] >> 2, seq: b[4] >> 2, cmd: b[5], data: b[6 : len(b)-1]}, nil
+}
+
+// checksum is the two's complement of the sum of the bytes
+func checksum(b []byte) (sum byte) {
+	for _, c := range b {
+		sum += c
+	}
+	return -sum
+}
+
+// keys secure the packets of an established session
+type keys struct {
+	integrity []byte
+	aes       []byte
+}
+
+// newKeys derives the integrity and confidentiality keys from the session integrity key
+func newKeys(sik []byte) *keys {
+	return &keys{
+		integrity: hmacSHA1(sik, bytes.Repeat([]byte{0x01}, sha1.Size)),
+		aes:       hmacSHA1(sik, bytes.Repeat([]byte{0x02}, sha1.Size))[:aes.BlockSize],
+	}
+}
+
+// packet is a rmcp packet, the session fields are only used with the ipmi class
+type packet struct {
+	class       byte
+	authType    byte
+	payloadType byte
+	sessionID   uint32
+	sequence    uint32
+	payload     []byte
+}
+
+// marshal encodes the packet, ipmi payloads are encrypted and authenticated when keys are given
+func (p *packet) marshal(k *keys) ([]byte, error) {
+	b := []byte{rmcpVersion, 0x00, rmcpNoAck, p.class}
+	if p.class == rmcpClassASF {
+		return append(b, p.payload...), nil
+	}
+
+	if p.authType == authTypeNone {
+		b = append(b, authTypeNone)
+		b = appendUint32(b, p.sequence)
+		b = appendUint32(b, p.sessionID)
+		b = append(b, byte(len(p.payload)))
+		return append(b, p.payload...), nil
+	}
+
+	payloadType, payload := p.payloadType, p.payload
+	if k != nil && payloadType == payloadIPMI {
+		encrypted, err := encrypt(k.aes, payload)
+		if err != nil {
+			return nil, err
+		}
+		payloadType |= payloadEncrypted | payloadAuthenticated
+		payload = encrypted
+	}
+
+	b = append(b, authTypeRMCPPlus, payloadType)
+	b = appendUint32(b, p.sessionID)
+	b = appendUint32(b, p.sequence)
+	b = append(b, byte(len(payload)), byte(len(payload)>>8))
+	b = append(b, payload...)
+
+	if payloadType&payloadAuthenticated != 0 {
+		// The integrity data goes from the auth type to the next header and must be a multiple of 4 bytes
+		pad := (4 - (len(b)-4+2)%4) % 4
+		b = append(b, bytes.Repeat([]byte{0xff}, pad)...)
+		b = append(b, byte(pad), rmcpClassIPMI)
+		b = append(b, hmacSHA1(k.integrity, b[4:])[:integrityLength]...)
+	}
+
+	return b, nil
+}
+
+// unmarshalPacket decodes the packet, verifying and decrypting it with the keys when it's secured
+func unmarshalPacket(b []byte, k *keys) (*packet, error) {
+	if len(b) < 5 || b[0] != rmcpVersion {
+		return nil, errShortPacket
+	}
+
+	p := &packet{class: b[3] & 0x1f}
+	if p.class == rmcpClassASF {
+		p.payload = b[4:]
+		return p, nil
+	}
+
+	p.authType = b[4]
+	if p.authType == authTypeNone {
+		if len(b) < 14 || len(b) < 14+int(b[13]) {
+			return nil, errShortPacket
+		}
+		p.sequence = binary.LittleEndian.Uint32(b[5:9])
+		p.sessionID = binary.LittleEndian.Uint32(b[9:13])
+		p.payload = b[14 : 14+int(b[13])]
+		return p, nil
+	}
+
+	if p.authType != authTypeRMCPPlus {
+		return nil, fmt.Errorf("unsupported ipmi authentication type %#x", p.authType)
+	}
+
+	if len(b) < 16 {
+		return nil, errShortPacket
+	}
+
+	payloadType := b[5]
+	p.payloadType = payloadType & payloadTypeMask
+	p.sessionID = binary.LittleEndian.Uint32(b[6:10])
+	p.sequence = binary.LittleEndian.Uint32(b[10:14])
+	length := int(binary.LittleEndian.Uint16(b[14:16]))
+	if len(b) < 16+length {
+		return nil, errShortPacket
+	}
+	p.payload = b[16 : 16+length]
+
+	if payloadType&payloadAuthenticated != 0 {
+		if k == nil || len(b) < 16+length+2+integrityLength {
+			return nil, errIntegrity
+		}
+		signed := b[:len(b)-integrityLength]
+		if !hmac.Equal(hmacSHA1(k.integrity, signed[4:])[:integrityLength], b[len(b)-integrityLength:]) {
+			return nil, errIntegrity
+		}
+	}
+
+	if payloadType&payloadEncrypted != 0 {
+		if k == nil {
+			return nil, errIntegrity
+		}
+		payload, err := decrypt(k.aes, p.payload)
+		if err != nil {
+			return nil, err
+		}
+		p.payload = payload
+	}
+
+	return p, nil
+}
+
+// encrypt pads the payload as rmcp+ expects and prepends the random iv
+func encrypt(key []byte, payload []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	pad := (aes.BlockSize - (len(payload)+1)%aes.BlockSize) % aes.BlockSize
+	plain := append([]byte(nil), payload...)
+	for i := 1; i <= pad; i++ {
+		plain = append(plain, byte(i))
+	}
+	plain = append(plain, byte(pad))
+
+	encrypted := make([]byte, aes.BlockSize+len(plain))
+	if _, err = rand.Read(encrypted[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, encrypted[:aes.BlockSize]).CryptBlocks(encrypted[aes.BlockSize:], plain)
+
+	return encrypted, nil
+}
+
+func decrypt(key []byte, payload []byte) ([]byte, error) {
+	if len(payload) < 2*aes.BlockSize || len(payload)%aes.BlockSize != 0 {
+		return nil, errShortPacket
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(payload)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, payload[:aes.BlockSize]).CryptBlocks(plain, payload[aes.BlockSize:])
+
+	pad := int(plain[len(plain)-1])
+	if pad >= aes.BlockSize || pad >= len(plain) {
+		return nil, errIntegrity
+	}
+
+	return plain[:len(plain)-1-pad], nil
+}
+
+func hmacSHA1(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha1.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// userKey is the password padded to the 20 bytes of an ipmi 2.0 password
+func userKey(password string) []byte {
+	key := make([]byte, 20)
+	copy(key, password)
+	return key
+}
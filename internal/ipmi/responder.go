@@ -0,0 +1,392 @@
+package ipmi
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Responder plays the bmc side of rmcp+ for a single user, serving the given inventory. It answers the presence
+// pings too, so it can stand in for the bmc of both the scanner and the collector
+type Responder struct {
+	Username string
+	Password string
+	DeviceID DeviceID
+	PowerOn  bool
+	FRU      FRU
+	Sensors  []Sensor
+
+	mu       sync.Mutex
+	sessions map[uint32]*responderSession
+}
+
+// responderSession is a session being established or established, keyed by the id the responder gave it
+type responderSession struct {
+	consoleID uint32
+	sequence  uint32
+	role      byte
+	user      []byte
+	rm        []byte
+	rc        []byte
+	guid      []byte
+	keys      *keys
+}
+
+// Serve answers the packets received on conn until it's closed
+func (r *Responder) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		if response := r.handle(append([]byte(nil), buf[:n]...)); response != nil {
+			conn.WriteTo(response, addr)
+		}
+	}
+}
+
+// handle returns the answer to a packet, or nil when it must be ignored
+func (r *Responder) handle(b []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sessions == nil {
+		r.sessions = make(map[uint32]*responderSession)
+	}
+
+	var session *responderSession
+	var k *keys
+	if len(b) >= 16 && b[4] == authTypeRMCPPlus {
+		if session = r.sessions[binary.LittleEndian.Uint32(b[6:10])]; session != nil {
+			k = session.keys
+		}
+	}
+
+	p, err := unmarshalPacket(b, k)
+	if err != nil {
+		return nil
+	}
+
+	if p.class == rmcpClassASF {
+		return r.pong(p.payload)
+	}
+
+	if p.authType == authTypeNone {
+		return r.sessionless(p.payload)
+	}
+
+	var response *packet
+	switch p.payloadType {
+	case payloadOpenSessionRequest:
+		response = r.openSession(p.payload)
+	case payloadRAKP1:
+		response = r.rakp1(p.payload)
+	case payloadRAKP3:
+		response = r.rakp3(p.payload)
+	case payloadIPMI:
+		if session == nil || session.keys == nil {
+			return nil
+		}
+		m, err := unmarshalMessage(p.payload)
+		if err != nil {
+			return nil
+		}
+		response = &packet{
+			class:       rmcpClassIPMI,
+			authType:    authTypeRMCPPlus,
+			payloadType: payloadIPMI,
+			sessionID:   session.consoleID,
+			sequence:    session.sequence,
+			payload:     r.command(m, p.sessionID).marshal(consoleAddress, bmcAddress),
+		}
+		session.sequence++
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	if response.payloadType != payloadIPMI {
+		k = nil
+	}
+	out, err := response.marshal(k)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// pong answers an asf presence ping announcing ipmi support
+func (r *Responder) pong(ping []byte) []byte {
+	if len(ping) < 8 || binary.BigEndian.Uint32(ping[0:4]) != asfIANA || ping[4] != asfPing {
+		return nil
+	}
+
+	return []byte{
+		rmcpVersion, 0x00, rmcpNoAck, rmcpClassASF,
+		0x00, 0x00, 0x11, 0xbe, // asf iana
+		asfPong, ping[5], 0x00, 0x10, // pong, tag of the ping, data length
+		0x00, 0x00, 0x11, 0xbe, // iana
+		0x00, 0x00, 0x00, 0x00, // oem
+		0x81, 0x00, // ipmi supported, no interactions
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+}
+
+// sessionless answers the get channel authentication capabilities, the only command allowed before a session
+func (r *Responder) sessionless(payload []byte) []byte {
+	m, err := unmarshalMessage(payload)
+	if err != nil {
+		return nil
+	}
+
+	response := &message{netFn: m.netFn + 1, cmd: m.cmd, seq: m.seq, data: []byte{0xc1}}
+	if m.netFn == netFnApp && m.cmd == 0x38 {
+		response.data = []byte{0x00, 0x01, 0x80, 0x04, 0x02, 0x00, 0x00, 0x00, 0x00}
+	}
+
+	out, _ := (&packet{class: rmcpClassIPMI, authType: authTypeNone, payload: response.marshal(consoleAddress, bmcAddress)}).marshal(nil)
+	return out
+}
+
+func (r *Responder) openSession(payload []byte) *packet {
+	if len(payload) < 32 {
+		return nil
+	}
+
+	response := []byte{payload[0], 0x00, privilegeUser, 0x00}
+	response = append(response, payload[4:8]...)
+
+	// We only speak cipher suite 3
+	if payload[12] != authRAKPHMACSHA1 || payload[20] != integrityHMACSHA196 || payload[28] != confidentialityAES {
+		response[1] = 0x11
+		return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadOpenSessionResponse, payload: response}
+	}
+
+	id, err := randomUint32()
+	if err != nil {
+		return nil
+	}
+	r.sessions[id] = &responderSession{consoleID: binary.LittleEndian.Uint32(payload[4:8]), sequence: 1}
+
+	response = appendUint32(response, id)
+	response = append(response, payload[8:32]...)
+	return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadOpenSessionResponse, payload: response}
+}
+
+func (r *Responder) rakp1(payload []byte) *packet {
+	if len(payload) < 28 || len(payload) < 28+int(payload[27]) {
+		return nil
+	}
+
+	id := binary.LittleEndian.Uint32(payload[4:8])
+	session := r.sessions[id]
+	if session == nil {
+		return nil
+	}
+
+	session.rm = payload[8:24]
+	session.role = payload[24]
+	session.user = payload[28 : 28+int(payload[27])]
+	session.rc = make([]byte, 16)
+	session.guid = make([]byte, 16)
+	rand.Read(session.rc)
+	rand.Read(session.guid)
+
+	consoleID := appendUint32(nil, session.consoleID)
+	response := append([]byte{payload[0], 0x00, 0x00, 0x00}, consoleID...)
+	if string(session.user) != r.Username {
+		response[1] = rakpUnauthorizedName
+		delete(r.sessions, id)
+		return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP2, payload: response}
+	}
+
+	response = append(response, session.rc...)
+	response = append(response, session.guid...)
+	response = append(response, hmacSHA1(userKey(r.Password), consoleID, payload[4:8], session.rm, session.rc, session.guid, []byte{session.role, byte(len(session.user))}, session.user)...)
+	return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP2, payload: response}
+}
+
+func (r *Responder) rakp3(payload []byte) *packet {
+	if len(payload) < 28 {
+		return nil
+	}
+
+	id := binary.LittleEndian.Uint32(payload[4:8])
+	session := r.sessions[id]
+	if session == nil || session.rc == nil {
+		return nil
+	}
+
+	consoleID := appendUint32(nil, session.consoleID)
+	kuid := userKey(r.Password)
+	response := append([]byte{payload[0], 0x00, 0x00, 0x00}, consoleID...)
+
+	expected := hmacSHA1(kuid, session.rc, consoleID, []byte{session.role, byte(len(session.user))}, session.user)
+	if !hmac.Equal(expected, payload[8:28]) {
+		response[1] = rakpInvalidIntegrity
+		delete(r.sessions, id)
+		return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP4, payload: response}
+	}
+
+	sik := hmacSHA1(kuid, session.rm, session.rc, []byte{session.role, byte(len(session.user))}, session.user)
+	session.keys = newKeys(sik)
+
+	response = append(response, hmacSHA1(sik, session.rm, payload[4:8], session.guid)[:integrityLength]...)
+	return &packet{class: rmcpClassIPMI, authType: authTypeRMCPPlus, payloadType: payloadRAKP4, payload: response}
+}
+
+// command answers an ipmi command received inside the session
+func (r *Responder) command(m *message, sessionID uint32) *message {
+	response := &message{netFn: m.netFn + 1, cmd: m.cmd, seq: m.seq, data: []byte{0xc1}}
+
+	switch {
+	case m.netFn == netFnApp && m.cmd == 0x01:
+		major, minor := firmware(r.DeviceID.Firmware)
+		id := r.DeviceID.ManufacturerID
+		response.data = []byte{0x00, 0x20, 0x01, major, minor, 0x02, 0xbf, byte(id), byte(id >> 8), byte(id >> 16), byte(r.DeviceID.ProductID), byte(r.DeviceID.ProductID >> 8)}
+	case m.netFn == netFnApp && m.cmd == 0x3c:
+		delete(r.sessions, sessionID)
+		response.data = []byte{0x00}
+	case m.netFn == netFnChassis && m.cmd == 0x01:
+		state := byte(0x00)
+		if r.PowerOn {
+			state = 0x01
+		}
+		response.data = []byte{0x00, state, 0x00, 0x00}
+	case m.netFn == netFnStorage && m.cmd == 0x10:
+		size := len(r.FRU.image())
+		response.data = []byte{0x00, byte(size), byte(size >> 8), 0x00}
+	case m.netFn == netFnStorage && m.cmd == 0x11 && len(m.data) >= 4:
+		image := r.FRU.image()
+		offset, count := int(binary.LittleEndian.Uint16(m.data[1:3])), int(m.data[3])
+		if offset > len(image) {
+			offset = len(image)
+		}
+		if offset+count > len(image) {
+			count = len(image) - offset
+		}
+		response.data = append([]byte{0x00, byte(count)}, image[offset:offset+count]...)
+	case m.netFn == netFnStorage && m.cmd == 0x22:
+		response.data = []byte{0x00, 0x01, 0x00}
+	case m.netFn == netFnStorage && m.cmd == 0x23 && len(m.data) >= 6:
+		records, _ := r.repository()
+		index := int(binary.LittleEndian.Uint16(m.data[2:4]))
+		if index > 0 {
+			index--
+		}
+		if index >= len(records) {
+			response.data = []byte{0xcb}
+			break
+		}
+		record := records[index]
+		offset, count := int(m.data[4]), int(m.data[5])
+		if count == 0xff || offset+count > len(record) {
+			count = len(record) - offset
+		}
+		next := uint16(index + 2)
+		if index == len(records)-1 {
+			next = 0xffff
+		}
+		response.data = append([]byte{0x00, byte(next), byte(next >> 8)}, record[offset:offset+count]...)
+	case m.netFn == netFnSensor && m.cmd == 0x2d && len(m.data) >= 1:
+		_, readings := r.repository()
+		if reading, ok := readings[m.data[0]]; ok {
+			response.data = []byte{0x00, reading, 0x40, 0x00, 0x00}
+		} else {
+			response.data = []byte{0xcb}
+		}
+	}
+
+	return response
+}
+
+// repository builds the sdr records of the sensors and their raw readings, keyed by sensor number
+func (r *Responder) repository() (records [][]byte, readings map[byte]byte) {
+	readings = make(map[byte]byte)
+	for i, sensor := range r.Sensors {
+		number := sensor.Number
+		if number == 0 {
+			number = byte(i + 1)
+		}
+
+		// Readings are a single byte, larger values lose precision through the result exponent
+		value, exponent := math.Max(sensor.Value, 0), 0
+		for value > 255 {
+			value /= 10
+			exponent++
+		}
+		readings[number] = byte(math.Round(value))
+
+		name := sensor.Name
+		if len(name) > 16 {
+			name = name[:16]
+		}
+
+		record := make([]byte, 48, 48+len(name))
+		record[0], record[1], record[2], record[3] = byte(i+1), byte((i+1)>>8), 0x51, 0x01
+		record[5], record[7], record[8], record[9] = bmcAddress, number, 0x07, 0x01
+		record[12], record[13] = sensor.Type, 0x01
+		record[21] = sensor.Unit
+		record[24] = 0x01
+		record[29] = byte(exponent << 4)
+		record[47] = 0xc0 | byte(len(name))
+		record = append(record, name...)
+		record[4] = byte(len(record) - 5)
+
+		records = append(records, record)
+	}
+
+	return records, readings
+}
+
+// image encodes the fru with a chassis, board and product area using 8 bits ascii fields
+func (f *FRU) image() []byte {
+	area := func(header []byte, values ...string) []byte {
+		b := append([]byte{0x01, 0x00}, header...)
+		for _, value := range values {
+			if len(value) > 63 {
+				value = value[:63]
+			}
+			b = append(b, 0xc0|byte(len(value)))
+			b = append(b, value...)
+		}
+		b = append(b, 0xc1)
+		for (len(b)+1)%8 != 0 {
+			b = append(b, 0x00)
+		}
+		b[1] = byte((len(b) + 1) / 8)
+		return append(b, checksum(b))
+	}
+
+	chassis := area([]byte{0x17}, f.ChassisPartNumber, f.ChassisSerial)
+	board := area([]byte{0x00, 0x00, 0x00, 0x00}, f.BoardManufacturer, f.BoardProduct, f.BoardSerial, f.BoardPartNumber, "")
+	product := area([]byte{0x00}, f.ProductManufacturer, f.ProductName, f.ProductPartNumber, f.ProductVersion, f.ProductSerial, f.AssetTag, "")
+
+	header := []byte{0x01, 0x00, 1, byte(1 + len(chassis)/8), byte(1 + (len(chassis)+len(board))/8), 0x00, 0x00}
+	image := append(header, checksum(header))
+	image = append(image, chassis...)
+	image = append(image, board...)
+	return append(image, product...)
+}
+
+// firmware splits a version like 2.70 into the binary major and bcd minor revisions of get device id
+func firmware(version string) (major byte, minor byte) {
+	parts := strings.SplitN(version, ".", 2)
+	if n, err := strconv.Atoi(parts[0]); err == nil {
+		major = byte(n) & 0x7f
+	}
+	if len(parts) == 2 {
+		if n, err := strconv.ParseUint(parts[1], 16, 8); err == nil {
+			minor = byte(n)
+		}
+	}
+	return major, minor
+}
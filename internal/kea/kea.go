@@ -0,0 +1,311 @@
+// Package kea reads the subnets of a kea dhcp server configuration. On top of json it accepts what kea itself
+// does: comments in shell, c and c++ style and <?include "file" ?> directives. The subnets of the shared networks
+// are read too, and get the options of their shared network and of the server they don't set themselves.
+// ReadLeases reads the addresses in use out of the lease files of the memfile backend.
+package kea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// maxIncludeDepth stops the include loops
+const maxIncludeDepth = 10
+
+var includeDirective = regexp.MustCompile(`^<\?include\s+"([^"]+)"\s*\?>`)
+
+// Subnet is a subnet of the configuration with the options and user context it ends up with, and the addresses
+// reserved in it
+type Subnet struct {
+	ID            int
+	Network       *net.IPNet
+	SharedNetwork string
+	Options       map[string]string
+	UserContext   map[string]interface{}
+	Reservations  []net.IP
+}
+
+// IPv6 tells whether the subnet was read from Dhcp6
+func (s *Subnet) IPv6() bool {
+	return s.Network.IP.To4() == nil
+}
+
+type config struct {
+	Dhcp4 *server `json:"Dhcp4"`
+	Dhcp6 *server `json:"Dhcp6"`
+}
+
+type server struct {
+	OptionData     []option        `json:"option-data"`
+	Subnet4        []subnet        `json:"subnet4"`
+	Subnet6        []subnet        `json:"subnet6"`
+	SharedNetworks []sharedNetwork `json:"shared-networks"`
+}
+
+type sharedNetwork struct {
+	Name        string                 `json:"name"`
+	OptionData  []option               `json:"option-data"`
+	UserContext map[string]interface{} `json:"user-context"`
+	Subnet4     []subnet               `json:"subnet4"`
+	Subnet6     []subnet               `json:"subnet6"`
+}
+
+type subnet struct {
+	ID           int                    `json:"id"`
+	Subnet       string                 `json:"subnet"`
+	OptionData   []option               `json:"option-data"`
+	UserContext  map[string]interface{} `json:"user-context"`
+	Reservations []reservation          `json:"reservations"`
+}
+
+// reservation has an ip-address in Dhcp4 and ip-addresses in Dhcp6
+type reservation struct {
+	IPAddress   string   `json:"ip-address"`
+	IPAddresses []string `json:"ip-addresses"`
+}
+
+type option struct {
+	Name  string `json:"name"`
+	Code  int    `json:"code"`
+	Space string `json:"space"`
+	Data  string `json:"data"`
+}
+
+// Read reads the subnets of the configuration file, relative includes are found from the directory of the file
+// including them
+func Read(path string) (subnets []*Subnet, err error) {
+	content, err := readFile(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parse(content, path)
+}
+
+// Parse reads the subnets of the configuration in content, relative includes are found from dir
+func Parse(content []byte, dir string) (subnets []*Subnet, err error) {
+	if content, err = preprocess(content, dir, 0); err != nil {
+		return nil, err
+	}
+	return parse(content, "kea config")
+}
+
+// parse reads the subnets of the configuration once its comments and includes are gone, errors point to the
+// line of the configuration with its includes expanded
+func parse(content []byte, name string) (subnets []*Subnet, err error) {
+	var c config
+	if err = json.Unmarshal(content, &c); err != nil {
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			return nil, fmt.Errorf("%s line %d: %s", name, line(content, e.Offset), err)
+		case *json.UnmarshalTypeError:
+			return nil, fmt.Errorf("%s line %d: %s", name, line(content, e.Offset), err)
+		}
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	if c.Dhcp4 == nil && c.Dhcp6 == nil {
+		return nil, fmt.Errorf("%s: no Dhcp4 or Dhcp6 configuration", name)
+	}
+
+	if c.Dhcp4 != nil {
+		if subnets, err = c.Dhcp4.subnets(subnets, false); err != nil {
+			return nil, fmt.Errorf("%s: Dhcp4 %s", name, err)
+		}
+	}
+	if c.Dhcp6 != nil {
+		if subnets, err = c.Dhcp6.subnets(subnets, true); err != nil {
+			return nil, fmt.Errorf("%s: Dhcp6 %s", name, err)
+		}
+	}
+	return subnets, nil
+}
+
+// subnets appends the subnets of the server, the ones outside of shared networks first
+func (s *server) subnets(subnets []*Subnet, ipv6 bool) ([]*Subnet, error) {
+	global := options(s.OptionData, nil)
+
+	list := s.Subnet4
+	if ipv6 {
+		list = s.Subnet6
+	}
+	for i := range list {
+		subnet, err := list[i].resolve(ipv6, global, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, subnet)
+	}
+
+	for _, network := range s.SharedNetworks {
+		inherited := options(network.OptionData, global)
+
+		list := network.Subnet4
+		if ipv6 {
+			list = network.Subnet6
+		}
+		for i := range list {
+			subnet, err := list[i].resolve(ipv6, inherited, network.UserContext, network.Name)
+			if err != nil {
+				return nil, fmt.Errorf("shared network %s: %s", network.Name, err)
+			}
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	return subnets, nil
+}
+
+// resolve parses the prefix of the subnet and adds the options and user context it inherits
+func (s *subnet) resolve(ipv6 bool, inherited map[string]string, context map[string]interface{}, sharedNetwork string) (*Subnet, error) {
+	_, network, err := net.ParseCIDR(s.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("subnet %d: invalid prefix %q", s.ID, s.Subnet)
+	}
+	if (network.IP.To4() == nil) != ipv6 {
+		return nil, fmt.Errorf("subnet %d: %s isn't of the address family of the server", s.ID, s.Subnet)
+	}
+
+	var reservations []net.IP
+	for _, r := range s.Reservations {
+		addresses := r.IPAddresses
+		if r.IPAddress != "" {
+			addresses = append(addresses, r.IPAddress)
+		}
+		for _, address := range addresses {
+			ip := net.ParseIP(address)
+			if ip == nil || !network.Contains(ip) {
+				return nil, fmt.Errorf("subnet %d: reservation %q isn't an address of %s", s.ID, address, network)
+			}
+			reservations = append(reservations, ip)
+		}
+	}
+
+	userContext := make(map[string]interface{}, len(context)+len(s.UserContext))
+	for key, value := range context {
+		userContext[key] = value
+	}
+	for key, value := range s.UserContext {
+		userContext[key] = value
+	}
+
+	return &Subnet{
+		ID:            s.ID,
+		Network:       network,
+		SharedNetwork: sharedNetwork,
+		Options:       options(s.OptionData, inherited),
+		UserContext:   userContext,
+		Reservations:  reservations,
+	}, nil
+}
+
+// options returns the inherited options overridden by the given ones. Options without a name are known by their
+// code, the ones of vendor spaces are left out
+func options(data []option, inherited map[string]string) map[string]string {
+	options := make(map[string]string, len(inherited)+len(data))
+	for name, value := range inherited {
+		options[name] = value
+	}
+
+	for _, o := range data {
+		if o.Space != "" && o.Space != "dhcp4" && o.Space != "dhcp6" {
+			continue
+		}
+
+		name := o.Name
+		if name == "" {
+			name = strconv.Itoa(o.Code)
+		}
+		options[name] = o.Data
+	}
+	return options
+}
+
+// readFile reads a configuration file without its comments and with its includes
+func readFile(path string, depth int) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if content, err = preprocess(content, filepath.Dir(path), depth); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return content, nil
+}
+
+// preprocess removes the comments of content and replaces the include directives by the files they name. The
+// comments are replaced by the lines they took so the json errors point to the right line
+func preprocess(content []byte, dir string, depth int) ([]byte, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("more than %d nested includes", maxIncludeDepth)
+	}
+
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				out.WriteByte(content[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '#' || bytes.HasPrefix(content[i:], []byte("//")):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case bytes.HasPrefix(content[i:], []byte("/*")):
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line(content, int64(i)))
+			}
+			comment := content[i : i+2+end+2]
+			out.Write(bytes.Repeat([]byte("\n"), bytes.Count(comment, []byte("\n"))))
+			i += len(comment) - 1
+		case bytes.HasPrefix(content[i:], []byte("<?include")):
+			directive := includeDirective.FindSubmatch(content[i:])
+			if directive == nil {
+				return nil, fmt.Errorf("line %d: invalid include directive", line(content, int64(i)))
+			}
+
+			path := string(directive[1])
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			included, err := readFile(path, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line(content, int64(i)), err)
+			}
+			out.Write(included)
+			i += len(directive[0]) - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// line returns the line of the offset in content
+func line(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
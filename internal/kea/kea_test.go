@@ -0,0 +1,138 @@
+package kea
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	subnets, err := Read("testdata/kea-dhcp4.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		id            int
+		network       string
+		sharedNetwork string
+		options       map[string]string
+		userContext   map[string]interface{}
+	}{
+		{10, "192.168.0.0/24", "", map[string]string{"domain-name": "example.com", "routers": "192.168.0.1"}, map[string]interface{}{"dora-site": "lhr4"}},
+		{11, "192.168.1.0/24", "", map[string]string{"domain-name": "example.com", "domain-search": "bmc.example.com example.com"}, map[string]interface{}{}},
+		{20, "10.1.0.0/24", "ams4-bmc", map[string]string{"domain-name": "ams4.bmc.example.com", "dora-scan-profile": "kvm", "routers": "10.1.0.1"}, map[string]interface{}{"rack": "a1", "tier": "bmc"}},
+		{21, "10.1.1.0/24", "ams4-bmc", map[string]string{"domain-name": "ams4-ext.example.com", "252": "http://wpad.example.com/wpad.dat"}, map[string]interface{}{"rack": "a2", "tier": "bmc"}},
+	}
+
+	if len(subnets) != len(tt) {
+		t.Fatalf("expected %d subnets, got %d", len(tt), len(subnets))
+	}
+	for i, tc := range tt {
+		subnet := subnets[i]
+		if subnet.ID != tc.id || subnet.Network.String() != tc.network || subnet.SharedNetwork != tc.sharedNetwork || subnet.IPv6() {
+			t.Errorf("expected subnet %d %s of %q, got %d %s of %q", tc.id, tc.network, tc.sharedNetwork, subnet.ID, subnet.Network, subnet.SharedNetwork)
+		}
+		for name, value := range tc.options {
+			if subnet.Options[name] != value {
+				t.Errorf("subnet %d: expected option %s to be %q, got %q", tc.id, name, value, subnet.Options[name])
+			}
+		}
+		if len(subnet.UserContext) != len(tc.userContext) {
+			t.Errorf("subnet %d: expected user context %v, got %v", tc.id, tc.userContext, subnet.UserContext)
+		}
+		for key, value := range tc.userContext {
+			if subnet.UserContext[key] != value {
+				t.Errorf("subnet %d: expected user context %s to be %v, got %v", tc.id, key, value, subnet.UserContext[key])
+			}
+		}
+	}
+
+	if len(subnets[0].Reservations) != 1 || subnets[0].Reservations[0].String() != "192.168.0.10" {
+		t.Errorf("expected the reservation of subnet 10, got %v", subnets[0].Reservations)
+	}
+
+	if _, ok := subnets[3].Options["vendor-thing"]; ok {
+		t.Error("expected the options of vendor spaces to be left out")
+	}
+}
+
+func TestReadDhcp6(t *testing.T) {
+	subnets, err := Read("testdata/kea-dhcp6.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(subnets) != 2 || !subnets[0].IPv6() || subnets[1].Network.String() != "2001:db8:2::/64" || subnets[1].SharedNetwork != "ams4-bmc" {
+		t.Fatalf("expected the two ipv6 subnets, got %+v", subnets)
+	}
+	if subnets[0].Options["domain-search"] != "bmc.example.com" || subnets[1].Options["domain-search"] != "ams4.bmc.example.com" {
+		t.Errorf("expected the global option to be overridden by the subnet, got %v and %v", subnets[0].Options, subnets[1].Options)
+	}
+	if len(subnets[0].Reservations) != 2 || subnets[0].Reservations[1].String() != "2001:db8:1::101" || len(subnets[1].Reservations) != 0 {
+		t.Errorf("expected the reserved addresses without the prefixes, got %v and %v", subnets[0].Reservations, subnets[1].Reservations)
+	}
+}
+
+func TestReadLeases(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	leases, err := ReadLeases("testdata/kea-leases6.csv", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"2001:db8:1::200", "2001:db8:2::5"}
+	if len(leases) != len(expected) {
+		t.Fatalf("expected the leases %v, got %v", expected, leases)
+	}
+	for i := range expected {
+		if leases[i].String() != expected[i] {
+			t.Errorf("expected lease %s, got %s", expected[i], leases[i])
+		}
+	}
+
+	if leases, err = ReadLeases("testdata/kea-leases4.csv", now); err != nil || len(leases) != 1 || leases[0].String() != "192.168.0.20" {
+		t.Errorf("expected the dhcp4 lease, got %v: %v", leases, err)
+	}
+
+	if _, err = ReadLeases("testdata/kea-dhcp6.conf", now); err == nil {
+		t.Error("expected a file without the lease columns to be refused")
+	}
+}
+
+func TestReadSample(t *testing.T) {
+	subnets, err := Read("../../kea-simple.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) == 0 || subnets[0].Network.String() != "192.168.0.0/24" || subnets[0].Options["domain-name"] != "bmc.example.com" {
+		t.Errorf("expected the subnets of the sample config, got %+v", subnets)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"malformed json", "{\n  \"Dhcp4\": {\n    \"subnet4\": [ { \"id\": 1, } ]\n  }\n}", "line 3"},
+		{"wrong type", `{"Dhcp4": {"subnet4": {"id": 1}}}`, "cannot unmarshal"},
+		{"no server", `{"Control-agent": {}}`, "no Dhcp4 or Dhcp6"},
+		{"invalid prefix", `{"Dhcp4": {"subnet4": [{"id": 3, "subnet": "10.0.0.0/33"}]}}`, "subnet 3: invalid prefix"},
+		{"reservation outside", `{"Dhcp4": {"subnet4": [{"id": 5, "subnet": "10.0.0.0/24", "reservations": [{"ip-address": "10.0.1.1"}]}]}}`, "subnet 5: reservation"},
+		{"wrong family", `{"Dhcp4": {"subnet4": [{"id": 4, "subnet": "2001:db8::/64"}]}}`, "address family"},
+		{"unterminated comment", "{\n/* the subnets\n", "line 2: unterminated comment"},
+		{"invalid include", `{"Dhcp4": <?include testdata/subnets4.json?>}`, "invalid include directive"},
+		{"missing include", `{"Dhcp4": {"subnet4": [<?include "testdata/missing.json"?>]}}`, "no such file"},
+		{"include loop", `{"Dhcp4": {"subnet4": [<?include "testdata/loop.conf"?>]}}`, "nested includes"},
+		{"empty", ``, "unexpected end"},
+	}
+
+	for _, tc := range tt {
+		_, err := Parse([]byte(tc.content), ".")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error with %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package kea
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// leaseTypeAddress is the lease_type of the dhcp6 address leases, the other ones are prefixes
+const leaseTypeAddress = "0"
+
+// leaseStateDefault is the state of the leases in use, not declined nor reclaimed
+const leaseStateDefault = "0"
+
+// ReadLeases returns the addresses leased at now in a lease file of the kea memfile backend, of dhcp4 or dhcp6.
+// kea appends the updates of a lease to the file, so the last line of an address is the one that counts
+func ReadLeases(path string, now time.Time) (leases []net.IP, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"address", "expire", "state"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%s: no %s column", path, name)
+		}
+	}
+	leaseType, hasLeaseType := columns["lease_type"]
+
+	active := make(map[string]bool)
+	var addresses []net.IP
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("%s line %d: expected %d columns, got %d", path, line, len(header), len(record))
+		}
+		if hasLeaseType && record[leaseType] != leaseTypeAddress {
+			continue
+		}
+
+		ip := net.ParseIP(record[columns["address"]])
+		expire, err := strconv.ParseInt(record[columns["expire"]], 10, 64)
+		if ip == nil || err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid address or expire", path, line)
+		}
+
+		if _, seen := active[ip.String()]; !seen {
+			addresses = append(addresses, ip)
+		}
+		active[ip.String()] = record[columns["state"]] == leaseStateDefault && time.Unix(expire, 0).After(now)
+	}
+
+	for _, ip := range addresses {
+		if active[ip.String()] {
+			leases = append(leases, ip)
+		}
+	}
+	return leases, nil
+}
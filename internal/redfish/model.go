@@ -0,0 +1,155 @@
+package redfish
+
+// link points to another resource of the api
+type link struct {
+	ID string `json:"@odata.id"`
+}
+
+// collection is the payload of every resource collection, eg: /redfish/v1/Systems
+type collection struct {
+	Members []link `json:"Members"`
+}
+
+// status is the common health block of the resources
+type status struct {
+	State  string `json:"State"`
+	Health string `json:"Health"`
+}
+
+// serviceRoot is the payload of /redfish/v1, it is the only resource served without authentication
+type serviceRoot struct {
+	RedfishVersion string                 `json:"RedfishVersion"`
+	Vendor         string                 `json:"Vendor"`
+	Product        string                 `json:"Product"`
+	Oem            map[string]interface{} `json:"Oem"`
+	Systems        link                   `json:"Systems"`
+	Chassis        link                   `json:"Chassis"`
+	Managers       link                   `json:"Managers"`
+}
+
+// system is a server as seen by /redfish/v1/Systems/{id}
+type system struct {
+	ID               string `json:"Id"`
+	Name             string `json:"Name"`
+	HostName         string `json:"HostName"`
+	Manufacturer     string `json:"Manufacturer"`
+	Model            string `json:"Model"`
+	SerialNumber     string `json:"SerialNumber"`
+	PowerState       string `json:"PowerState"`
+	BiosVersion      string `json:"BiosVersion"`
+	Status           status `json:"Status"`
+	ProcessorSummary struct {
+		Count int    `json:"Count"`
+		Model string `json:"Model"`
+	} `json:"ProcessorSummary"`
+	MemorySummary struct {
+		TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+	} `json:"MemorySummary"`
+	Processors         link `json:"Processors"`
+	EthernetInterfaces link `json:"EthernetInterfaces"`
+	Storage            link `json:"Storage"`
+	Links              struct {
+		Chassis   []link `json:"Chassis"`
+		ManagedBy []link `json:"ManagedBy"`
+	} `json:"Links"`
+}
+
+// processor is a socket of a system
+type processor struct {
+	Model         string `json:"Model"`
+	ProcessorType string `json:"ProcessorType"`
+	TotalCores    int    `json:"TotalCores"`
+	TotalThreads  int    `json:"TotalThreads"`
+	Status        status `json:"Status"`
+}
+
+// ethernetInterface is a nic of a system or manager
+type ethernetInterface struct {
+	ID         string `json:"Id"`
+	Name       string `json:"Name"`
+	MACAddress string `json:"MACAddress"`
+	SpeedMbps  int    `json:"SpeedMbps"`
+	LinkStatus string `json:"LinkStatus"`
+}
+
+// storage is a storage controller of a system, we only need its drives
+type storage struct {
+	Drives []link `json:"Drives"`
+}
+
+// drive is a physical disk attached to a storage controller
+type drive struct {
+	Name          string `json:"Name"`
+	Model         string `json:"Model"`
+	SerialNumber  string `json:"SerialNumber"`
+	Revision      string `json:"Revision"`
+	MediaType     string `json:"MediaType"`
+	CapacityBytes int64  `json:"CapacityBytes"`
+	Status        status `json:"Status"`
+}
+
+// chassis is a physical enclosure, for a rack server it's the server itself
+type chassis struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	ChassisType  string `json:"ChassisType"`
+	Manufacturer string `json:"Manufacturer"`
+	Model        string `json:"Model"`
+	SerialNumber string `json:"SerialNumber"`
+	PowerState   string `json:"PowerState"`
+	Status       status `json:"Status"`
+	Thermal      link   `json:"Thermal"`
+	Power        link   `json:"Power"`
+	Links        struct {
+		ComputerSystems []link `json:"ComputerSystems"`
+		ManagedBy       []link `json:"ManagedBy"`
+	} `json:"Links"`
+}
+
+// thermal holds the temperatures and fans of a chassis
+type thermal struct {
+	Temperatures []struct {
+		Name            string  `json:"Name"`
+		ReadingCelsius  float64 `json:"ReadingCelsius"`
+		PhysicalContext string  `json:"PhysicalContext"`
+	} `json:"Temperatures"`
+	Fans []struct {
+		MemberID     string `json:"MemberId"`
+		Name         string `json:"Name"`
+		Reading      int64  `json:"Reading"`
+		ReadingUnits string `json:"ReadingUnits"`
+		Status       status `json:"Status"`
+	} `json:"Fans"`
+}
+
+// power holds the consumption and power supplies of a chassis
+type power struct {
+	PowerControl []struct {
+		PowerConsumedWatts float64 `json:"PowerConsumedWatts"`
+	} `json:"PowerControl"`
+	PowerSupplies []struct {
+		MemberID             string  `json:"MemberId"`
+		Name                 string  `json:"Name"`
+		Model                string  `json:"Model"`
+		SerialNumber         string  `json:"SerialNumber"`
+		PartNumber           string  `json:"PartNumber"`
+		PowerCapacityWatts   float64 `json:"PowerCapacityWatts"`
+		LastPowerOutputWatts float64 `json:"LastPowerOutputWatts"`
+		Status               status  `json:"Status"`
+	} `json:"PowerSupplies"`
+	Redundancy []struct {
+		Mode   string `json:"Mode"`
+		Status status `json:"Status"`
+	} `json:"Redundancy"`
+}
+
+// manager is the bmc itself
+type manager struct {
+	ID                 string `json:"Id"`
+	Name               string `json:"Name"`
+	Model              string `json:"Model"`
+	FirmwareVersion    string `json:"FirmwareVersion"`
+	ManagerType        string `json:"ManagerType"`
+	Status             status `json:"Status"`
+	EthernetInterfaces link   `json:"EthernetInterfaces"`
+}
@@ -0,0 +1,537 @@
+// Package redfish collects any bmc implementing the dmtf redfish api. It's used as a fallback for the hardware
+// bmclib doesn't recognise, so it only relies on the standard resources: Systems, Chassis and Managers.
+package redfish
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// HardwareType is the bmc type reported for every device collected through redfish
+const HardwareType = "redfish"
+
+// errNoEnclosure is returned when none of the chassis is an enclosure
+var errNoEnclosure = fmt.Errorf("no chassis of type enclosure found")
+
+// Redfish is a connection to a bmc speaking redfish
+type Redfish struct {
+	host     string
+	username string
+	password string
+	client   *http.Client
+	root     *serviceRoot
+}
+
+// New reads the service root of the host and returns errors.ErrDeviceNotMatched when it doesn't speak redfish
+func New(host string, username string, password string) (r *Redfish, err error) {
+	r = &Redfish{
+		host:     host,
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout:   120 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		},
+		root: &serviceRoot{},
+	}
+
+	resp, err := r.client.Get(r.url("/redfish/v1"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.ErrDeviceNotMatched
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(r.root); err != nil || r.root.RedfishVersion == "" {
+		return nil, errors.ErrDeviceNotMatched
+	}
+
+	return r, nil
+}
+
+// url returns the address of the path on the bmc, the ipv6 addresses go between brackets
+func (r *Redfish) url(path string) string {
+	host := r.host
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("https://%s%s", host, path)
+}
+
+// get reads the resource at path into v
+func (r *Redfish) get(path string, v interface{}) (err error) {
+	log.WithFields(log.Fields{"step": "bmc connection", "vendor": HardwareType, "ip": r.host, "endpoint": path}).Debug("retrieving data from bmc")
+
+	req, err := http.NewRequest("GET", r.url(path), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.username, r.password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.ErrLoginFailed
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.ErrPageNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status %d retrieving %s", resp.StatusCode, path)
+	}
+
+	return json.Unmarshal(payload, v)
+}
+
+// members returns the resources of a collection, a missing link means an empty collection
+func (r *Redfish) members(l link) (members []link, err error) {
+	if l.ID == "" {
+		return members, err
+	}
+
+	c := &collection{}
+	if err = r.get(l.ID, c); err != nil {
+		return members, err
+	}
+
+	return c.Members, err
+}
+
+// CheckCredentials verifies whether the credentials are valid or not
+func (r *Redfish) CheckCredentials() (err error) {
+	_, err = r.members(r.root.Systems)
+	return err
+}
+
+// UpdateCredentials updates login credentials
+func (r *Redfish) UpdateCredentials(username string, password string) {
+	r.username = username
+	r.password = password
+}
+
+// Close is a noop, we use basic auth so there is no session to close
+func (r *Redfish) Close() (err error) {
+	return err
+}
+
+// Vendor returns the vendor announced by the service root, old implementations only announce it as an oem key
+func (r *Redfish) Vendor() (vendor string) {
+	if r.root.Vendor != "" {
+		return r.root.Vendor
+	}
+
+	var oem []string
+	for name := range r.root.Oem {
+		oem = append(oem, name)
+	}
+
+	if len(oem) == 0 {
+		return devices.Unknown
+	}
+
+	sort.Strings(oem)
+	return oem[0]
+}
+
+// HardwareType returns the type of bmc we are talking to
+func (r *Redfish) HardwareType() string {
+	return HardwareType
+}
+
+// IsEnclosure returns whether the bmc manages a chassis with blades rather than a single server
+func (r *Redfish) IsEnclosure() (isEnclosure bool, err error) {
+	_, err = r.enclosure()
+	if err == errNoEnclosure {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// enclosure returns the first chassis of type enclosure
+func (r *Redfish) enclosure() (enclosure *chassis, err error) {
+	members, err := r.members(r.root.Chassis)
+	if err != nil {
+		return enclosure, err
+	}
+
+	for _, member := range members {
+		c := &chassis{}
+		if err = r.get(member.ID, c); err != nil {
+			return enclosure, err
+		}
+
+		if c.ChassisType == "Enclosure" {
+			return c, nil
+		}
+	}
+
+	return enclosure, errNoEnclosure
+}
+
+// ServerSnapshot returns the first system of the bmc as a discrete
+func (r *Redfish) ServerSnapshot() (discrete *devices.Discrete, err error) {
+	members, err := r.members(r.root.Systems)
+	if err != nil {
+		return discrete, err
+	}
+
+	if len(members) == 0 {
+		return discrete, fmt.Errorf("no system found in %s", r.root.Systems.ID)
+	}
+
+	return r.system(members[0].ID, true)
+}
+
+// ChassisSnapshot returns the enclosure with its power supplies, fans and the blades it contains
+func (r *Redfish) ChassisSnapshot() (c *devices.Chassis, err error) {
+	enclosure, err := r.enclosure()
+	if err != nil {
+		return c, err
+	}
+
+	c = &devices.Chassis{
+		Serial:            strings.ToLower(strings.TrimSpace(enclosure.SerialNumber)),
+		Name:              enclosure.Name,
+		BmcAddress:        r.host,
+		Model:             enclosure.Model,
+		Vendor:            enclosure.Manufacturer,
+		Status:            health(enclosure.Status),
+		PsuRedundancyMode: devices.Unknown,
+	}
+
+	if c.Vendor == "" {
+		c.Vendor = r.Vendor()
+	}
+
+	if c.TempC, c.Fans, err = r.thermal(enclosure.Thermal, c.Serial); err != nil {
+		return c, err
+	}
+
+	var p *power
+	if c.PowerKw, c.Psus, p, err = r.power(enclosure.Power); err != nil {
+		return c, err
+	}
+
+	if p != nil && len(p.Redundancy) > 0 {
+		c.IsPsuRedundant = p.Redundancy[0].Status.Health == "OK"
+		switch p.Redundancy[0].Mode {
+		case "N+m", "Sharing":
+			c.PsuRedundancyMode = devices.PowerSupply
+		case "NotRedundant":
+			c.PsuRedundancyMode = devices.NoRedundancy
+		}
+	}
+
+	if len(enclosure.Links.ManagedBy) > 0 {
+		if c.FwVersion, c.Nics, err = r.manager(enclosure.Links.ManagedBy[0].ID); err != nil {
+			return c, err
+		}
+	}
+
+	for position, member := range enclosure.Links.ComputerSystems {
+		server, err := r.system(member.ID, false)
+		if err != nil {
+			return c, err
+		}
+
+		c.Blades = append(c.Blades, &devices.Blade{
+			Serial:               server.Serial,
+			Name:                 server.Name,
+			BiosVersion:          server.BiosVersion,
+			BmcType:              HardwareType,
+			Disks:                server.Disks,
+			Nics:                 server.Nics,
+			BladePosition:        position + 1,
+			Model:                server.Model,
+			PowerState:           server.PowerState,
+			TempC:                server.TempC,
+			PowerKw:              server.PowerKw,
+			Status:               server.Status,
+			Vendor:               server.Vendor,
+			ChassisSerial:        c.Serial,
+			Processor:            server.Processor,
+			ProcessorCount:       server.ProcessorCount,
+			ProcessorCoreCount:   server.ProcessorCoreCount,
+			ProcessorThreadCount: server.ProcessorThreadCount,
+			Memory:               server.Memory,
+		})
+	}
+
+	return c, nil
+}
+
+// system reads a system and everything attached to it, the bmc data is only read for standalone servers
+func (r *Redfish) system(path string, standalone bool) (discrete *devices.Discrete, err error) {
+	s := &system{}
+	if err = r.get(path, s); err != nil {
+		return discrete, err
+	}
+
+	discrete = &devices.Discrete{
+		Serial:         strings.ToLower(strings.TrimSpace(s.SerialNumber)),
+		Name:           s.HostName,
+		BiosVersion:    s.BiosVersion,
+		BmcType:        HardwareType,
+		Model:          s.Model,
+		PowerState:     strings.ToLower(s.PowerState),
+		Status:         health(s.Status),
+		Vendor:         s.Manufacturer,
+		Processor:      s.ProcessorSummary.Model,
+		ProcessorCount: s.ProcessorSummary.Count,
+		Memory:         int(s.MemorySummary.TotalSystemMemoryGiB),
+	}
+
+	if discrete.Name == "" {
+		discrete.Name = s.Name
+	}
+
+	if discrete.Vendor == "" {
+		discrete.Vendor = r.Vendor()
+	}
+
+	processors, err := r.members(s.Processors)
+	if err != nil {
+		return discrete, err
+	}
+
+	for _, member := range processors {
+		p := &processor{}
+		if err = r.get(member.ID, p); err != nil {
+			return discrete, err
+		}
+
+		if p.ProcessorType != "" && p.ProcessorType != "CPU" {
+			continue
+		}
+
+		if discrete.Processor == "" {
+			discrete.Processor = p.Model
+		}
+		discrete.ProcessorCoreCount += p.TotalCores
+		discrete.ProcessorThreadCount += p.TotalThreads
+	}
+
+	if discrete.Nics, err = r.nics(s.EthernetInterfaces, ""); err != nil {
+		return discrete, err
+	}
+
+	if discrete.Disks, err = r.disks(s.Storage); err != nil {
+		return discrete, err
+	}
+
+	if !standalone {
+		return discrete, nil
+	}
+
+	discrete.BmcAddress = r.host
+	if len(s.Links.Chassis) > 0 {
+		c := &chassis{}
+		if err = r.get(s.Links.Chassis[0].ID, c); err != nil {
+			return discrete, err
+		}
+
+		if discrete.TempC, _, err = r.thermal(c.Thermal, discrete.Serial); err != nil {
+			return discrete, err
+		}
+
+		if discrete.PowerKw, discrete.Psus, _, err = r.power(c.Power); err != nil {
+			return discrete, err
+		}
+	}
+
+	if len(s.Links.ManagedBy) > 0 {
+		var nics []*devices.Nic
+		if discrete.BmcVersion, nics, err = r.manager(s.Links.ManagedBy[0].ID); err != nil {
+			return discrete, err
+		}
+		discrete.Nics = append(discrete.Nics, nics...)
+	}
+
+	return discrete, nil
+}
+
+// nics reads a collection of ethernet interfaces, when name is given it's used for all of them
+func (r *Redfish) nics(l link, name string) (nics []*devices.Nic, err error) {
+	members, err := r.members(l)
+	if err != nil {
+		return nics, err
+	}
+
+	for _, member := range members {
+		e := &ethernetInterface{}
+		if err = r.get(member.ID, e); err != nil {
+			return nics, err
+		}
+
+		if e.MACAddress == "" {
+			continue
+		}
+
+		nic := &devices.Nic{
+			MacAddress: strings.ToLower(e.MACAddress),
+			Name:       name,
+			Up:         e.LinkStatus == "LinkUp",
+		}
+
+		if nic.Name == "" {
+			nic.Name = e.Name
+		}
+
+		if e.SpeedMbps > 0 {
+			nic.Speed = fmt.Sprintf("%dMbps", e.SpeedMbps)
+		}
+
+		nics = append(nics, nic)
+	}
+
+	return nics, err
+}
+
+// disks reads the drives of all storage controllers
+func (r *Redfish) disks(l link) (disks []*devices.Disk, err error) {
+	controllers, err := r.members(l)
+	if err != nil {
+		return disks, err
+	}
+
+	for _, controller := range controllers {
+		s := &storage{}
+		if err = r.get(controller.ID, s); err != nil {
+			return disks, err
+		}
+
+		for _, member := range s.Drives {
+			d := &drive{}
+			if err = r.get(member.ID, d); err != nil {
+				return disks, err
+			}
+
+			disks = append(disks, &devices.Disk{
+				Serial:    strings.ToLower(strings.TrimSpace(d.SerialNumber)),
+				Status:    health(d.Status),
+				Type:      d.MediaType,
+				Size:      fmt.Sprintf("%d GB", d.CapacityBytes/1000000000),
+				Model:     strings.ToLower(d.Model),
+				Location:  d.Name,
+				FwVersion: strings.ToLower(d.Revision),
+			})
+		}
+	}
+
+	return disks, err
+}
+
+// thermal returns the inlet temperature and the fans of a chassis, fans have no serial so we build one like bmclib does
+func (r *Redfish) thermal(l link, serial string) (temp int, fans []*devices.Fan, err error) {
+	if l.ID == "" {
+		return temp, fans, err
+	}
+
+	t := &thermal{}
+	if err = r.get(l.ID, t); err != nil {
+		return temp, fans, err
+	}
+
+	for position, sensor := range t.Temperatures {
+		if position == 0 || sensor.PhysicalContext == "Intake" {
+			temp = int(sensor.ReadingCelsius)
+		}
+		if sensor.PhysicalContext == "Intake" {
+			break
+		}
+	}
+
+	for position, fan := range t.Fans {
+		f := &devices.Fan{
+			Serial:   fmt.Sprintf("%d_%s", position+1, serial),
+			Status:   health(fan.Status),
+			Position: position + 1,
+			Model:    fan.Name,
+		}
+
+		if fan.ReadingUnits == "" || fan.ReadingUnits == "RPM" {
+			f.CurrentRPM = fan.Reading
+		}
+
+		fans = append(fans, f)
+	}
+
+	return temp, fans, err
+}
+
+// power returns the consumption and power supplies of a chassis
+func (r *Redfish) power(l link) (powerKw float64, psus []*devices.Psu, p *power, err error) {
+	if l.ID == "" {
+		return powerKw, psus, p, err
+	}
+
+	p = &power{}
+	if err = r.get(l.ID, p); err != nil {
+		return powerKw, psus, p, err
+	}
+
+	if len(p.PowerControl) > 0 {
+		powerKw = p.PowerControl[0].PowerConsumedWatts / 1000.00
+	}
+
+	for position, supply := range p.PowerSupplies {
+		partNumber := supply.PartNumber
+		if partNumber == "" {
+			partNumber = supply.Model
+		}
+
+		psus = append(psus, &devices.Psu{
+			Serial:     strings.ToLower(strings.TrimSpace(supply.SerialNumber)),
+			CapacityKw: supply.PowerCapacityWatts / 1000.00,
+			PowerKw:    supply.LastPowerOutputWatts / 1000.00,
+			Status:     health(supply.Status),
+			PartNumber: partNumber,
+			Position:   position + 1,
+		})
+	}
+
+	return powerKw, psus, p, err
+}
+
+// manager returns the firmware version and the nics of the bmc
+func (r *Redfish) manager(path string) (version string, nics []*devices.Nic, err error) {
+	m := &manager{}
+	if err = r.get(path, m); err != nil {
+		return version, nics, err
+	}
+
+	nics, err = r.nics(m.EthernetInterfaces, "bmc")
+	return m.FirmwareVersion, nics, err
+}
+
+// health returns the health of a resource, falling back to its state when the health isn't reported
+func health(s status) string {
+	if s.Health != "" {
+		return s.Health
+	}
+	return s.State
+}
@@ -0,0 +1,137 @@
+package redfish
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/errors"
+)
+
+// mockServer serves the resources stored in testdata, every resource but the service root requires authentication
+func mockServer(t *testing.T, name string) *httptest.Server {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(content, &resources); err != nil {
+		t.Fatal(err)
+	}
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resource, found := resources[strings.TrimSuffix(r.URL.Path, "/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		if username, password, _ := r.BasicAuth(); r.URL.Path != "/redfish/v1" && (username != "Priest" || password != "Wololo") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resource)
+	}))
+}
+
+func connect(t *testing.T, server *httptest.Server, password string) *Redfish {
+	r, err := New(strings.TrimPrefix(server.URL, "https://"), "Priest", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestNotRedfish(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := New(strings.TrimPrefix(server.URL, "https://"), "Priest", "Wololo"); err != errors.ErrDeviceNotMatched {
+		t.Errorf("expected %s, got %v", errors.ErrDeviceNotMatched, err)
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	server := mockServer(t, "discrete")
+	defer server.Close()
+
+	r := connect(t, server, "Wrong")
+	if err := r.CheckCredentials(); err != errors.ErrLoginFailed {
+		t.Errorf("expected %s, got %v", errors.ErrLoginFailed, err)
+	}
+
+	r.UpdateCredentials("Priest", "Wololo")
+	if err := r.CheckCredentials(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerSnapshot(t *testing.T) {
+	server := mockServer(t, "discrete")
+	defer server.Close()
+
+	r := connect(t, server, "Wololo")
+	if r.Vendor() != "Lenovo" {
+		t.Errorf("expected the vendor from the oem key, got %q", r.Vendor())
+	}
+
+	if isEnclosure, err := r.IsEnclosure(); err != nil || isEnclosure {
+		t.Fatalf("a rack server is not an enclosure: %v", err)
+	}
+
+	discrete, err := r.ServerSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if discrete.Serial != "j300abcd" || discrete.Model != "ThinkSystem SR650" || discrete.Memory != 384 || discrete.TempC != 22 {
+		t.Errorf("unexpected serial %q, model %q, memory %d or temperature %d", discrete.Serial, discrete.Model, discrete.Memory, discrete.TempC)
+	}
+
+	if discrete.ProcessorCount != 2 || discrete.ProcessorCoreCount != 32 || discrete.ProcessorThreadCount != 64 {
+		t.Errorf("unexpected processors %d, cores %d or threads %d", discrete.ProcessorCount, discrete.ProcessorCoreCount, discrete.ProcessorThreadCount)
+	}
+
+	if len(discrete.Nics) != 3 || discrete.Nics[2].Name != "bmc" || len(discrete.Disks) != 2 || len(discrete.Psus) != 2 {
+		t.Errorf("expected 3 nics with the bmc last, 2 disks and 2 psus, got %d, %d and %d", len(discrete.Nics), len(discrete.Disks), len(discrete.Psus))
+	}
+
+	if discrete.BmcVersion != "2.70" || discrete.PowerKw != 0.31 {
+		t.Errorf("unexpected bmc version %q or power %f", discrete.BmcVersion, discrete.PowerKw)
+	}
+}
+
+func TestChassisSnapshot(t *testing.T) {
+	server := mockServer(t, "enclosure")
+	defer server.Close()
+
+	r := connect(t, server, "Wololo")
+	if isEnclosure, err := r.IsEnclosure(); err != nil || !isEnclosure {
+		t.Fatalf("expected an enclosure: %v", err)
+	}
+
+	chassis, err := r.ChassisSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chassis.Serial != "j302cccc" || len(chassis.Psus) != 6 || len(chassis.Fans) != 10 || !chassis.IsPsuRedundant {
+		t.Errorf("unexpected serial %q, %d psus, %d fans or redundancy %t", chassis.Serial, len(chassis.Psus), len(chassis.Fans), chassis.IsPsuRedundant)
+	}
+
+	if len(chassis.Blades) != 2 {
+		t.Fatalf("expected 2 blades, got %d", len(chassis.Blades))
+	}
+
+	blade := chassis.Blades[1]
+	if blade.Serial != "j301bbbb" || blade.BladePosition != 2 || blade.ChassisSerial != "j302cccc" || len(blade.Nics) != 1 {
+		t.Errorf("unexpected blade %q in position %d of chassis %q with %d nics", blade.Serial, blade.BladePosition, blade.ChassisSerial, len(blade.Nics))
+	}
+}
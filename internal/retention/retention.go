@@ -0,0 +1,105 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+// Policy is the retention of a resource, the rows not updated for MaxAge are purged and archived first with Archive
+type Policy struct {
+	Resource string
+	MaxAge   time.Duration
+	Archive  bool
+}
+
+// Result is what a policy matched and purged
+type Result struct {
+	Policy
+	Before      time.Time
+	Stale       []storage.StaleCount
+	Purged      int
+	ArchiveFile string
+}
+
+// Policies returns the policies set in retention.<resource>.max_age, the other resources are kept forever
+func Policies() (policies []Policy) {
+	for _, resource := range storage.RetentionResources {
+		maxAge := time.Duration(viper.GetInt(fmt.Sprintf("retention.%s.max_age", resource))) * time.Hour
+		if maxAge <= 0 {
+			continue
+		}
+		policies = append(policies, Policy{Resource: resource, MaxAge: maxAge, Archive: viper.GetBool(fmt.Sprintf("retention.%s.archive", resource))})
+	}
+	return policies
+}
+
+// Run applies the policies to the database, with dryRun it only counts what they would purge. A purge failing
+// partway is returned with its results, what it purged and archived so far included
+func Run(db *gorm.DB, policies []Policy, dryRun bool) (results []Result, err error) {
+	now := time.Now()
+	for _, policy := range policies {
+		result := Result{Policy: policy, Before: now.Add(-policy.MaxAge)}
+		if result.Stale, err = storage.CountStale(db, policy.Resource, result.Before); err != nil {
+			return results, err
+		}
+
+		if !dryRun && len(result.Stale) > 0 {
+			err = purge(db, &result, now)
+		}
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// purge deletes what the policy matched, writing it to a file in retention.archive_path first when it's archived
+func purge(db *gorm.DB, result *Result, now time.Time) (err error) {
+	if !result.Archive {
+		result.Purged, err = storage.PurgeStale(db, result.Resource, result.Before, nil)
+		return err
+	}
+
+	dir := viper.GetString("retention.archive_path")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.ndjson", result.Resource, now.Format("20060102T150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	result.ArchiveFile = path
+
+	result.Purged, err = storage.PurgeStale(db, result.Resource, result.Before, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Scheduled applies the configured policies and logs what they purged, the server runs it every retention.interval hours
+func Scheduled(db *gorm.DB) {
+	results, err := Run(db, Policies(), false)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "retention"}).Error(err)
+	}
+
+	for _, result := range results {
+		log.WithFields(log.Fields{"operation": "retention", "resource": result.Resource, "purged": result.Purged, "archive": result.ArchiveFile}).Info("stale rows purged")
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{fmt.Sprintf("retention.%s.purged", result.Resource)}, int64(result.Purged))
+		}
+	}
+}
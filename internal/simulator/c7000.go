@@ -0,0 +1,149 @@
+package simulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bmc-toolbox/bmclib/providers/hp"
+)
+
+// c7000Rimp is the payload of /xmldata?item=all, bmclib expects it to start with <RIMP>
+type c7000Rimp struct {
+	XMLName xml.Name `xml:"RIMP"`
+	hp.Rimp
+}
+
+// soapEnvelope is the minimum of the onboard administrator soap api bmclib needs to log in
+const soapEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope" xmlns:hpoa="hpoa.xsd">
+<SOAP-ENV:Body>%s</SOAP-ENV:Body>
+</SOAP-ENV:Envelope>`
+
+// c7000Handler answers the requests bmclib does to a c7000 onboard administrator
+type c7000Handler struct {
+	device   *Device
+	username string
+	password string
+}
+
+func newC7000Handler(device *Device, username string, password string) http.Handler {
+	return &c7000Handler{device: device, username: username, password: password}
+}
+
+func (c *c7000Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/xmldata":
+		writeXML(w, c.rimp())
+	case r.URL.Path == "/hpoa" && r.Method == http.MethodPost:
+		c.soap(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// soap handles the log in and log out bmclib does, every other soap call is acknowledged with an empty body
+func (c *c7000Handler) soap(w http.ResponseWriter, r *http.Request) {
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Login *struct {
+			Username string `xml:"username"`
+			Password string `xml:"password"`
+		} `xml:"Body>userLogIn"`
+	}
+
+	if err := xml.Unmarshal(payload, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	if request.Login == nil {
+		fmt.Fprintf(w, soapEnvelope, "")
+		return
+	}
+
+	if request.Login.Username != c.username || request.Login.Password != c.password {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, soapEnvelope, "<SOAP-ENV:Fault><SOAP-ENV:Reason><SOAP-ENV:Text>Login failed.</SOAP-ENV:Text></SOAP-ENV:Reason></SOAP-ENV:Fault>")
+		return
+	}
+
+	key := make([]byte, 8)
+	rand.Read(key)
+	fmt.Fprintf(w, soapEnvelope, fmt.Sprintf("<hpoa:userLogInResponse><hpoa:HpOaSessionKeyToken><hpoa:oaSessionKey>%s</hpoa:oaSessionKey></hpoa:HpOaSessionKeyToken></hpoa:userLogInResponse>", hex.EncodeToString(key)))
+}
+
+func (c *c7000Handler) rimp() *c7000Rimp {
+	d := c.device
+	infra := &hp.Infra2{
+		Addr:   d.Address,
+		Status: "OK",
+		Temp:   &hp.Temp{C: 22, Desc: "AMBIENT"},
+		EnclSn: d.Serial,
+		Pn:     "BladeSystem c7000 Enclosure G2",
+		Encl:   d.Name,
+		Rack:   "UnnamedRack",
+		ChassisPower: &hp.ChassisPower{
+			PowerConsumed:  float64(1200 + 250*len(d.Blades)),
+			Redundancy:     "REDUNDANT",
+			RedundancyMode: "AC_REDUNDANT",
+		},
+		Managers: []*hp.Manager{{MgmtIPAddr: d.Address, Role: "ACTIVE", MacAddr: d.mac(0), Status: "OK", Name: "OA1"}},
+		Switches: []*hp.Switch{{Spn: "HP VC FlexFabric 10Gb/24-Port Module"}},
+	}
+
+	for n := 1; n <= d.Psus; n++ {
+		infra.ChassisPower.Powersupply = append(infra.ChassisPower.Powersupply, &hp.Powersupply{
+			Bay:          &hp.Bay{Connection: n},
+			Sn:           fmt.Sprintf("%sP%02d", d.Serial, n),
+			Pn:           "588603-B21",
+			Status:       "OK",
+			Capacity:     2650,
+			ActualOutput: 400,
+		})
+	}
+
+	for n := 1; n <= d.Fans; n++ {
+		infra.Fans = append(infra.Fans, &hp.Fan{
+			Bay:        &hp.Bay{Connection: n},
+			PN:         "412140-B21",
+			ProducName: "Active Cool 200 Fan",
+			PowerUsed:  20,
+			RpmCUR:     6000,
+			RpmMAX:     18000,
+			RpmMIN:     10,
+			Status:     "OK",
+		})
+	}
+
+	for _, blade := range d.Blades {
+		infra.Blades = append(infra.Blades, &hp.Blade{
+			Bay:         &hp.Bay{Connection: blade.bay},
+			Bsn:         blade.Serial,
+			MgmtIPAddr:  blade.Address,
+			MgmtType:    "iLO4",
+			MgmtVersion: iloFirmware + " Jun 15 2017",
+			Name:        blade.Name,
+			Type:        "SERVER",
+			Power:       &hp.Power{PowerConsumed: 250, PowerState: "ON"},
+			Status:      "OK",
+			Spn:         blade.Model,
+			Temp:        &hp.Temp{C: 24, Desc: "AMBIENT"},
+			BladeRomVer: "I36 02/17/2017",
+		})
+	}
+
+	return &c7000Rimp{Rimp: hp.Rimp{
+		Infra2: infra,
+		MP:     &hp.MP{Pn: d.Model, Sn: "OB" + d.Serial, Fwri: "4.70"},
+	}}
+}
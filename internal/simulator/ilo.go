@@ -0,0 +1,258 @@
+package simulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bmc-toolbox/bmclib/providers/hp"
+)
+
+const iloFirmware = "2.54"
+
+// iloRimp is the payload of /xmldata?item=all, bmclib expects it to start with <RIMP>
+type iloRimp struct {
+	XMLName xml.Name `xml:"RIMP"`
+	hp.RimpBlade
+}
+
+// iloHandler answers the requests bmclib does to an iLO 4
+type iloHandler struct {
+	device   *Device
+	username string
+	password string
+	sessions map[string]bool
+	lock     sync.Mutex
+}
+
+func newIloHandler(device *Device, username string, password string) http.Handler {
+	return &iloHandler{device: device, username: username, password: password, sessions: make(map[string]bool)}
+}
+
+func (i *iloHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/xmldata":
+		writeXML(w, i.rimp())
+		return
+	case "/json/login_session":
+		i.login(w, r)
+		return
+	}
+
+	endpoints := map[string]func() interface{}{
+		"/json/overview":           i.overview,
+		"/json/mem_info":           i.memInfo,
+		"/json/proc_info":          i.procInfo,
+		"/json/power_summary":      i.powerSummary,
+		"/json/health_temperature": i.healthTemperature,
+		"/json/license":            i.license,
+		"/json/power_supplies":     i.powerSupplies,
+		"/json/health_phy_drives":  i.healthPhyDrives,
+		"/json/rck_info":           i.rckInfo,
+		"/json/chassis_info":       i.chassisInfo,
+	}
+
+	endpoint, found := endpoints[r.URL.Path]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !i.authenticated(r) {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(w, map[string]string{"message": "JS_ERR_LOST_SESSION"})
+		return
+	}
+
+	writeJSON(w, endpoint())
+}
+
+// login checks the credentials posted by bmclib and gives a session cookie back
+func (i *iloHandler) login(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Method    string `json:"method"`
+		UserLogin string `json:"user_login"`
+		Password  string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "login" {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"message": "JS_ERR_BAD_REQUEST"})
+		return
+	}
+
+	if request.UserLogin != i.username || request.Password != i.password {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(w, map[string]string{"message": "JS_ERR_LOST_SESSION", "details": "Invalid login attempt."})
+		return
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	session := hex.EncodeToString(key)
+
+	i.lock.Lock()
+	i.sessions[session] = true
+	i.lock.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: "sessionKey", Value: session, Path: "/"})
+	writeJSON(w, map[string]string{"session_key": session, "user_name": i.username, "user_account": i.username})
+}
+
+func (i *iloHandler) authenticated(r *http.Request) bool {
+	cookie, err := r.Cookie("sessionKey")
+	if err != nil {
+		return false
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.sessions[cookie.Value]
+}
+
+func (i *iloHandler) rimp() *iloRimp {
+	d := i.device
+	rimp := &iloRimp{RimpBlade: hp.RimpBlade{
+		MP: &hp.MP{
+			Pn:   "Integrated Lights-Out 4 (iLO 4)",
+			Sn:   "ILO" + d.Serial,
+			Fwri: iloFirmware,
+		},
+		HSI: &hp.HSI{
+			Sbsn: d.Serial,
+			Spn:  d.Model,
+			NICS: []*hp.NIC{{Description: "iLO 4", MacAddr: d.mac(0), Status: "OK"}},
+		},
+	}}
+
+	for n := 1; n <= d.Nics; n++ {
+		rimp.HSI.NICS = append(rimp.HSI.NICS, &hp.NIC{Description: "HP FlexFabric 10Gb 2-port 534FLB Adapter", MacAddr: d.mac(n), Status: "OK"})
+	}
+
+	if d.chassis != nil {
+		rimp.BladeSystem = &hp.BladeSystem{
+			Bay:     d.bay,
+			Manager: &hp.Manager{MgmtIPAddr: d.chassis.Address, Role: "ACTIVE", Name: d.chassis.Name},
+		}
+	}
+
+	return rimp
+}
+
+func (i *iloHandler) overview() interface{} {
+	return map[string]interface{}{
+		"server_name":     i.device.Name,
+		"product_name":    i.device.Model,
+		"serial_num":      i.device.Serial,
+		"system_rom":      "I36 v2.40 (02/17/2017)",
+		"system_rom_date": "02/17/2017",
+		"license":         "iLO Advanced",
+		"ilo_fw_version":  iloFirmware + " Jun 15 2017",
+		"ip_address":      i.device.Address,
+		"system_health":   "OP_STATUS_OK",
+		"power":           "ON",
+	}
+}
+
+func (i *iloHandler) memInfo() interface{} {
+	return map[string]interface{}{"mem_total_mem_size": 262144}
+}
+
+func (i *iloHandler) procInfo() interface{} {
+	var processors []map[string]interface{}
+	for n := 0; n < 2; n++ {
+		processors = append(processors, map[string]interface{}{
+			"proc_socket":      fmt.Sprintf("Proc %d", n+1),
+			"proc_name":        "Intel(R) Xeon(R) CPU E5-2680 v4 @ 2.40GHz",
+			"proc_num_cores":   14,
+			"proc_num_threads": 28,
+		})
+	}
+	return map[string]interface{}{"hostpwr_state": "ON", "processors": processors}
+}
+
+func (i *iloHandler) powerSummary() interface{} {
+	return map[string]interface{}{"hostpwr_state": "ON", "power_supply_input_power": 180}
+}
+
+func (i *iloHandler) healthTemperature() interface{} {
+	return map[string]interface{}{
+		"hostpwr_state": "ON",
+		"temperature": []map[string]interface{}{
+			{"label": "01-Inlet Ambient", "location": "Ambient", "status": "OP_STATUS_OK", "currentreading": 21, "temp_unit": "Celsius"},
+			{"label": "02-CPU 1", "location": "CPU", "status": "OP_STATUS_OK", "currentreading": 40, "temp_unit": "Celsius"},
+		},
+	}
+}
+
+func (i *iloHandler) license() interface{} {
+	return map[string]interface{}{"name": "iLO Advanced", "type": "Perpetual"}
+}
+
+func (i *iloHandler) powerSupplies() interface{} {
+	supplies := make([]map[string]interface{}, 0)
+	for n := 1; n <= i.device.Psus; n++ {
+		supplies = append(supplies, map[string]interface{}{
+			"ps_bay":           n,
+			"ps_present":       "PS_YES",
+			"ps_condition":     "PS_OK",
+			"ps_error_code":    "PS_GOOD_IN_USE",
+			"ps_model":         "720478-B21",
+			"ps_serial_num":    fmt.Sprintf("%sP%02d", i.device.Serial, n),
+			"ps_max_cap_watts": 500,
+			"ps_output_watts":  90,
+			"supply":           true,
+		})
+	}
+	return map[string]interface{}{"supplies": supplies, "present_power_reading": 180}
+}
+
+func (i *iloHandler) healthPhyDrives() interface{} {
+	drives := make([]map[string]interface{}, 0)
+	for n := 1; n <= i.device.Disks; n++ {
+		drives = append(drives, map[string]interface{}{
+			"name":            fmt.Sprintf("Physical Drive in Port 1I Box 1 Bay %d", n),
+			"status":          "OP_STATUS_OK",
+			"serial_no":       fmt.Sprintf("%sD%02d", i.device.Serial, n),
+			"model":           "EG1200JEMDA",
+			"capacity":        "1200 GB",
+			"location":        fmt.Sprintf("Port 1I Box 1 Bay %d", n),
+			"fw_version":      "HPD6",
+			"drive_mediatype": "HDD",
+		})
+	}
+	return map[string]interface{}{
+		"hostpwr_state":    "ON",
+		"phy_drive_arrays": []map[string]interface{}{{"physical_drives": drives}},
+	}
+}
+
+func (i *iloHandler) rckInfo() interface{} {
+	info := map[string]interface{}{"ip_addr": "Unknown", "enc_sn": "Unknown"}
+	if i.device.chassis != nil {
+		info["ip_addr"] = i.device.chassis.Address
+		info["enc_name"] = i.device.chassis.Name
+		info["enc_sn"] = i.device.chassis.Serial
+		info["bay_num"] = i.device.bay
+		info["srv_loc"] = fmt.Sprintf("Bay %d", i.device.bay)
+	}
+	return info
+}
+
+func (i *iloHandler) chassisInfo() interface{} {
+	return map[string]interface{}{"node_number": 0, "chassis_sn": ""}
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
+
+func writeXML(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(payload)
+}
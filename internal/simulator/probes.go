@@ -0,0 +1,50 @@
+package simulator
+
+import (
+	"net"
+
+	"github.com/bmc-toolbox/dora/internal/ipmi"
+)
+
+// sshBanner is what the scanner and a ssh client see when connecting to port 22, we never go further than that
+const sshBanner = "SSH-2.0-mpSSH_0.2.1\r\n"
+
+// serveSSH greets every connection with the banner and closes it
+func serveSSH(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte(sshBanner))
+		conn.Close()
+	}
+}
+
+// responder answers rmcp presence pings and ipmi sessions with the fru and sensors of the device
+func (d *Device) responder(username string, password string) *ipmi.Responder {
+	manufacturer, id := "HP", uint32(11)
+	if d.Type == Ipmi {
+		manufacturer, id = "Supermicro", 10876
+	}
+
+	return &ipmi.Responder{
+		Username: username,
+		Password: password,
+		DeviceID: ipmi.DeviceID{ManufacturerID: id, Firmware: "2.70"},
+		PowerOn:  true,
+		FRU: ipmi.FRU{
+			BoardManufacturer:   manufacturer,
+			BoardProduct:        d.Model,
+			BoardSerial:         d.Serial,
+			ProductManufacturer: manufacturer,
+			ProductName:         d.Model,
+			ProductSerial:       d.Serial,
+		},
+		Sensors: []ipmi.Sensor{
+			{Name: "Inlet Temp", Type: ipmi.SensorTemperature, Unit: ipmi.UnitCelsius, Value: 21},
+			{Name: "CPU1 Temp", Type: ipmi.SensorTemperature, Unit: ipmi.UnitCelsius, Value: 40},
+			{Name: "PW Consumption", Type: ipmi.SensorPowerSupply, Unit: ipmi.UnitWatts, Value: 180},
+		},
+	}
+}
@@ -0,0 +1,319 @@
+// Package simulator runs fake bmcs on loopback addresses, so a full scan and collection can be done locally
+// without any hardware. Every device listens on its own address with the ports the scanner probes: ssh on 22,
+// https on 443 and rmcp on 623. The https server answers the requests bmclib does to discover and collect an
+// HP iLO (discrete or blade) or an HP c7000 chassis, and rmcp speaks ipmi over lan. Devices of type ipmi
+// only listen on 623, like the bmcs exposing nothing but ipmi.
+package simulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	stdlog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Types of devices we know how to simulate
+const (
+	Ilo   = "ilo"
+	C7000 = "c7000"
+	Ipmi  = "ipmi"
+)
+
+// Ports the simulated devices listen on, they match what the scanner probes
+const (
+	sshPort   = 22
+	httpsPort = 443
+	ipmiPort  = 623
+)
+
+// Device describes a simulated bmc, everything not given is filled with generated values
+type Device struct {
+	Address string    `mapstructure:"address"`
+	Type    string    `mapstructure:"type"`
+	Serial  string    `mapstructure:"serial"`
+	Model   string    `mapstructure:"model"`
+	Name    string    `mapstructure:"name"`
+	Nics    int       `mapstructure:"nics"`
+	Disks   int       `mapstructure:"disks"`
+	Psus    int       `mapstructure:"psus"`
+	Fans    int       `mapstructure:"fans"`
+	Blades  []*Device `mapstructure:"blades"`
+
+	// Filled for the blades of a chassis
+	bay     int
+	chassis *Device
+}
+
+// setDefaults fills the missing attributes of the device and its blades
+func (d *Device) setDefaults() error {
+	if net.ParseIP(d.Address) == nil {
+		return fmt.Errorf("invalid address %q for device %s", d.Address, d.Serial)
+	}
+
+	if d.Type == "" {
+		d.Type = Ilo
+	}
+
+	if d.Serial == "" {
+		d.Serial = fmt.Sprintf("SIM%07d", hash(d.Address)%10000000)
+	}
+	d.Serial = strings.ToUpper(d.Serial)
+
+	if d.Name == "" {
+		d.Name = fmt.Sprintf("sim-%s", strings.ToLower(d.Serial))
+	}
+
+	switch d.Type {
+	case Ilo:
+		if len(d.Blades) > 0 {
+			return fmt.Errorf("device %s of type %s can't have blades", d.Serial, d.Type)
+		}
+		if d.Model == "" {
+			d.Model = "ProLiant DL380 Gen9"
+			if d.chassis != nil {
+				d.Model = "ProLiant BL460c Gen9"
+			}
+		}
+		if d.Nics == 0 {
+			d.Nics = 2
+		}
+		if d.Psus == 0 && d.chassis == nil {
+			d.Psus = 2
+		}
+	case Ipmi:
+		if len(d.Blades) > 0 {
+			return fmt.Errorf("device %s of type %s can't have blades", d.Serial, d.Type)
+		}
+		if d.Model == "" {
+			d.Model = "SYS-1029U-TR4"
+		}
+	case C7000:
+		if d.Model == "" {
+			d.Model = "BladeSystem c7000 DDR2 Onboard Administrator with KVM"
+		}
+		if d.Psus == 0 {
+			d.Psus = 6
+		}
+		if d.Fans == 0 {
+			d.Fans = 10
+		}
+		for position, blade := range d.Blades {
+			if blade.Type != "" && blade.Type != Ilo {
+				return fmt.Errorf("blade %s of chassis %s must be of type %s", blade.Serial, d.Serial, Ilo)
+			}
+			blade.bay = position + 1
+			blade.chassis = d
+			if err := blade.setDefaults(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown type %q for device %s", d.Type, d.Serial)
+	}
+
+	return nil
+}
+
+// mac generates a stable mac address for the nth interface of the device
+func (d *Device) mac(n int) string {
+	h := hash(d.Serial)
+	return fmt.Sprintf("02:%02x:%02x:%02x:%02x:%02x", byte(h>>24), byte(h>>16), byte(h>>8), byte(h), byte(n))
+}
+
+// hash returns a stable number for the given string
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// Simulator holds the listeners of all simulated devices
+type Simulator struct {
+	username string
+	password string
+	devices  []*Device
+	servers  []*http.Server
+	closers  []func() error
+	wg       sync.WaitGroup
+}
+
+// New validates the devices and returns a simulator accepting the given credentials
+func New(username string, password string, devices []*Device) (s *Simulator, err error) {
+	s = &Simulator{username: username, password: password}
+
+	seen := make(map[string]bool)
+	for _, device := range devices {
+		if err = device.setDefaults(); err != nil {
+			return nil, err
+		}
+
+		for _, d := range append([]*Device{device}, device.Blades...) {
+			if seen[d.Address] {
+				return nil, fmt.Errorf("address %s is used by more than one device", d.Address)
+			}
+			seen[d.Address] = true
+			s.devices = append(s.devices, d)
+		}
+	}
+
+	return s, nil
+}
+
+// Generate returns discretes and chassis with the given number of blades, using consecutive addresses from start
+func Generate(start string, discretes int, chassis int, blades int) (devices []*Device, err error) {
+	ip := net.ParseIP(start).To4()
+	if ip == nil {
+		return devices, fmt.Errorf("invalid ipv4 address %q", start)
+	}
+	ip = append(net.IP(nil), ip...)
+
+	next := func() string {
+		address := ip.String()
+		for i := len(ip) - 1; i >= 0; i-- {
+			ip[i]++
+			if ip[i] > 0 {
+				break
+			}
+		}
+		return address
+	}
+
+	for i := 0; i < discretes; i++ {
+		devices = append(devices, &Device{Address: next(), Type: Ilo, Disks: 2})
+	}
+
+	for i := 0; i < chassis; i++ {
+		c := &Device{Address: next(), Type: C7000}
+		for j := 0; j < blades; j++ {
+			c.Blades = append(c.Blades, &Device{Address: next(), Type: Ilo, Disks: 2})
+		}
+		devices = append(devices, c)
+	}
+
+	return devices, err
+}
+
+// Devices returns all simulated devices, blades included
+func (s *Simulator) Devices() []*Device {
+	return s.devices
+}
+
+// Start binds the ports of every device, listening on ports below 1024 requires root or CAP_NET_BIND_SERVICE
+func (s *Simulator) Start() (err error) {
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		return err
+	}
+
+	for _, device := range s.devices {
+		if err = s.listen(device, cert); err != nil {
+			s.Close()
+			return err
+		}
+		log.WithFields(log.Fields{"operation": "simulate", "ip": device.Address, "type": device.Type, "serial": device.Serial}).Info("device started")
+	}
+
+	return nil
+}
+
+func (s *Simulator) listen(device *Device, cert tls.Certificate) error {
+	rmcp, err := net.ListenPacket("udp", net.JoinHostPort(device.Address, fmt.Sprint(ipmiPort)))
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, rmcp.Close)
+	responder := device.responder(s.username, s.password)
+	s.serve(func() { responder.Serve(rmcp) })
+
+	if device.Type == Ipmi {
+		return nil
+	}
+
+	https, err := net.Listen("tcp", net.JoinHostPort(device.Address, fmt.Sprint(httpsPort)))
+	if err != nil {
+		return err
+	}
+
+	// The scanner opens and closes connections without a handshake, we don't want a log line for each of them
+	server := &http.Server{Handler: Handler(device, s.username, s.password), ErrorLog: stdlog.New(ioutil.Discard, "", 0)}
+	s.servers = append(s.servers, server)
+	s.serve(func() { server.Serve(tls.NewListener(https, &tls.Config{Certificates: []tls.Certificate{cert}})) })
+
+	ssh, err := net.Listen("tcp", net.JoinHostPort(device.Address, fmt.Sprint(sshPort)))
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, ssh.Close)
+	s.serve(func() { serveSSH(ssh) })
+
+	return nil
+}
+
+func (s *Simulator) serve(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// Close stops all devices and waits for them to finish
+func (s *Simulator) Close() {
+	for _, server := range s.servers {
+		server.Close()
+	}
+
+	for _, closer := range s.closers {
+		closer()
+	}
+
+	s.wg.Wait()
+}
+
+// Handler returns the https handler of the device, it only accepts the given credentials
+func Handler(device *Device, username string, password string) http.Handler {
+	switch device.Type {
+	case C7000:
+		return newC7000Handler(device, username, password)
+	default:
+		return newIloHandler(device, username, password)
+	}
+}
+
+// selfSignedCertificate creates the certificate used by the https servers, bmclib doesn't verify it
+func selfSignedCertificate() (cert tls.Certificate, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return cert, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"dora simulator"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return cert, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
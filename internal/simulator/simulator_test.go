@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclib/discover"
+	"github.com/bmc-toolbox/dora/internal/ipmi"
+	"github.com/bmc-toolbox/dora/scanner"
+)
+
+func startDevice(t *testing.T, device *Device) (host string, close func()) {
+	if err := device.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewTLSServer(Handler(device, "Priest", "Wololo"))
+	return strings.TrimPrefix(server.URL, "https://"), server.Close
+}
+
+func TestDiscrete(t *testing.T) {
+	host, close := startDevice(t, &Device{Address: "127.0.0.1", Serial: "sim0000001", Nics: 4, Disks: 3, Psus: 2})
+	defer close()
+
+	conn, err := discover.ScanAndConnect(host, "Priest", "Wololo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bmc, ok := conn.(devices.Bmc)
+	if !ok {
+		t.Fatalf("expected a bmc, got %T", conn)
+	}
+
+	if err := bmc.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := bmc.ServerSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discrete, ok := snapshot.(*devices.Discrete)
+	if !ok {
+		t.Fatalf("expected a discrete, got %T", snapshot)
+	}
+
+	if discrete.Serial != "sim0000001" || len(discrete.Nics) != 5 || len(discrete.Disks) != 3 || len(discrete.Psus) != 2 {
+		t.Errorf("unexpected serial %q, %d nics, %d disks and %d psus", discrete.Serial, len(discrete.Nics), len(discrete.Disks), len(discrete.Psus))
+	}
+}
+
+func TestWrongCredentials(t *testing.T) {
+	host, close := startDevice(t, &Device{Address: "127.0.0.1"})
+	defer close()
+
+	conn, err := discover.ScanAndConnect(host, "Priest", "Wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.(devices.Bmc).CheckCredentials(); err == nil {
+		t.Error("login must fail with the wrong password")
+	}
+}
+
+func TestChassis(t *testing.T) {
+	chassis := &Device{Address: "127.0.0.1", Type: C7000, Serial: "sim0000100", Psus: 4, Blades: []*Device{{Address: "127.0.0.2"}, {Address: "127.0.0.3"}}}
+	host, close := startDevice(t, chassis)
+	defer close()
+
+	conn, err := discover.ScanAndConnect(host, "Priest", "Wololo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmc, ok := conn.(devices.Cmc)
+	if !ok {
+		t.Fatalf("expected a chassis, got %T", conn)
+	}
+
+	if err := cmc.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := cmc.ChassisSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot.Serial != "sim0000100" || len(snapshot.Blades) != 2 || len(snapshot.Psus) != 4 || len(snapshot.Fans) != 10 {
+		t.Errorf("unexpected serial %q, %d blades, %d psus and %d fans", snapshot.Serial, len(snapshot.Blades), len(snapshot.Psus), len(snapshot.Fans))
+	}
+
+	if snapshot.Blades[1].BmcAddress != "127.0.0.3" || snapshot.Blades[1].BladePosition != 2 {
+		t.Errorf("unexpected blade address %s in bay %d", snapshot.Blades[1].BmcAddress, snapshot.Blades[1].BladePosition)
+	}
+}
+
+func TestRMCP(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	device := &Device{Address: "127.0.0.1", Type: Ipmi, Serial: "sim0000200"}
+	if err = device.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	go device.responder("Priest", "Wololo").Serve(conn)
+
+	result, err := scanner.Probe("ipmi", "127.0.0.1", conn.LocalAddr().(*net.UDPAddr).Port, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.String() != "open" {
+		t.Errorf("expected the ipmi port to be open, got %s", result)
+	}
+
+	client := ipmi.New(conn.LocalAddr().String(), "Priest", "Wololo")
+	defer client.Close()
+	if err = client.CheckCredentials(); err != nil {
+		t.Fatal(err)
+	}
+
+	fru, err := client.FRU()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fru.ProductSerial != "SIM0000200" || fru.ProductManufacturer != "Supermicro" {
+		t.Errorf("unexpected serial %q or manufacturer %q", fru.ProductSerial, fru.ProductManufacturer)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/bmc-toolbox/dora/storage"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -29,9 +30,9 @@ func TestGatherDBStats(t *testing.T) {
 		AddRow(3)
 
 	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\"$").WillReturnRows(resultRows)
-	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \"updated_at\".*").WillReturnRows(resultRows)
-	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \\(vendor in \\('hp'\\)\\)$").WillReturnRows(resultRows)
-	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \\(vendor in \\('hp'\\)\\) AND \"updated_at\".*$").WillReturnRows(resultRows)
+	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \\(\"updated_at\" < \\$1\\)$").WithArgs(sqlmock.AnyArg()).WillReturnRows(resultRows)
+	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \\(\"vendor\" in \\(\\$1\\)\\)$").WithArgs("HP").WillReturnRows(resultRows)
+	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"chasses\" WHERE \\(\"vendor\" in \\(\\$1\\)\\) AND \\(\"updated_at\" < \\$2\\)$").WithArgs("HP", sqlmock.AnyArg()).WillReturnRows(resultRows)
 	// to prevent errors like "all expectations were already fulfilled" in logs
 	zeroRow := sqlmock.NewRows([]string{"count(*)"})
 	for i := 0; i <= 100; i++ {
@@ -55,8 +56,8 @@ func TestGatherDBStats(t *testing.T) {
 		"total count of chassis is right")
 	assert.EqualValues(t, 5, s.Chassis.Updated24hAgo,
 		"total count of updated 24h ago chassis is right")
-	assert.EqualValues(t, 4, s.Chassis.Vendors["hp"].Total,
+	assert.EqualValues(t, 4, s.Chassis.Vendors[devices.HP].Total,
 		"total value of hp chassis is right")
-	assert.EqualValues(t, 3, s.Chassis.Vendors["hp"].Updated24hAgo,
+	assert.EqualValues(t, 3, s.Chassis.Vendors[devices.HP].Updated24hAgo,
 		"total count of updated 24h ago hp chassis is right")
 }
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"crypto/md5"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// AssetChange contains the fields of an asset that changed between two collections
+type AssetChange struct {
+	ID          string       `gorm:"primary_key" json:"-"`
+	AssetType   string       `gorm:"index" json:"asset_type"`
+	AssetSerial string       `gorm:"index" json:"asset_serial"`
+	Source      string       `json:"source"`
+	ChangedAt   time.Time    `gorm:"index" json:"changed_at"`
+	Changes     FieldChanges `gorm:"type:text" json:"changes"`
+}
+
+// GenID generates the ID based on the date we have
+func (a *AssetChange) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", a.AssetType, a.AssetSerial, a.ChangedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (a *AssetChange) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", a.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (a AssetChange) GetName() string {
+	return "asset_changes"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (a AssetChange) GetID() string {
+	return a.ID
+}
+
+// FieldChange is the value of a field before and after a collection. Components are addressed by their id,
+// eg: disks[s3yjnx0k123456].status, and have an empty before when they appeared or an empty after when they are gone
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// FieldChanges is stored as json in a single column
+type FieldChanges []FieldChange
+
+// Value to satisfy the driver.Valuer interface
+func (f FieldChanges) Value() (driver.Value, error) {
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+// Scan to satisfy the sql.Scanner interface
+func (f *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, f)
+	case string:
+		return json.Unmarshal([]byte(v), f)
+	case nil:
+		*f = nil
+		return nil
+	default:
+		return fmt.Errorf("unable to scan %T into FieldChanges", value)
+	}
+}
+
+// Fields we keep overwriting on every collection, their history lives in the readings
+var volatileFields = map[string]bool{
+	"updated_at":   true,
+	"power_kw":     true,
+	"temp_c":       true,
+	"current_rpm":  true,
+	"last_seen_at": true,
+	"removed_at":   true,
+}
+
+// Changes returns the fields of the discrete and its components that differ from the previous collection
+func (d *Discrete) Changes(previous *Discrete) FieldChanges {
+	return fieldChanges("", reflect.ValueOf(previous).Elem(), reflect.ValueOf(d).Elem())
+}
+
+// Changes returns the fields of the blade and its components that differ from the previous collection
+func (b *Blade) Changes(previous *Blade) FieldChanges {
+	return fieldChanges("", reflect.ValueOf(previous).Elem(), reflect.ValueOf(b).Elem())
+}
+
+// Changes returns the fields of the chassis, its blades and components that differ from the previous collection
+func (c *Chassis) Changes(previous *Chassis) FieldChanges {
+	return fieldChanges("", reflect.ValueOf(previous).Elem(), reflect.ValueOf(c).Elem())
+}
+
+// fieldChanges walks both structs comparing the exposed fields and matching the components by their id
+func fieldChanges(prefix string, before reflect.Value, after reflect.Value) (changes FieldChanges) {
+	for i := 0; i < before.NumField(); i++ {
+		field := before.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if field.Type.Kind() == reflect.Slice && isComponent(field.Type.Elem()) {
+			changes = append(changes, componentChanges(prefix+snakeCase(field.Name), before.Field(i), after.Field(i))...)
+			continue
+		}
+
+		if name == "-" || name == "" || volatileFields[name] {
+			continue
+		}
+
+		b, a := fmt.Sprint(before.Field(i).Interface()), fmt.Sprint(after.Field(i).Interface())
+		if b != a {
+			changes = append(changes, FieldChange{Field: prefix + name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// componentChanges reports the components that appeared or are gone and the changes of the ones in both lists
+func componentChanges(prefix string, before reflect.Value, after reflect.Value) (changes FieldChanges) {
+	previous := make(map[string]reflect.Value)
+	for i := 0; i < before.Len(); i++ {
+		if !before.Index(i).IsNil() {
+			previous[componentID(before.Index(i))] = before.Index(i).Elem()
+		}
+	}
+
+	current := make(map[string]bool)
+	for i := 0; i < after.Len(); i++ {
+		if after.Index(i).IsNil() {
+			continue
+		}
+
+		id := componentID(after.Index(i))
+		current[id] = true
+		path := fmt.Sprintf("%s[%s]", prefix, id)
+		if old, found := previous[id]; found {
+			changes = append(changes, fieldChanges(path+".", old, after.Index(i).Elem())...)
+		} else {
+			changes = append(changes, FieldChange{Field: path, After: id})
+		}
+	}
+
+	for i := 0; i < before.Len(); i++ {
+		if before.Index(i).IsNil() {
+			continue
+		}
+
+		if id := componentID(before.Index(i)); !current[id] {
+			changes = append(changes, FieldChange{Field: fmt.Sprintf("%s[%s]", prefix, id), Before: id})
+		}
+	}
+	return changes
+}
+
+// isComponent tells whether the slice holds components we can identify, like nics, disks or blades
+func isComponent(t reflect.Type) bool {
+	_, ok := reflect.Zero(t).Interface().(interface{ GetID() string })
+	return ok && t.Kind() == reflect.Ptr
+}
+
+func componentID(v reflect.Value) string {
+	return v.Interface().(interface{ GetID() string }).GetID()
+}
+
+// snakeCase names the components like their jsonapi relationship, eg: StorageBlades becomes storage_blades
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
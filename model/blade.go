@@ -103,6 +103,8 @@ type Blade struct {
 	StorageBlade         StorageBlade `json:"-" gorm:"ForeignKey:BladeSerial"`
 	Memory               int          `json:"memory_in_gb"`
 	UpdatedAt            time.Time    `json:"updated_at"`
+	LastSeenAt           time.Time    `json:"last_seen_at"`
+	DeletedAt            *time.Time   `json:"removed_at" gorm:"column:removed_at;index"`
 }
 
 // GetID to satisfy jsonapi.MarshalIdentifier interface
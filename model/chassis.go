@@ -10,7 +10,6 @@ import (
 	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/bmc-toolbox/bmclib/errors"
 	"github.com/kr/pretty"
-	"github.com/lib/pq"
 	"github.com/manyminds/api2go/jsonapi"
 )
 
@@ -110,7 +109,7 @@ type Chassis struct {
 	BmcWEBReachable   bool            `json:"bmc_web_reachable"`
 	BmcAuth           bool            `json:"bmc_auth"`
 	Blades            []*Blade        `json:"-" gorm:"ForeignKey:ChassisSerial"`
-	FaultySlots       pq.Int64Array   `json:"faulty_slots" gorm:"type:integer[2]"`
+	FaultySlots       Slots           `json:"faulty_slots"`
 	StorageBlades     []*StorageBlade `json:"-" gorm:"ForeignKey:ChassisSerial"`
 	Nics              []*Nic          `json:"-" gorm:"ForeignKey:ChassisSerial"`
 	Psus              []*Psu          `json:"-" gorm:"ForeignKey:ChassisSerial"`
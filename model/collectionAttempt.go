@@ -0,0 +1,82 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Collection outcomes stored in CollectionAttempt.Outcome
+const (
+	// CollectionSucceeded means the asset was collected and stored
+	CollectionSucceeded = "success"
+	// CollectionSkipped means we reached the bmc but decided not to collect it (eg: blade of a managed chassis)
+	CollectionSkipped = "skipped"
+	// CollectionScanFailed means we were unable to identify or connect to the bmc
+	CollectionScanFailed = "scan_failed"
+	// CollectionWrongCredentials means none of the credentials we have were accepted
+	CollectionWrongCredentials = "wrong_credentials"
+	// CollectionCredentialsUnavailable means we failed to retrieve the credentials for the bmc
+	CollectionCredentialsUnavailable = "credentials_unavailable"
+	// CollectionConnectionFailed means the bmc was identified, but we failed to talk to it
+	CollectionConnectionFailed = "connection_failed"
+	// CollectionBladeDetectionFailed means we couldn't find out whether the bmc belongs to a blade
+	CollectionBladeDetectionFailed = "blade_detection_failed"
+	// CollectionFailed means we logged in, but failed to read or store the data
+	CollectionFailed = "collection_failed"
+	// CollectionRemovalRefused means the asset was stored, but the removal guard kept the components it didn't report
+	CollectionRemovalRefused = "removal_refused"
+	// CollectionTimeout means the collection didn't finish within collector.host_timeout or the run deadline, nothing was stored
+	CollectionTimeout = "timeout"
+	// CollectionCancelled means the collection was interrupted, nothing was stored
+	CollectionCancelled = "cancelled"
+	// CollectionUnknownDevice means the device isn't supported by any collector
+	CollectionUnknownDevice = "unknown_device"
+	// CollectionStandby means we logged into a standby chassis manager, the chassis is collected through the active one
+	CollectionStandby = "standby"
+)
+
+// CollectionAttempt contains the result of each time we tried to collect a bmc
+type CollectionAttempt struct {
+	ID           string    `gorm:"primary_key" json:"-"`
+	IP           string    `gorm:"index" json:"ip"`
+	Site         string    `gorm:"index" json:"site"`
+	Source       string    `json:"source"`
+	StartedAt    time.Time `gorm:"index" json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Outcome      string    `gorm:"index" json:"outcome"`
+	Error        string    `gorm:"type:text" json:"error"`
+	Vendor       string    `json:"vendor"`
+	HardwareType string    `json:"hardware_type"`
+	Credential   string    `json:"credential"`
+	// Serial is the one of the asset collected, empty when the collection failed before reading it
+	Serial string `gorm:"index" json:"serial"`
+}
+
+// GenID generates the ID based on the date we have
+func (c *CollectionAttempt) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", c.IP, c.Source, c.StartedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (c *CollectionAttempt) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", c.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (c CollectionAttempt) GetName() string {
+	return "collection_attempts"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (c CollectionAttempt) GetID() string {
+	return c.ID
+}
@@ -109,6 +109,7 @@ type Discrete struct {
 	ProcessorCoreCount   int       `json:"processor_core_count"`
 	ProcessorThreadCount int       `json:"processor_thread_count"`
 	Memory               int       `json:"memory_in_gb"`
+	DataSource           string    `json:"data_source"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
 
@@ -16,16 +16,18 @@ To make the magic of dynamic filtering work, we need to define each json field m
 
 // Disk represents a disk device
 type Disk struct {
-	Serial         string    `json:"serial" gorm:"primary_key"`
-	Status         string    `json:"status"`
-	Type           string    `json:"type"`
-	Size           string    `json:"size"`
-	Model          string    `json:"model"`
-	Location       string    `json:"location"`
-	FwVersion      string    `json:"fw_version"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	BladeSerial    string    `json:"-"`
-	DiscreteSerial string    `json:"-"`
+	Serial         string     `json:"serial" gorm:"primary_key"`
+	Status         string     `json:"status"`
+	Type           string     `json:"type"`
+	Size           string     `json:"size"`
+	Model          string     `json:"model"`
+	Location       string     `json:"location"`
+	FwVersion      string     `json:"fw_version"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	DeletedAt      *time.Time `json:"removed_at" gorm:"column:removed_at;index"`
+	BladeSerial    string     `json:"-"`
+	DiscreteSerial string     `json:"-"`
 }
 
 // GetID to satisfy jsonapi.MarshalIdentifier interface
@@ -16,14 +16,16 @@ To make the magic of dynamic filtering work, we need to define each json field m
 
 // Fan contains the network information of the cards attached to blades or chassis
 type Fan struct {
-	Serial        string    `json:"serial" gorm:"primary_key"`
-	Status        string    `json:"status"`
-	Position      int       `json:"position"`
-	Model         string    `json:"model"`
-	CurrentRPM    int64     `json:"current_rpm"`
-	PowerKw       float64   `json:"power_kw"`
-	ChassisSerial string    `json:"-"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	Serial        string     `json:"serial" gorm:"primary_key"`
+	Status        string     `json:"status"`
+	Position      int        `json:"position"`
+	Model         string     `json:"model"`
+	CurrentRPM    int64      `json:"current_rpm"`
+	PowerKw       float64    `json:"power_kw"`
+	ChassisSerial string     `json:"-"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	DeletedAt     *time.Time `json:"removed_at" gorm:"column:removed_at;index"`
 }
 
 // GetID to satisfy jsonapi.MarshalIdentifier interface
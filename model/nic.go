@@ -16,13 +16,15 @@ To make the magic of dynamic filtering work, we need to define each json field m
 
 // Nic contains the network information of the cards attached to blades or chassis
 type Nic struct {
-	MacAddress     string    `json:"mac_address" gorm:"primary_key"`
-	Name           string    `json:"name"`
-	Speed          string    `json:"speed"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	BladeSerial    string    `json:"-"`
-	DiscreteSerial string    `json:"-"`
-	ChassisSerial  string    `json:"-"`
+	MacAddress     string     `json:"mac_address" gorm:"primary_key"`
+	Name           string     `json:"name"`
+	Speed          string     `json:"speed"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	DeletedAt      *time.Time `json:"removed_at" gorm:"column:removed_at;index"`
+	BladeSerial    string     `json:"-"`
+	DiscreteSerial string     `json:"-"`
+	ChassisSerial  string     `json:"-"`
 }
 
 // GetID to satisfy jsonapi.MarshalIdentifier interface
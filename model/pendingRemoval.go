@@ -0,0 +1,21 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+)
+
+// PendingRemoval remembers a removal the guard refused, the next collection confirms it by reporting the same removal
+type PendingRemoval struct {
+	ID           string    `gorm:"primary_key" json:"-"`
+	ParentSerial string    `gorm:"index" json:"parent_serial"`
+	Component    string    `json:"component"`
+	Removing     int       `json:"removing"`
+	RefusedAt    time.Time `json:"refused_at"`
+}
+
+// GenID generates the ID based on the date we have
+func (p *PendingRemoval) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s", p.ParentSerial, p.Component))))
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// PortStateChange is a scan that found a port in a different state than the previous one. Ports found closed
+// the first time they are scanned aren't recorded, so a change with an empty previous state is a new port
+type PortStateChange struct {
+	ID            string    `gorm:"primary_key" json:"-"`
+	ScannedPortID string    `gorm:"index" json:"scanned_port_id"`
+	Site          string    `gorm:"index" json:"site"`
+	CIDR          string    `gorm:"column:cidr" json:"cidr"`
+	IP            string    `gorm:"index" json:"ip"`
+	Port          int       `json:"port"`
+	Protocol      string    `json:"protocol"`
+	ScannedBy     string    `json:"scanned_by"`
+	PreviousState string    `json:"previous_state"`
+	State         string    `gorm:"index" json:"state"`
+	ChangedAt     time.Time `gorm:"index" json:"changed_at"`
+}
+
+// NewPortStateChange returns the change of the port from the previous state to its current one
+func NewPortStateChange(port *ScannedPort, previous string, changedAt time.Time) *PortStateChange {
+	return &PortStateChange{
+		ScannedPortID: port.ID,
+		Site:          port.Site,
+		CIDR:          port.CIDR,
+		IP:            port.IP,
+		Port:          port.Port,
+		Protocol:      port.Protocol,
+		ScannedBy:     port.ScannedBy,
+		PreviousState: previous,
+		State:         port.State,
+		ChangedAt:     changedAt,
+	}
+}
+
+// GenID generates the ID based on the date we have
+func (p *PortStateChange) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", p.ScannedPortID, p.ChangedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (p *PortStateChange) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", p.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (p PortStateChange) GetName() string {
+	return "port_state_changes"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (p PortStateChange) GetID() string {
+	return p.ID
+}
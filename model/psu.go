@@ -16,14 +16,16 @@ To make the magic of dynamic filtering work, we need to define each json field m
 
 // Psu contains the network information of the cards attached to blades or chassis
 type Psu struct {
-	Serial         string    `json:"serial" gorm:"primary_key"`
-	CapacityKw     float64   `json:"capacity_kw"`
-	PowerKw        float64   `json:"power_kw"`
-	Status         string    `json:"status"`
-	PartNumber     string    `json:"part_number"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	DiscreteSerial string    `json:"-"`
-	ChassisSerial  string    `json:"-"`
+	Serial         string     `json:"serial" gorm:"primary_key"`
+	CapacityKw     float64    `json:"capacity_kw"`
+	PowerKw        float64    `json:"power_kw"`
+	Status         string     `json:"status"`
+	PartNumber     string     `json:"part_number"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	DeletedAt      *time.Time `json:"removed_at" gorm:"column:removed_at;index"`
+	DiscreteSerial string     `json:"-"`
+	ChassisSerial  string     `json:"-"`
 }
 
 // GetID to satisfy jsonapi.MarshalIdentifier interface
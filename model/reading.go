@@ -0,0 +1,106 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Metrics stored in Reading.Metric
+const (
+	// MetricTempC is the temperature in celsius reported by the asset
+	MetricTempC = "temp_c"
+	// MetricPowerKw is the power consumption in kilowatts reported by the asset
+	MetricPowerKw = "power_kw"
+	// MetricRPM is the current speed of a fan
+	MetricRPM = "rpm"
+)
+
+// Reading is a single sensor value of an asset at the time it was collected. Readings older than
+// collector.readings.raw_max_age are averaged into one reading per collector.readings.resolution
+type Reading struct {
+	ID          string    `gorm:"primary_key" json:"-"`
+	AssetSerial string    `gorm:"index" json:"asset_serial"`
+	AssetType   string    `gorm:"index" json:"asset_type"`
+	Metric      string    `gorm:"index" json:"metric"`
+	Value       float64   `json:"value"`
+	Samples     int       `json:"samples"`
+	Resolution  int       `gorm:"index" json:"resolution"`
+	CollectedAt time.Time `gorm:"index" json:"collected_at"`
+}
+
+// GenID generates the ID based on the date we have
+func (r *Reading) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%s-%d", r.AssetSerial, r.AssetType, r.Metric, r.CollectedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (r *Reading) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", r.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (r Reading) GetName() string {
+	return "readings"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (r Reading) GetID() string {
+	return r.ID
+}
+
+// newReading returns a raw reading, zero values are left out as most bmcs report 0 for the sensors they don't have
+func newReading(readings []*Reading, serial string, assetType string, metric string, value float64, collectedAt time.Time) []*Reading {
+	if serial == "" || value == 0 {
+		return readings
+	}
+
+	return append(readings, &Reading{
+		AssetSerial: serial,
+		AssetType:   assetType,
+		Metric:      metric,
+		Value:       value,
+		Samples:     1,
+		CollectedAt: collectedAt,
+	})
+}
+
+// Readings returns the temperature and power of the discrete and the power of its psus
+func (d *Discrete) Readings(collectedAt time.Time) (readings []*Reading) {
+	readings = newReading(readings, d.Serial, "discrete", MetricTempC, float64(d.TempC), collectedAt)
+	readings = newReading(readings, d.Serial, "discrete", MetricPowerKw, d.PowerKw, collectedAt)
+	for _, psu := range d.Psus {
+		readings = newReading(readings, psu.Serial, "psu", MetricPowerKw, psu.PowerKw, collectedAt)
+	}
+	return readings
+}
+
+// Readings returns the temperature and power of the blade
+func (b *Blade) Readings(collectedAt time.Time) (readings []*Reading) {
+	readings = newReading(readings, b.Serial, "blade", MetricTempC, float64(b.TempC), collectedAt)
+	return newReading(readings, b.Serial, "blade", MetricPowerKw, b.PowerKw, collectedAt)
+}
+
+// Readings returns the temperature and power of the chassis, its blades and psus and the speed of its fans
+func (c *Chassis) Readings(collectedAt time.Time) (readings []*Reading) {
+	readings = newReading(readings, c.Serial, "chassis", MetricTempC, float64(c.TempC), collectedAt)
+	readings = newReading(readings, c.Serial, "chassis", MetricPowerKw, c.PowerKw, collectedAt)
+	for _, blade := range c.Blades {
+		readings = append(readings, blade.Readings(collectedAt)...)
+	}
+	for _, psu := range c.Psus {
+		readings = newReading(readings, psu.Serial, "psu", MetricPowerKw, psu.PowerKw, collectedAt)
+	}
+	for _, fan := range c.Fans {
+		readings = newReading(readings, fan.Serial, "fan", MetricRPM, float64(fan.CurrentRPM), collectedAt)
+	}
+	return readings
+}
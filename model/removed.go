@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* Components are never deleted when a collection stops reporting them, they are kept with removed_at set
+
+The field is called DeletedAt so gorm treats it as a soft delete: Delete marks the rows as removed and every
+query skips them unless it's Unscoped. Purging them for good is done by storage.PurgeRemoved
+
+*/
+
+// Restoring is set on the gorm scope when the records come from an export, they keep the last_seen_at they had
+const Restoring = "dora:restoring"
+
+// lastSeen sets LastSeenAt to now, unless the record is being restored
+func lastSeen(scope *gorm.Scope) (err error) {
+	if _, ok := scope.Get(Restoring); ok {
+		return nil
+	}
+	return scope.SetColumn("LastSeenAt", time.Now())
+}
+
+// BeforeSave records when the blade was last seen by a collection
+func (b *Blade) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
+
+// BeforeSave records when the disk was last seen by a collection
+func (d *Disk) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
+
+// BeforeSave records when the fan was last seen by a collection
+func (p *Fan) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
+
+// BeforeSave records when the nic was last seen by a collection
+func (n *Nic) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
+
+// BeforeSave records when the psu was last seen by a collection
+func (p *Psu) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
+
+// BeforeSave records when the storage blade was last seen by a collection
+func (s *StorageBlade) BeforeSave(scope *gorm.Scope) (err error) {
+	return lastSeen(scope)
+}
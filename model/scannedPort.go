@@ -14,17 +14,29 @@ To make the magic of dynamic filtering work, we need to define each json field m
 
 */
 
-// ScannedPort contains all ports found by the scanner
+// Port states stored in ScannedPort.State
+const (
+	// PortOpen means the port answered the probe
+	PortOpen = "open"
+	// PortClosed means the port didn't answer the probe
+	PortClosed = "closed"
+)
+
+// ScannedPort contains all ports found by the scanner. FirstSeenAt is the first scan that found the port open,
+// it stays empty for the addresses of a subnet where nothing ever answered
 type ScannedPort struct {
-	ID        string    `gorm:"primary_key" json:"-"`
-	Site      string    `gorm:"unique_index:scanned_result" json:"site"`
-	CIDR      string    `gorm:"unique_index:scanned_result;column:cidr" json:"cidr"`
-	IP        string    `gorm:"unique_index:scanned_result" json:"ip"`
-	Port      int       `gorm:"unique_index:scanned_result" json:"port"`
-	Protocol  string    `gorm:"unique_index:scanned_result" json:"protocol"`
-	ScannedBy string    `gorm:"unique_index:scanned_result" json:"scanned_by"`
-	State     string    `json:"state"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           string     `gorm:"primary_key" json:"-"`
+	Site         string     `gorm:"unique_index:scanned_result" json:"site"`
+	CIDR         string     `gorm:"unique_index:scanned_result;column:cidr" json:"cidr"`
+	IP           string     `gorm:"unique_index:scanned_result" json:"ip"`
+	Port         int        `gorm:"unique_index:scanned_result" json:"port"`
+	Protocol     string     `gorm:"unique_index:scanned_result" json:"protocol"`
+	ScannedBy    string     `gorm:"unique_index:scanned_result" json:"scanned_by"`
+	State        string     `json:"state"`
+	FirstSeenAt  *time.Time `json:"first_seen_at"`
+	LastOpenAt   *time.Time `json:"last_open_at"`
+	LastClosedAt *time.Time `json:"last_closed_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // GenID generates the ID based on the date we have
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"database/sql/driver"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// Slots is a list of chassis slots, it's an integer array on postgres and its text form, eg: {1,4}, everywhere else
+type Slots []int64
+
+// GormDataType picks the column type for the dialect, mysql doesn't know arrays
+func (Slots) GormDataType(dialect gorm.Dialect) string {
+	if dialect.GetName() == "postgres" {
+		return "integer[]"
+	}
+	return "varchar(255)"
+}
+
+// Value writes the slots in the array literal format postgres expects, the other dialects store it as it is
+func (s Slots) Value() (driver.Value, error) {
+	return pq.Int64Array(s).Value()
+}
+
+// Scan reads the slots back from the array literal
+func (s *Slots) Scan(src interface{}) error {
+	return (*pq.Int64Array)(s).Scan(src)
+}
@@ -33,17 +33,19 @@ func NewStorageBladeFromDevice(b *devices.StorageBlade) (blade *StorageBlade) {
 
 // StorageBlade contains all the storage blade information we will expose across different vendors
 type StorageBlade struct {
-	Serial        string    `json:"serial" gorm:"primary_key"`
-	FwVersion     string    `json:"fw_version"`
-	BladePosition int       `json:"blade_position"`
-	Model         string    `json:"model"`
-	TempC         int       `json:"temp_c"`
-	PowerKw       float64   `json:"power_kw"`
-	Status        string    `json:"status"`
-	Vendor        string    `json:"vendor"`
-	ChassisSerial string    `json:"-"`
-	BladeSerial   string    `json:"-"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	Serial        string     `json:"serial" gorm:"primary_key"`
+	FwVersion     string     `json:"fw_version"`
+	BladePosition int        `json:"blade_position"`
+	Model         string     `json:"model"`
+	TempC         int        `json:"temp_c"`
+	PowerKw       float64    `json:"power_kw"`
+	Status        string     `json:"status"`
+	Vendor        string     `json:"vendor"`
+	ChassisSerial string     `json:"-"`
+	BladeSerial   string     `json:"-"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	DeletedAt     *time.Time `json:"removed_at" gorm:"column:removed_at;index"`
 }
 
 // GetName to satisfy jsonapi naming schema
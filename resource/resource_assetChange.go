@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// AssetChangeResource for api2go routes
+type AssetChangeResource struct {
+	AssetChangeStorage *storage.AssetChangeStorage
+}
+
+// FindAll AssetChanges
+func (a AssetChangeResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, changes, err := a.queryAndCountAllWrapper(r)
+	return &Response{Res: changes}, err
+}
+
+// FindOne AssetChange
+func (a AssetChangeResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := a.AssetChangeStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load AssetChanges in chunks
+func (a AssetChangeResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, changes, err := a.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: changes}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (a AssetChangeResource) queryAndCountAllWrapper(r api2go.Request) (count int, changes []model.AssetChange, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, changes, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, changes, err = a.AssetChangeStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, changes, err
+		}
+	}
+
+	if !hasFilters {
+		count, changes, err = a.AssetChangeStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, changes, err
+		}
+	}
+
+	return count, changes, err
+}
@@ -23,7 +23,12 @@ func (b BladeResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 
 // FindOne Blade
 func (b BladeResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := b.BladeStorage.GetOne(ID)
+	bladeStorage := b.BladeStorage
+	if includeRemoved(r) {
+		bladeStorage = bladeStorage.Unscoped()
+	}
+
+	res, err := bladeStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -45,11 +50,16 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 		}
 	}
 
+	bladeStorage := b.BladeStorage
+	if includeRemoved(r) {
+		bladeStorage = bladeStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	offset, limit := filter.OffSetAndLimitParse(&r)
 
 	if hasFilters {
-		count, blades, err = b.BladeStorage.GetAllByFilters(offset, limit, filters)
+		count, blades, err = bladeStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, blades, err
@@ -59,11 +69,11 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(blades) == 0 {
-			count, blades, err = b.BladeStorage.GetAllWithAssociations(offset, limit, include)
+			count, blades, err = bladeStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var bladesWithInclude []model.Blade
 			for _, bl := range blades {
-				blWithInclude, err := b.BladeStorage.GetOne(bl.Serial)
+				blWithInclude, err := bladeStorage.GetOne(bl.Serial)
 				if err != nil {
 					return count, blades, err
 				}
@@ -75,7 +85,7 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 
 	chassisID, hasChassis := r.QueryParams["chassisID"]
 	if hasChassis {
-		count, blades, err = b.BladeStorage.GetAllByChassisID(offset, limit, chassisID)
+		count, blades, err = bladeStorage.GetAllByChassisID(offset, limit, chassisID)
 		if err != nil {
 			return count, blades, err
 		}
@@ -83,7 +93,7 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 
 	nicsID, hasNIC := r.QueryParams["nicsID"]
 	if hasNIC {
-		count, blades, err = b.BladeStorage.GetAllByNicsID(offset, limit, nicsID)
+		count, blades, err = bladeStorage.GetAllByNicsID(offset, limit, nicsID)
 		if err != nil {
 			return count, blades, err
 		}
@@ -91,7 +101,7 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 
 	storageBladesID, hasStorageBlade := r.QueryParams["storage_bladesID"]
 	if hasStorageBlade {
-		count, blades, err = b.BladeStorage.GetAllByStorageBladesID(offset, limit, storageBladesID)
+		count, blades, err = bladeStorage.GetAllByStorageBladesID(offset, limit, storageBladesID)
 		if err != nil {
 			return count, blades, err
 		}
@@ -99,14 +109,14 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 
 	disksID, hasDisk := r.QueryParams["disksID"]
 	if hasDisk {
-		count, blades, err = b.BladeStorage.GetAllByDisksID(offset, limit, disksID)
+		count, blades, err = bladeStorage.GetAllByDisksID(offset, limit, disksID)
 		if err != nil {
 			return count, blades, err
 		}
 	}
 
 	if !hasFilters && !hasChassis && !hasInclude && !hasNIC && !hasStorageBlade && !hasDisk {
-		count, blades, err = b.BladeStorage.GetAll(offset, limit)
+		count, blades, err = bladeStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, blades, err
 		}
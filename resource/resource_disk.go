@@ -23,7 +23,12 @@ func (d DiskResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 
 // FindOne disks
 func (d DiskResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := d.DiskStorage.GetOne(ID)
+	diskStorage := d.DiskStorage
+	if includeRemoved(r) {
+		diskStorage = diskStorage.Unscoped()
+	}
+
+	res, err := diskStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -46,9 +51,14 @@ func (d DiskResource) queryAndCountAllWrapper(r api2go.Request) (count int, disk
 	}
 
 	offset, limit := filter.OffSetAndLimitParse(&r)
+	diskStorage := d.DiskStorage
+	if includeRemoved(r) {
+		diskStorage = diskStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	if hasFilters {
-		count, disks, err = d.DiskStorage.GetAllByFilters(offset, limit, filters)
+		count, disks, err = diskStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, disks, err
@@ -58,11 +68,11 @@ func (d DiskResource) queryAndCountAllWrapper(r api2go.Request) (count int, disk
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(disks) == 0 {
-			count, disks, err = d.DiskStorage.GetAllWithAssociations(offset, limit, include)
+			count, disks, err = diskStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var disksWithInclude []model.Disk
 			for _, ds := range disks {
-				diskWithInclude, err := d.DiskStorage.GetOne(ds.Serial)
+				diskWithInclude, err := diskStorage.GetOne(ds.Serial)
 				if err != nil {
 					return count, disks, err
 				}
@@ -74,18 +84,18 @@ func (d DiskResource) queryAndCountAllWrapper(r api2go.Request) (count int, disk
 
 	bladeID, hasBlade := r.QueryParams["bladesID"]
 	if hasBlade {
-		count, disks, err = d.DiskStorage.GetAllByBladeID(offset, limit, bladeID)
+		count, disks, err = diskStorage.GetAllByBladeID(offset, limit, bladeID)
 		return count, disks, err
 	}
 
 	discreteID, hasDiscrete := r.QueryParams["discretesID"]
 	if hasDiscrete {
-		count, disks, err = d.DiskStorage.GetAllByDiscreteID(offset, limit, discreteID)
+		count, disks, err = diskStorage.GetAllByDiscreteID(offset, limit, discreteID)
 		return count, disks, err
 	}
 
 	if !hasFilters && !hasBlade && !hasDiscrete {
-		count, disks, err = d.DiskStorage.GetAll(offset, limit)
+		count, disks, err = diskStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, disks, err
 		}
@@ -23,7 +23,12 @@ func (f FanResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 
 // FindOne Fan
 func (f FanResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := f.FanStorage.GetOne(ID)
+	fanStorage := f.FanStorage
+	if includeRemoved(r) {
+		fanStorage = fanStorage.Unscoped()
+	}
+
+	res, err := fanStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -46,9 +51,14 @@ func (f FanResource) queryAndCountAllWrapper(r api2go.Request) (count int, fans
 	}
 
 	offset, limit := filter.OffSetAndLimitParse(&r)
+	fanStorage := f.FanStorage
+	if includeRemoved(r) {
+		fanStorage = fanStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	if hasFilters {
-		count, fans, err = f.FanStorage.GetAllByFilters(offset, limit, filters)
+		count, fans, err = fanStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, fans, err
@@ -58,11 +68,11 @@ func (f FanResource) queryAndCountAllWrapper(r api2go.Request) (count int, fans
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(fans) == 0 {
-			count, fans, err = f.FanStorage.GetAllWithAssociations(offset, limit, include)
+			count, fans, err = fanStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var fansWithInclude []model.Fan
 			for _, fn := range fans {
-				fanWithInclude, err := f.FanStorage.GetOne(fn.Serial)
+				fanWithInclude, err := fanStorage.GetOne(fn.Serial)
 				if err != nil {
 					return count, fans, err
 				}
@@ -74,12 +84,12 @@ func (f FanResource) queryAndCountAllWrapper(r api2go.Request) (count int, fans
 
 	chassisID, hasChassis := r.QueryParams["chassisID"]
 	if hasChassis {
-		count, fans, err = f.FanStorage.GetAllByChassisID(offset, limit, chassisID)
+		count, fans, err = fanStorage.GetAllByChassisID(offset, limit, chassisID)
 		return count, fans, err
 	}
 
 	if !hasFilters && !hasChassis {
-		count, fans, err = f.FanStorage.GetAll(offset, limit)
+		count, fans, err = fanStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, fans, err
 		}
@@ -23,7 +23,12 @@ func (n NicResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 
 // FindOne Nics
 func (n NicResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := n.NicStorage.GetOne(ID)
+	nicStorage := n.NicStorage
+	if includeRemoved(r) {
+		nicStorage = nicStorage.Unscoped()
+	}
+
+	res, err := nicStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -45,11 +50,16 @@ func (n NicResource) queryAndCountAllWrapper(r api2go.Request) (count int, nics
 		}
 	}
 
+	nicStorage := n.NicStorage
+	if includeRemoved(r) {
+		nicStorage = nicStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	offset, limit := filter.OffSetAndLimitParse(&r)
 
 	if hasFilters {
-		count, nics, err = n.NicStorage.GetAllByFilters(offset, limit, filters)
+		count, nics, err = nicStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, nics, err
@@ -59,11 +69,11 @@ func (n NicResource) queryAndCountAllWrapper(r api2go.Request) (count int, nics
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(nics) == 0 {
-			count, nics, err = n.NicStorage.GetAllWithAssociations(offset, limit, include)
+			count, nics, err = nicStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var nicsWithInclude []model.Nic
 			for _, nc := range nics {
-				ncWithInclude, err := n.NicStorage.GetOne(nc.MacAddress)
+				ncWithInclude, err := nicStorage.GetOne(nc.MacAddress)
 				if err != nil {
 					return count, nics, err
 				}
@@ -75,24 +85,24 @@ func (n NicResource) queryAndCountAllWrapper(r api2go.Request) (count int, nics
 
 	bladeID, hasBlade := r.QueryParams["bladesID"]
 	if hasBlade {
-		count, nics, err = n.NicStorage.GetAllByBladeID(offset, limit, bladeID)
+		count, nics, err = nicStorage.GetAllByBladeID(offset, limit, bladeID)
 		return count, nics, err
 	}
 
 	chassisID, hasChassis := r.QueryParams["chassisID"]
 	if hasChassis {
-		count, nics, err = n.NicStorage.GetAllByChassisID(offset, limit, chassisID)
+		count, nics, err = nicStorage.GetAllByChassisID(offset, limit, chassisID)
 		return count, nics, err
 	}
 
 	discreteID, hasDiscrete := r.QueryParams["discretesID"]
 	if hasDiscrete {
-		count, nics, err = n.NicStorage.GetAllByDiscreteID(offset, limit, discreteID)
+		count, nics, err = nicStorage.GetAllByDiscreteID(offset, limit, discreteID)
 		return count, nics, err
 	}
 
 	if !hasFilters && !hasBlade && !hasChassis && !hasDiscrete {
-		count, nics, err = n.NicStorage.GetAll(offset, limit)
+		count, nics, err = nicStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, nics, err
 		}
@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// PortStateChangeResource for api2go routes
+type PortStateChangeResource struct {
+	PortStateChangeStorage *storage.PortStateChangeStorage
+}
+
+// FindAll PortStateChanges
+func (p PortStateChangeResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, changes, err := p.queryAndCountAllWrapper(r)
+	return &Response{Res: changes}, err
+}
+
+// FindOne PortStateChange
+func (p PortStateChangeResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := p.PortStateChangeStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load PortStateChanges in chunks
+func (p PortStateChangeResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, changes, err := p.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: changes}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (p PortStateChangeResource) queryAndCountAllWrapper(r api2go.Request) (count int, changes []model.PortStateChange, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, changes, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, changes, err = p.PortStateChangeStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, changes, err
+		}
+	}
+
+	if !hasFilters {
+		count, changes, err = p.PortStateChangeStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, changes, err
+		}
+	}
+
+	return count, changes, err
+}
@@ -23,7 +23,12 @@ func (p PsuResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 
 // FindOne Psu
 func (p PsuResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := p.PsuStorage.GetOne(ID)
+	psuStorage := p.PsuStorage
+	if includeRemoved(r) {
+		psuStorage = psuStorage.Unscoped()
+	}
+
+	res, err := psuStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -46,9 +51,14 @@ func (p PsuResource) queryAndCountAllWrapper(r api2go.Request) (count int, psus
 	}
 
 	offset, limit := filter.OffSetAndLimitParse(&r)
+	psuStorage := p.PsuStorage
+	if includeRemoved(r) {
+		psuStorage = psuStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	if hasFilters {
-		count, psus, err = p.PsuStorage.GetAllByFilters(offset, limit, filters)
+		count, psus, err = psuStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, psus, err
@@ -58,11 +68,11 @@ func (p PsuResource) queryAndCountAllWrapper(r api2go.Request) (count int, psus
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(psus) == 0 {
-			count, psus, err = p.PsuStorage.GetAllWithAssociations(offset, limit, include)
+			count, psus, err = psuStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var psusWithInclude []model.Psu
 			for _, ps := range psus {
-				psWithInclude, err := p.PsuStorage.GetOne(ps.Serial)
+				psWithInclude, err := psuStorage.GetOne(ps.Serial)
 				if err != nil {
 					return count, psus, err
 				}
@@ -74,18 +84,18 @@ func (p PsuResource) queryAndCountAllWrapper(r api2go.Request) (count int, psus
 
 	chassisID, hasChassis := r.QueryParams["chassisID"]
 	if hasChassis {
-		count, psus, err = p.PsuStorage.GetAllByChassisID(offset, limit, chassisID)
+		count, psus, err = psuStorage.GetAllByChassisID(offset, limit, chassisID)
 		return count, psus, err
 	}
 
 	discreteID, hasDiscrete := r.QueryParams["discretesID"]
 	if hasDiscrete {
-		count, psus, err = p.PsuStorage.GetAllByDiscreteID(offset, limit, discreteID)
+		count, psus, err = psuStorage.GetAllByDiscreteID(offset, limit, discreteID)
 		return count, psus, err
 	}
 
 	if !hasFilters && !hasChassis && !hasDiscrete {
-		count, psus, err = p.PsuStorage.GetAll(offset, limit)
+		count, psus, err = psuStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, psus, err
 		}
@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// ReadingResource for api2go routes
+type ReadingResource struct {
+	ReadingStorage *storage.ReadingStorage
+}
+
+// FindAll Readings
+func (re ReadingResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, readings, err := re.queryAndCountAllWrapper(r)
+	return &Response{Res: readings}, err
+}
+
+// FindOne Reading
+func (re ReadingResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := re.ReadingStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load Readings in chunks
+func (re ReadingResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, readings, err := re.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: readings}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (re ReadingResource) queryAndCountAllWrapper(r api2go.Request) (count int, readings []model.Reading, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, readings, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, readings, err = re.ReadingStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, readings, err
+		}
+	}
+
+	if !hasFilters {
+		count, readings, err = re.ReadingStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, readings, err
+		}
+	}
+
+	return count, readings, err
+}
@@ -23,7 +23,12 @@ func (s StorageBladeResource) FindAll(r api2go.Request) (api2go.Responder, error
 
 // FindOne StorageBlade
 func (s StorageBladeResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
-	res, err := s.StorageBladeStorage.GetOne(ID)
+	storageBladeStorage := s.StorageBladeStorage
+	if includeRemoved(r) {
+		storageBladeStorage = storageBladeStorage.Unscoped()
+	}
+
+	res, err := storageBladeStorage.GetOne(ID)
 	if err == gorm.ErrRecordNotFound {
 		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
 	}
@@ -45,11 +50,16 @@ func (s StorageBladeResource) queryAndCountAllWrapper(r api2go.Request) (count i
 		}
 	}
 
+	storageBladeStorage := s.StorageBladeStorage
+	if includeRemoved(r) {
+		storageBladeStorage = storageBladeStorage.Unscoped()
+	}
+
 	filters, hasFilters := filter.NewFilterSet(&r)
 	offset, limit := filter.OffSetAndLimitParse(&r)
 
 	if hasFilters {
-		count, storageblades, err = s.StorageBladeStorage.GetAllByFilters(offset, limit, filters)
+		count, storageblades, err = storageBladeStorage.GetAllByFilters(offset, limit, filters)
 		filters.Clean()
 		if err != nil {
 			return count, storageblades, err
@@ -59,11 +69,11 @@ func (s StorageBladeResource) queryAndCountAllWrapper(r api2go.Request) (count i
 	include, hasInclude := r.QueryParams["include"]
 	if hasInclude {
 		if len(storageblades) == 0 {
-			count, storageblades, err = s.StorageBladeStorage.GetAllWithAssociations(offset, limit, include)
+			count, storageblades, err = storageBladeStorage.GetAllWithAssociations(offset, limit, include)
 		} else {
 			var bladesWithInclude []model.StorageBlade
 			for _, bl := range storageblades {
-				blWithInclude, err := s.StorageBladeStorage.GetOne(bl.Serial)
+				blWithInclude, err := storageBladeStorage.GetOne(bl.Serial)
 				if err != nil {
 					return count, storageblades, err
 				}
@@ -75,7 +85,7 @@ func (s StorageBladeResource) queryAndCountAllWrapper(r api2go.Request) (count i
 
 	chassisID, hasChassis := r.QueryParams["chassisID"]
 	if hasChassis {
-		count, storageblades, err = s.StorageBladeStorage.GetAllByChassisID(offset, limit, chassisID)
+		count, storageblades, err = storageBladeStorage.GetAllByChassisID(offset, limit, chassisID)
 		if err != nil {
 			return count, storageblades, err
 		}
@@ -83,14 +93,14 @@ func (s StorageBladeResource) queryAndCountAllWrapper(r api2go.Request) (count i
 
 	bladesID, hasBlade := r.QueryParams["bladesID"]
 	if hasBlade {
-		count, storageblades, err = s.StorageBladeStorage.GetAllByBladeID(offset, limit, bladesID)
+		count, storageblades, err = storageBladeStorage.GetAllByBladeID(offset, limit, bladesID)
 		if err != nil {
 			return count, storageblades, err
 		}
 	}
 
 	if !hasFilters && !hasChassis && !hasBlade {
-		count, storageblades, err = s.StorageBladeStorage.GetAll(offset, limit)
+		count, storageblades, err = storageBladeStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, storageblades, err
 		}
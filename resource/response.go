@@ -1,6 +1,10 @@
 package resource
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/manyminds/api2go"
+)
 
 // The Response struct implements api2go.Responder
 type Response struct {
@@ -32,3 +36,9 @@ func (r Response) Result() interface{} {
 func (r Response) StatusCode() int {
 	return r.Code
 }
+
+// includeRemoved tells whether the request opted in to see the components marked as removed, eg: ?removed=true
+func includeRemoved(r api2go.Request) bool {
+	removed, ok := r.QueryParams["removed"]
+	return ok && len(removed) > 0 && removed[0] == "true"
+}
@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/nats-io/go-nats"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// batch buffers the scan results of a subnet and writes them by scanner.batch_size rows, the results of a host
+// always end up in the same batch
+type batch struct {
+	subnet             *ToScan
+	scannedPortStorage *storage.ScannedPortStorage
+	nc                 *nats.Conn
+	size               int
+
+	mu      sync.Mutex
+	pending []storage.ScanResult
+}
+
+// newBatch returns the batch of the subnet, nc is used to collect the new bmcs and can be nil
+func newBatch(subnet *ToScan, scannedPortStorage *storage.ScannedPortStorage, nc *nats.Conn) *batch {
+	size := viper.GetInt("scanner.batch_size")
+	if size < 1 {
+		size = 1
+	}
+	return &batch{subnet: subnet, scannedPortStorage: scannedPortStorage, nc: nc, size: size}
+}
+
+// add buffers the results of a host and writes the buffer once it's full
+func (b *batch) add(results []storage.ScanResult) {
+	b.mu.Lock()
+	b.pending = append(b.pending, results...)
+	var full []storage.ScanResult
+	if len(b.pending) >= b.size {
+		full, b.pending = b.pending, nil
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.write(full)
+	}
+}
+
+// flush writes what's left in the buffer
+func (b *batch) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(pending) > 0 {
+		b.write(pending)
+	}
+}
+
+// write stores the results in one go and reports the new open ports
+func (b *batch) write(results []storage.ScanResult) {
+	start := time.Now()
+	changes, err := b.scannedPortStorage.RecordBatch(results)
+	if viper.GetBool("metrics.enabled") {
+		metrics.UpdateTimer([]string{"scan.batch_latency"}, time.Since(start))
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "storing scan", "subnet": b.subnet.CIDR, "rows": len(results)}).Error(err)
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{"scan.batch_failed"}, 1)
+			metrics.IncrCounter([]string{"scan.rows_failed"}, int64(len(results)))
+		}
+		return
+	}
+
+	for _, change := range changes {
+		log.WithFields(log.Fields{"operation": "storing scan", "subnet": b.subnet.CIDR, "host": change.IP, "port": change.Port, "previous": change.PreviousState}).Debugf("port is now %s", change.State)
+	}
+
+	found := make(map[string]bool)
+	var newHosts []string
+	for _, result := range results {
+		sp := result.Port
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{fmt.Sprintf("scan.%v_%v.scanned_successfully", sp.Protocol, sp.Port)}, 1)
+		}
+		if sp.State != model.PortOpen || sp.FirstSeenAt == nil || !sp.FirstSeenAt.Equal(result.ScannedAt) {
+			continue
+		}
+
+		log.WithFields(log.Fields{"operation": "storing scan", "subnet": b.subnet.CIDR, "host": sp.IP, "port": sp.Port}).Info("new port found")
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{"scan.new_ports"}, 1)
+		}
+		if !found[sp.IP] {
+			found[sp.IP] = true
+			newHosts = append(newHosts, sp.IP)
+		}
+	}
+	if viper.GetBool("metrics.enabled") {
+		metrics.IncrCounter([]string{"scan.rows_written"}, int64(len(results)))
+	}
+
+	if b.nc != nil {
+		for _, ip := range newHosts {
+			collectNew(b.nc, ip)
+		}
+	}
+}
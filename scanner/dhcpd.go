@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"github.com/bmc-toolbox/dora/internal/dhcpd"
+	"github.com/spf13/viper"
+)
+
+// dhcpdSource reads the subnets of an isc dhcpd.conf
+type dhcpdSource struct {
+	path string
+}
+
+// Subnets returns the subnets to scan of the dhcpd.conf
+func (s *dhcpdSource) Subnets() ([]*ToScan, error) {
+	dhcpdSubnets, err := dhcpd.Read(s.path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSubnetsFromDhcpd(dhcpdSubnets)
+}
+
+// LoadSubnetsFromDhcpd returns the subnets to scan out of the subnets of a dhcpd.conf, the first rule of
+// scanner.dhcpd_rules selecting a subnet gives its site. dhcpd has no user context, so the rules only look at
+// the options. The subnets get the profile named by their scanner.dhcpd_profile_option option, the ipv6 ones have
+// no candidate hosts of their own
+func LoadSubnetsFromDhcpd(dhcpdSubnets []*dhcpd.Subnet) (subnets []*ToScan, err error) {
+	rules, err := subnetRules("scanner.dhcpd_rules", false)
+	if err != nil {
+		return nil, err
+	}
+
+	dhcpdProfileOption := viper.GetString("scanner.dhcpd_profile_option")
+	for _, subnet := range dhcpdSubnets {
+		site, ok := rules.site(subnet.Options, nil)
+		if !ok {
+			continue
+		}
+
+		toScan := &ToScan{CIDR: subnet.Network.String(), Site: site}
+		if dhcpdProfileOption != "" {
+			toScan.Profile = subnet.Options[dhcpdProfileOption]
+		}
+		subnets = append(subnets, toScan)
+	}
+
+	return subnets, nil
+}
@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// hostIterator gives the addresses to probe one at a time
+type hostIterator interface {
+	// Next returns the next address, ok is false once they were all given
+	Next() (ip string, ok bool)
+}
+
+// addressIterator walks the addresses of an ipv4 network without holding them. The network and broadcast
+// addresses are skipped, except in /31 and /32 where every address is a host
+type addressIterator struct {
+	next net.IP
+	last net.IP
+	done bool
+}
+
+// newAddressIterator returns an iterator on the host addresses of the ipv4 network
+func newAddressIterator(network *net.IPNet) *addressIterator {
+	first := network.IP.To4().Mask(network.Mask)
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^network.Mask[i]
+	}
+
+	if ones, bits := network.Mask.Size(); bits-ones > 1 {
+		first, last = add(first, 1), add(last, -1)
+	}
+	return &addressIterator{next: first, last: last}
+}
+
+// Next returns the next address of the network
+func (it *addressIterator) Next() (ip string, ok bool) {
+	if it.done {
+		return ip, false
+	}
+
+	ip = it.next.String()
+	if it.next.Equal(it.last) {
+		it.done = true
+	} else {
+		it.next = add(it.next, 1)
+	}
+	return ip, true
+}
+
+// add returns a copy of the ipv4 address moved by one up or down
+func add(ip net.IP, step int) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for j := len(next) - 1; j >= 0; j-- {
+		next[j] += byte(step)
+		if (step > 0 && next[j] != 0) || (step < 0 && next[j] != 0xff) {
+			break
+		}
+	}
+	return next
+}
+
+// listIterator gives the addresses of a list
+type listIterator struct {
+	hosts []string
+}
+
+// Next returns the next address of the list
+func (it *listIterator) Next() (ip string, ok bool) {
+	if len(it.hosts) == 0 {
+		return ip, false
+	}
+	ip, it.hosts = it.hosts[0], it.hosts[1:]
+	return ip, true
+}
+
+// hosts returns the addresses to probe: the candidate hosts of the subnet that are in it, or every address of an
+// ipv4 subnet without candidates. Scanning a /64 address by address is never going to end, so the ipv6 subnets
+// are only scanned on their candidates
+func (t *ToScan) hosts() (hostIterator, error) {
+	_, network, err := net.ParseCIDR(t.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.Hosts) == 0 {
+		if network.IP.To4() == nil {
+			return nil, fmt.Errorf("ipv6 subnet without candidate hosts")
+		}
+		return newAddressIterator(network), nil
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, host := range t.Hosts {
+		ip := net.ParseIP(host)
+		if ip == nil || !network.Contains(ip) {
+			return nil, fmt.Errorf("candidate host %q isn't an address of the subnet", host)
+		}
+		if !seen[ip.String()] {
+			seen[ip.String()] = true
+			hosts = append(hosts, ip.String())
+		}
+	}
+	return &listIterator{hosts: hosts}, nil
+}
+
+// addHosts gives the ipv6 subnets the candidate hosts they contain and don't have yet
+func addHosts(subnets []*ToScan, hosts []net.IP) {
+	for _, subnet := range subnets {
+		_, network, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil || network.IP.To4() != nil {
+			continue
+		}
+
+		seen := make(map[string]bool, len(subnet.Hosts))
+		for _, host := range subnet.Hosts {
+			seen[host] = true
+		}
+		for _, ip := range hosts {
+			if network.Contains(ip) && !seen[ip.String()] {
+				seen[ip.String()] = true
+				subnet.Hosts = append(subnet.Hosts, ip.String())
+			}
+		}
+	}
+}
+
+// readHostFiles reads the candidate hosts of scanner.ipv6_host_files
+func readHostFiles() (hosts []net.IP, err error) {
+	for _, path := range viper.GetStringSlice("scanner.ipv6_host_files") {
+		list, err := readHostFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, list...)
+	}
+	return hosts, nil
+}
+
+// readHostFile reads a list of addresses, one per line, or the neighbors of ip -6 neigh show. The first field of
+// a line is the address, the neighbors that failed or are still incomplete are skipped
+func readHostFile(path string) (hosts []net.IP, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for line := 1; lines.Scan(); line++ {
+		text := lines.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		state := fields[len(fields)-1]
+		if state == "FAILED" || state == "INCOMPLETE" {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			return nil, fmt.Errorf("%s line %d: invalid address %q", path, line, fields[0])
+		}
+		hosts = append(hosts, ip)
+	}
+
+	if err = lines.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return hosts, nil
+}
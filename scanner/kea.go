@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/bmc-toolbox/dora/internal/kea"
+	"github.com/spf13/viper"
+)
+
+// keaSource reads the subnets of a kea config
+type keaSource struct {
+	path string
+}
+
+// Subnets returns the subnets to scan of the kea config
+func (s *keaSource) Subnets() ([]*ToScan, error) {
+	keaSubnets, err := kea.Read(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets, err := LoadSubnetsFromKea(keaSubnets)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ipv6 subnets are scanned on the addresses kea leased too
+	if leases := viper.GetString("scanner.kea_leases6"); leases != "" {
+		leased, err := kea.ReadLeases(leases, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		addHosts(subnets, leased)
+	}
+	return subnets, nil
+}
+
+// LoadSubnetsFromKea returns the subnets to scan out of the subnets of a kea config, the first rule of
+// scanner.kea_rules selecting a subnet gives its site. The subnets get the profile named by their
+// scanner.kea_profile_option option, the ipv6 ones their reservations as candidate hosts
+func LoadSubnetsFromKea(keaSubnets []*kea.Subnet) (subnets []*ToScan, err error) {
+	rules, err := subnetRules("scanner.kea_rules", true)
+	if err != nil {
+		return nil, err
+	}
+
+	keaProfileOption := viper.GetString("scanner.kea_profile_option")
+	for _, subnet := range keaSubnets {
+		site, ok := rules.site(subnet.Options, subnet.UserContext)
+		if !ok {
+			continue
+		}
+
+		toScan := &ToScan{CIDR: subnet.Network.String(), Site: site}
+		if keaProfileOption != "" {
+			toScan.Profile = subnet.Options[keaProfileOption]
+		}
+		if subnet.IPv6() {
+			for _, ip := range subnet.Reservations {
+				toScan.Hosts = append(toScan.Hosts, ip.String())
+			}
+		}
+		subnets = append(subnets, toScan)
+	}
+
+	return subnets, nil
+}
+
+// ReadKeaConfig reads scanner.kea_config and returns the subnets to scan
+func ReadKeaConfig() (subnets []*ToScan, err error) {
+	return (&keaSource{path: viper.GetString("scanner.kea_config")}).Subnets()
+}
@@ -2,8 +2,8 @@ package scanner
 
 import (
 	"errors"
-	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -36,9 +36,9 @@ func (r Result) String() string {
 
 // probeTCP determines whether the indicated TCP port on the target host is
 // open.
-func probeTCP(node string, port int) Result {
-	address := fmt.Sprintf("%s:%d", node, port)
-	conn, err := net.DialTimeout("tcp4", address, 1*time.Second)
+func probeTCP(node string, port int, timeout time.Duration) Result {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
 		log.WithFields(log.Fields{"dial": "tcp", "address": address}).Debug(err)
 		return closed
@@ -49,9 +49,9 @@ func probeTCP(node string, port int) Result {
 
 // probeTCP determines whether the indicated IPMI port on the target host is
 // open.
-func probeIPMI(node string, port int) Result {
-	address := fmt.Sprintf("%s:%d", node, port)
-	conn, err := net.DialTimeout("udp4", address, 1*time.Second)
+func probeIPMI(node string, port int, timeout time.Duration) Result {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", address, timeout)
 	if err != nil {
 		log.WithFields(log.Fields{"dial": "udp", "address": address}).Debug(err)
 		return closed
@@ -66,7 +66,7 @@ func probeIPMI(node string, port int) Result {
 		return closed
 	}
 
-	err = conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
 	if err != nil {
 		log.WithFields(log.Fields{"set read timeout": "udp", "address": address}).Debug(err)
 		return closed
@@ -90,13 +90,13 @@ func probeIPMI(node string, port int) Result {
 }
 
 // Probe determines whether the specified port on the on the specified host is
-// potentially accepting input via the specified network protocol.
-func Probe(protocol, host string, port int) (r Result, err error) {
+// potentially accepting input via the specified network protocol within the timeout.
+func Probe(protocol, host string, port int, timeout time.Duration) (r Result, err error) {
 	switch protocol {
 	case "tcp":
-		return probeTCP(host, port), err
+		return probeTCP(host, port, timeout), err
 	case "ipmi":
-		return probeIPMI(host, port), err
+		return probeIPMI(host, port, timeout), err
 	default:
 		return r, ErrUnsupportedProtocol
 	}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Services of the probed ports, they set the reachability flags of the assets and the collectors go through
+// the web and ipmi ones
+const (
+	ServiceSSH  = "ssh"
+	ServiceWeb  = "web"
+	ServiceIpmi = "ipmi"
+)
+
+// DefaultProfile is used by the subnets without a profile of their own
+const DefaultProfile = "default"
+
+// ProfilePort is a port probed by a scan profile, the timeout is in seconds
+type ProfilePort struct {
+	Protocol string `json:"protocol" mapstructure:"protocol"`
+	Port     int    `json:"port" mapstructure:"port"`
+	Timeout  int    `json:"timeout" mapstructure:"timeout"`
+	Service  string `json:"service" mapstructure:"service"`
+}
+
+// Profiles are the ports to probe by profile name
+type Profiles map[string][]ProfilePort
+
+// defaultProfiles are used when scanner.profiles isn't configured, they are the ports we always scanned
+var defaultProfiles = Profiles{
+	DefaultProfile: {
+		{Protocol: "tcp", Port: 22, Timeout: 1, Service: ServiceSSH},
+		{Protocol: "tcp", Port: 443, Timeout: 1, Service: ServiceWeb},
+		{Protocol: "ipmi", Port: 623, Timeout: 1, Service: ServiceIpmi},
+	},
+}
+
+// LoadProfiles reads and validates scanner.profiles. Viper lowercases the keys, so do the profile names
+func LoadProfiles() (profiles Profiles, err error) {
+	if !viper.IsSet("scanner.profiles") {
+		return defaultProfiles, nil
+	}
+
+	if err = viper.UnmarshalKey("scanner.profiles", &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, profiles.validate()
+}
+
+// validate checks the ports of every profile and sets the default timeout
+func (p Profiles) validate() error {
+	if _, ok := p[DefaultProfile]; !ok {
+		return fmt.Errorf("scan profile %s is missing", DefaultProfile)
+	}
+
+	services := make(map[string]string)
+	for name, ports := range p {
+		if len(ports) == 0 {
+			return fmt.Errorf("scan profile %s has no ports", name)
+		}
+
+		for i := range ports {
+			port := &ports[i]
+			if port.Protocol != "tcp" && port.Protocol != "ipmi" {
+				return fmt.Errorf("scan profile %s: %s %s", name, ErrUnsupportedProtocol, port.Protocol)
+			}
+			if port.Port < 1 || port.Port > 65535 {
+				return fmt.Errorf("scan profile %s: invalid port %d", name, port.Port)
+			}
+			if port.Timeout <= 0 {
+				port.Timeout = 1
+			}
+			if port.Service != "" && port.Service != ServiceSSH && port.Service != ServiceWeb && port.Service != ServiceIpmi {
+				return fmt.Errorf("scan profile %s: unknown service %s", name, port.Service)
+			}
+
+			// the scanned ports don't remember their profile, the service is found by protocol and port
+			key := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+			if service, ok := services[key]; ok && service != port.Service {
+				return fmt.Errorf("scan profile %s: %s is already the %q service in another profile", name, key, service)
+			}
+			services[key] = port.Service
+		}
+	}
+
+	if len(p.Ports(ServiceWeb, ServiceIpmi)) == 0 {
+		return fmt.Errorf("none of the scan profiles has a %s or %s port to collect through", ServiceWeb, ServiceIpmi)
+	}
+	return nil
+}
+
+// Of returns the profile of the subnet: the one it was given, the one of its site in scanner.site_profiles or
+// the default one
+func (p Profiles) Of(subnet *ToScan) (name string, ports []ProfilePort) {
+	name = subnet.Profile
+	if name == "" {
+		name = viper.GetStringMapString("scanner.site_profiles")[strings.ToLower(subnet.Site)]
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	name = strings.ToLower(name)
+	return name, p[name]
+}
+
+// Service returns the service of the protocol and port, empty when no profile gives it one
+func (p Profiles) Service(protocol string, port int) string {
+	for _, ports := range p {
+		for _, profilePort := range ports {
+			if profilePort.Protocol == protocol && profilePort.Port == port {
+				return profilePort.Service
+			}
+		}
+	}
+	return ""
+}
+
+// Ports returns the ports of all profiles giving any of the services, each protocol and port once
+func (p Profiles) Ports(services ...string) (ports []ProfilePort) {
+	seen := make(map[string]bool)
+	for _, name := range p.names() {
+		for _, port := range p[name] {
+			key := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+			if seen[key] {
+				continue
+			}
+
+			for _, service := range services {
+				if port.Service == service {
+					seen[key] = true
+					ports = append(ports, port)
+					break
+				}
+			}
+		}
+	}
+	return ports
+}
+
+// names returns the names of the profiles sorted, so the same profiles always give the same ports
+func (p Profiles) names() (names []string) {
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
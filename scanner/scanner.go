@@ -2,13 +2,8 @@ package scanner
 
 import (
 	"encoding/json"
-	"fmt"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
-	"io/ioutil"
-	"net"
-	"os"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/bmc-toolbox/dora/storage"
@@ -18,225 +13,148 @@ import (
 	"github.com/spf13/viper"
 )
 
-// Kea is the main entry for parsing the kea config file
-type Kea struct {
-	Dhcp4 *Dhcp4 `json:"Dhcp4"`
-}
-
-// Dhcp4 contains the dhcp information for ipv4 networks
-type Dhcp4 struct {
-	Subnet4 []*Subnet4 `json:"subnet4"`
-}
-
-// Subnet4 contains all the subnets managed by Kea
-type Subnet4 struct {
-	OptionData []*OptionData `json:"option-data"`
-	Subnet     string        `json:"subnet"`
-}
-
-// OptionData contains the options send to the clients during the dhcp request
-type OptionData struct {
-	Data string `json:"data"`
-	Name string `json:"name"`
-}
-
-// ToScan payload message to scan a network
+// ToScan payload message to scan a network, without a profile it's scanned with the one of its site. Hosts are
+// the candidates to probe instead of every address, the ipv6 networks are only scanned on them
 type ToScan struct {
-	CIDR string `json:"cidr"`
-	Site string `json:"site"`
-}
-
-type scanOption struct {
-	Protocol string
-	Port     int
-}
-
-var scanProfiles = []scanOption{
-	{
-		Protocol: "tcp",
-		Port:     22,
-	},
-	{
-		Protocol: "tcp",
-		Port:     443,
-	},
-	{
-		Protocol: "ipmi",
-		Port:     623,
-	},
-}
-
-// LoadSubnetsFromKea from kea.cfg
-func LoadSubnetsFromKea(content []byte) (subnets []*ToScan) {
-	keaData := &Kea{}
-	err := json.Unmarshal(content, &keaData)
-	if err != nil {
-		panic(err)
-	}
-
-	keaDomainNameSuffix := viper.GetString("scanner.kea_domain_name_suffix")
-	for _, subnet := range keaData.Dhcp4.Subnet4 {
-		for _, option := range subnet.OptionData {
-			if option.Name == "domain-name" && strings.HasSuffix(option.Data, keaDomainNameSuffix) {
-				if strings.HasSuffix(option.Data, keaDomainNameSuffix) {
-					_, ipv4Net, err := net.ParseCIDR(subnet.Subnet)
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "subnet parsing"}).Warn(err)
-						continue
-					}
-					toScan := &ToScan{
-						CIDR: ipv4Net.String(),
-						Site: strings.TrimSuffix(option.Data, keaDomainNameSuffix),
-					}
-					subnets = append(subnets, toScan)
-				}
-			}
-		}
-	}
-
-	return subnets
+	CIDR    string   `json:"cidr"`
+	Site    string   `json:"site"`
+	Profile string   `json:"profile,omitempty"`
+	Hosts   []string `json:"hosts,omitempty"`
 }
 
-func nexIP(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
-		}
+// collectNew asks the collectors to collect a bmc found for the first time
+func collectNew(nc *nats.Conn, ip string) {
+	subject := "dora::collect"
+	nc.Publish(subject, []byte(ip))
+	nc.Flush()
+	if err := nc.LastError(); err != nil {
+		log.WithFields(log.Fields{"operation": "collecting new port", "subject": subject, "payload": ip}).Error(err)
+		return
 	}
+	log.WithFields(log.Fields{"operation": "collecting new port", "subject": subject, "payload": ip}).Info("sent")
 }
 
-func ipsWithinASubnet(cidr string) ([]string, error) {
-	ip, ipnet, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return nil, err
-	}
-
-	var ips []string
-	for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); nexIP(ip) {
-		ips = append(ips, ip.String())
+// scan probes every address of the subnets it receives with the ports of their profile, the hosts of a subnet
+// are probed by scanner.host_concurrency workers and their results are written by batches, flushed once the
+// subnet is done. nc is used to collect the new bmcs and can be nil
+func scan(input <-chan *ToScan, db *gorm.DB, nc *nats.Conn, profiles Profiles) {
+	hostConcurrency := viper.GetInt("scanner.host_concurrency")
+	if hostConcurrency < 1 {
+		hostConcurrency = 1
 	}
-	// remove network address and broadcast address
-	return ips[1 : len(ips)-1], nil
-}
 
-func scan(input <-chan *ToScan, db *gorm.DB) {
-	ScannedBy := viper.GetString("scanner.scanned_by")
+	scannedBy := viper.GetString("scanner.scanned_by")
+	scannedPortStorage := storage.NewScannedPortStorage(db)
 	for subnet := range input {
+		profile, ports := profiles.Of(subnet)
+		if ports == nil {
+			log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR, "profile": profile}).Warn("unknown scan profile, using the default one")
+			profile, ports = DefaultProfile, profiles[DefaultProfile]
+		}
 
-		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Info("network scan started")
-
-		ips, err := ipsWithinASubnet(subnet.CIDR)
+		hosts, err := subnet.hosts()
 		if err != nil {
 			log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Error(err)
 			continue
 		}
 
-		for _, s := range scanProfiles {
-			for _, ip := range ips {
-				graphiteKey := fmt.Sprintf("scan.%v_%v.scanned_successfully", s.Protocol, s.Port)
-				probeStatus, err := Probe(s.Protocol, ip, s.Port)
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "scanning host", "subnet": subnet.CIDR, "host": ip, "port": s.Port}).Error(err)
-					// failed scan for particular service is not a problem, we don't want separate metric on that
+		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR, "profile": profile}).Info("network scan started")
+
+		results := newBatch(subnet, scannedPortStorage, nc)
+		cc := make(chan string, hostConcurrency)
+		wg := sync.WaitGroup{}
+		wg.Add(hostConcurrency)
+		for i := 0; i < hostConcurrency; i++ {
+			go func(subnet *ToScan) {
+				defer wg.Done()
+				for ip := range cc {
+					results.add(scanHost(subnet, ip, ports, scannedBy))
 				}
+			}(subnet)
+		}
 
-				sp := model.ScannedPort{
-					IP:        ip,
-					CIDR:      subnet.CIDR,
-					Port:      s.Port,
-					State:     probeStatus.String(),
-					Site:      subnet.Site,
-					Protocol:  s.Protocol,
-					ScannedBy: ScannedBy,
-				}
-				sp.ID = sp.GenID()
-
-				if err = db.Save(&sp).Error; err != nil {
-					log.WithFields(log.Fields{"operation": "storing scan", "subnet": subnet.CIDR, "host": ip, "port": s.Port}).Error(err)
-					graphiteKey = "scan.db_save_failed"
-				}
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-			}
+		for ip, ok := hosts.Next(); ok; ip, ok = hosts.Next() {
+			cc <- ip
 		}
+		close(cc)
+		wg.Wait()
+		results.flush()
 
 		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Info("network scan finished")
 	}
 }
 
-// ReadKeaConfig reads the config configuration file and returns its data
-func ReadKeaConfig() (content []byte, err error) {
-	keaConfig := viper.GetString("scanner.kea_config")
-	content, err = ioutil.ReadFile(keaConfig)
-	if err != nil {
-		return content, err
-	}
-
-	return content, err
-}
-
-func LoadSubnets(source string, subnetsToScan []string, site []string) (subnets []*ToScan) {
-	content, err := ReadKeaConfig()
-	if err != nil {
-		log.WithFields(log.Fields{"operation": "loading subnets"}).Error(err)
-		os.Exit(1)
-	}
-
-	if source == "kea" {
-		subnets = LoadSubnetsFromKea(content)
+// scanHost probes all the ports of the host together and returns their results, scanned at the same time
+func scanHost(subnet *ToScan, ip string, ports []ProfilePort, scannedBy string) []storage.ScanResult {
+	results := make([]Result, len(ports))
+	wg := sync.WaitGroup{}
+	wg.Add(len(ports))
+	for i := range ports {
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			results[i], err = Probe(ports[i].Protocol, ip, ports[i].Port, time.Duration(ports[i].Timeout)*time.Second)
+			if err != nil {
+				log.WithFields(log.Fields{"operation": "scanning host", "subnet": subnet.CIDR, "host": ip, "port": ports[i].Port}).Error(err)
+				// failed scan for particular service is not a problem, we don't want separate metric on that
+			}
+		}(i)
 	}
+	wg.Wait()
 
-	if subnetsToScan[0] == "all" && site[0] == "all" {
-		return subnets
-	} else if subnetsToScan[0] == "all" && site[0] != "all" {
-		filteredSubnets := make([]*ToScan, 0)
-		for _, subnet := range subnets {
-			for _, s := range site {
-				if subnet.Site == s {
-					filteredSubnets = append(filteredSubnets, subnet)
-				}
-			}
-		}
-		subnets = filteredSubnets
-	} else {
-		filteredSubnets := make([]*ToScan, 0)
-		for _, subnet := range subnets {
-			for _, s := range subnetsToScan {
-				if s == subnet.CIDR {
-					filteredSubnets = append(filteredSubnets, subnet)
-				}
-			}
+	scannedAt := time.Now()
+	scans := make([]storage.ScanResult, len(ports))
+	for i, s := range ports {
+		scans[i] = storage.ScanResult{
+			Port: &model.ScannedPort{
+				IP:        ip,
+				CIDR:      subnet.CIDR,
+				Port:      s.Port,
+				State:     results[i].String(),
+				Site:      subnet.Site,
+				Protocol:  s.Protocol,
+				ScannedBy: scannedBy,
+			},
+			ScannedAt: scannedAt,
 		}
-		subnets = filteredSubnets
 	}
-
-	return subnets
-}
-
-// ListSubnets all or a list of given subnets
-func ListSubnets(subnetsToQuery []string, site []string) (subnets []*ToScan) {
-	return LoadSubnets(viper.GetString("scanner.subnet_source"), subnetsToQuery, site)
+	return scans
 }
 
 // ScanNetworks scan specific or all networks and try to find chassis, blades and servers
 func ScanNetworks(subnetsToScan []string, site []string) {
 	concurrency := viper.GetInt("scanner.concurrency")
 
+	profiles, err := LoadProfiles()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading scan profiles"}).Fatal(err)
+	}
+
 	cc := make(chan *ToScan, concurrency)
 	wg := sync.WaitGroup{}
 	db := storage.InitDB()
 
+	var nc *nats.Conn
+	if viper.GetBool("scanner.collect_new_ports") {
+		nc, err = nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
+		if err != nil {
+			log.Fatalf("Publisher unable to connect: %v\n", err)
+		}
+		defer nc.Close()
+	}
+
 	wg.Add(concurrency)
 	for i := 0; i < concurrency; i++ {
 		go func(input <-chan *ToScan, db *gorm.DB, wg *sync.WaitGroup) {
 			defer wg.Done()
-			scan(input, db)
+			scan(input, db, nc, profiles)
 		}(cc, db, &wg)
 	}
 
-	subnets := LoadSubnets(viper.GetString("scanner.subnet_source"), subnetsToScan, site)
+	subnets, err := LoadSubnets(subnetsToScan, site)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading subnets"}).Fatal(err)
+	}
 
 	for idx := range subnets {
 		cc <- subnets[idx]
@@ -254,15 +172,26 @@ func ScanNetworksWorker() {
 	}
 
 	concurrency := viper.GetInt("scanner.concurrency")
+	profiles, err := LoadProfiles()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading scan profiles"}).Fatal(err)
+	}
+
 	cc := make(chan *ToScan, concurrency)
 	wg := sync.WaitGroup{}
 	db := storage.InitDB()
 
+	// the new bmcs are published on the connection we already have
+	collector := nc
+	if !viper.GetBool("scanner.collect_new_ports") {
+		collector = nil
+	}
+
 	wg.Add(concurrency)
 	for i := 0; i < concurrency; i++ {
 		go func(input <-chan *ToScan, db *gorm.DB, wg *sync.WaitGroup) {
 			defer wg.Done()
-			scan(input, db)
+			scan(input, db, collector, profiles)
 		}(cc, db, &wg)
 	}
 
@@ -1,8 +1,17 @@
 package scanner
 
 import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/bmc-toolbox/dora/internal/kea"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
 	"github.com/spf13/viper"
 )
 
@@ -36,7 +45,14 @@ func TestLoadConfig(t *testing.T) {
 
 	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
 	for _, tc := range tt {
-		networks := LoadSubnetsFromKea(tc.content)
+		keaSubnets, err := kea.Parse(tc.content, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		networks, err := LoadSubnetsFromKea(keaSubnets)
+		if err != nil {
+			t.Fatal(err)
+		}
 		found := false
 		foundNetworks := make([]string, 0)
 		for _, network := range networks {
@@ -53,3 +69,331 @@ func TestLoadConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestProfiles(t *testing.T) {
+	defer viper.Reset()
+
+	profiles, err := LoadProfiles()
+	if err != nil || profiles.Service("tcp", 443) != ServiceWeb || profiles.Service("ipmi", 623) != ServiceIpmi {
+		t.Fatalf("expected the default profiles without scanner.profiles, got %v: %v", profiles, err)
+	}
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"default": []map[string]interface{}{
+			{"protocol": "tcp", "port": 443, "service": "web"},
+			{"protocol": "ipmi", "port": 623, "timeout": 2, "service": "ipmi"},
+		},
+		"kvm": []map[string]interface{}{
+			{"protocol": "tcp", "port": 8443, "service": "web"},
+			{"protocol": "tcp", "port": 5900, "timeout": 3},
+		},
+	})
+	viper.Set("scanner.site_profiles", map[string]string{"ams4": "kvm"})
+
+	if profiles, err = LoadProfiles(); err != nil {
+		t.Fatal(err)
+	}
+	if profiles["default"][0].Timeout != 1 || profiles["default"][1].Timeout != 2 {
+		t.Errorf("expected the timeouts to default to 1 second, got %+v", profiles["default"])
+	}
+
+	subnets := []struct {
+		subnet  *ToScan
+		profile string
+	}{
+		{&ToScan{CIDR: "10.0.0.0/24", Site: "ams4"}, "kvm"},
+		{&ToScan{CIDR: "10.0.0.0/24", Site: "lhr4"}, "default"},
+		{&ToScan{CIDR: "10.0.0.0/24", Site: "lhr4", Profile: "KVM"}, "kvm"},
+	}
+	for _, s := range subnets {
+		if name, ports := profiles.Of(s.subnet); name != s.profile || len(ports) != 2 {
+			t.Errorf("expected %+v to be scanned with %s, got %s %+v", s.subnet, s.profile, name, ports)
+		}
+	}
+
+	collectable := profiles.Ports(ServiceWeb, ServiceIpmi)
+	if len(collectable) != 3 || collectable[0].Port != 443 || collectable[1].Port != 623 || collectable[2].Port != 8443 {
+		t.Errorf("expected to collect through 443, 623 and 8443, got %+v", collectable)
+	}
+
+	invalid := []map[string]interface{}{
+		{"kvm": []map[string]interface{}{{"protocol": "tcp", "port": 443, "service": "web"}}},
+		{"default": []map[string]interface{}{{"protocol": "udp", "port": 443, "service": "web"}}},
+		{"default": []map[string]interface{}{{"protocol": "tcp", "port": 0, "service": "web"}}},
+		{"default": []map[string]interface{}{{"protocol": "tcp", "port": 443, "service": "https"}}},
+		{"default": []map[string]interface{}{{"protocol": "tcp", "port": 22, "service": "ssh"}}},
+		{"default": []map[string]interface{}{{"protocol": "tcp", "port": 443, "service": "web"}}, "kvm": []map[string]interface{}{{"protocol": "tcp", "port": 443, "service": "ssh"}}},
+	}
+	for _, profiles := range invalid {
+		viper.Set("scanner.profiles", profiles)
+		if _, err = LoadProfiles(); err == nil {
+			t.Errorf("expected %v to be refused", profiles)
+		}
+	}
+}
+
+func TestKeaRules(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("scanner.kea_profile_option", "dora-scan-profile")
+	viper.Set("scanner.kea_rules", []map[string]interface{}{
+		{"user_context": "dora-site"},
+		{"option": "domain-name", "regex": `^([a-z0-9]+)\.bmc\.example\.com$`},
+	})
+
+	keaSubnets, err := kea.Read("../internal/kea/testdata/kea-dhcp4.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keaSubnets6, err := kea.Read("../internal/kea/testdata/kea-dhcp6.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subnets, err := LoadSubnetsFromKea(append(keaSubnets, keaSubnets6...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ToScan{
+		{CIDR: "192.168.0.0/24", Site: "lhr4"},
+		{CIDR: "10.1.0.0/24", Site: "ams4", Profile: "kvm"},
+	}
+	if len(subnets) != len(expected) {
+		t.Fatalf("expected %v, got %d subnets", expected, len(subnets))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(*subnets[i], expected[i]) {
+			t.Errorf("expected %+v, got %+v", expected[i], subnets[i])
+		}
+	}
+
+	for _, rules := range [][]map[string]interface{}{
+		{{"regex": ".*"}},
+		{{"option": "domain-name", "user_context": "dora-site"}},
+		{{"option": "domain-name", "regex": "("}},
+	} {
+		viper.Set("scanner.kea_rules", rules)
+		if _, err = LoadSubnetsFromKea(keaSubnets); err == nil {
+			t.Errorf("expected %v to be refused", rules)
+		}
+	}
+}
+
+func TestSubnetSources(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("scanner.kea_domain_name_suffix", ".bmc.example.com")
+	viper.Set("scanner.dhcpd_profile_option", "dora-scan-profile")
+	viper.Set("scanner.dhcpd_config", "testdata/dhcpd.conf")
+	viper.Set("scanner.subnet_file", "testdata/subnets.yaml")
+	viper.Set("scanner.subnet_source", []string{"file", "dhcpd"})
+
+	subnets, err := LoadSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ToScan{
+		{CIDR: "10.1.0.0/24", Site: "ams4"},
+		{CIDR: "10.2.0.0/24", Site: "fra1", Profile: "kvm"},
+		{CIDR: "10.4.0.0/24", Site: "sin1", Profile: "kvm"},
+		{CIDR: "10.3.0.0/24", Site: "ams4"},
+	}
+	if len(subnets) != len(expected) {
+		t.Fatalf("expected %v, got %d subnets", expected, len(subnets))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(*subnets[i], expected[i]) {
+			t.Errorf("expected %+v, got %+v", expected[i], subnets[i])
+		}
+	}
+
+	// the first source giving a subnet wins
+	viper.Set("scanner.subnet_source", "file, dhcpd")
+	viper.Set("scanner.subnet_file", "testdata/subnets.csv")
+	if subnets, err = LoadSubnets([]string{"all"}, []string{"lhr4"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 1 || !reflect.DeepEqual(*subnets[0], ToScan{CIDR: "10.3.0.0/24", Site: "lhr4"}) {
+		t.Errorf("expected only 10.3.0.0/24 of lhr4, got %v", subnets)
+	}
+	if subnets, err = LoadSubnets([]string{"10.1.0.0/24"}, []string{"all"}); err != nil || len(subnets) != 1 || subnets[0].Site != "fra1" {
+		t.Errorf("expected 10.1.0.0/24 of fra1, got %v: %v", subnets, err)
+	}
+
+	for _, source := range []string{"", "ldap", "kea,file"} {
+		viper.Set("scanner.subnet_source", source)
+		viper.Set("scanner.kea_config", "testdata/missing.conf")
+		if _, err = LoadSubnets([]string{"all"}, []string{"all"}); err == nil {
+			t.Errorf("expected subnet source %q to fail", source)
+		}
+	}
+
+	viper.Set("scanner.dhcpd_rules", []map[string]interface{}{{"user_context": "dora-site"}})
+	if _, err = (&dhcpdSource{path: "testdata/dhcpd.conf"}).Subnets(); err == nil {
+		t.Error("expected the dhcpd rules on user context to be refused")
+	}
+}
+
+func TestCandidateHosts(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("scanner.subnet_source", "kea,file")
+	viper.Set("scanner.kea_config", "../internal/kea/testdata/kea-dhcp6.conf")
+	viper.Set("scanner.kea_leases6", "../internal/kea/testdata/kea-leases6.csv")
+	viper.Set("scanner.kea_rules", []map[string]interface{}{{"option": "domain-search", "regex": `^(\w+)\.bmc\.example\.com$`}})
+	viper.Set("scanner.subnet_file", "testdata/subnets.yaml")
+	viper.Set("scanner.ipv6_host_files", []string{"testdata/neighbors6"})
+
+	subnets, err := LoadSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the leases of the test file expired long ago and the first kea subnet has no site
+	expected := map[string][]string{
+		"10.1.0.0/24":     nil,
+		"10.2.0.0/24":     nil,
+		"2001:db8::/64":   {"2001:db8::10", "2001:db8::11"},
+		"2001:db8:2::/64": nil,
+	}
+	if len(subnets) != 3 {
+		t.Fatalf("expected the ipv4 subnets and the ipv6 one with neighbors, got %d subnets", len(subnets))
+	}
+	for _, subnet := range subnets {
+		if hosts, ok := expected[subnet.CIDR]; !ok || !reflect.DeepEqual(subnet.Hosts, hosts) {
+			t.Errorf("expected %s to have the candidates %v, got %v", subnet.CIDR, hosts, subnet.Hosts)
+		}
+	}
+
+	if subnets, err = LoadSubnets([]string{"2001:DB8:0::/64"}, []string{"all"}); err != nil || len(subnets) != 1 {
+		t.Errorf("expected to find the ipv6 subnet however it's written, got %v: %v", subnets, err)
+	}
+
+	hosts, err := (&ToScan{CIDR: "2001:db8::/64", Hosts: []string{"2001:db8::10", "2001:DB8:0::10", "2001:db8::11"}}).hosts()
+	if err != nil || !reflect.DeepEqual(all(hosts), []string{"2001:db8::10", "2001:db8::11"}) {
+		t.Errorf("expected each candidate once, got %v: %v", hosts, err)
+	}
+	if hosts, err = (&ToScan{CIDR: "10.0.0.0/30"}).hosts(); err != nil || !reflect.DeepEqual(all(hosts), []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("expected the ipv4 subnet to be scanned address by address, got %v: %v", hosts, err)
+	}
+	for _, subnet := range []*ToScan{{CIDR: "2001:db8::/64"}, {CIDR: "2001:db8::/64", Hosts: []string{"2001:db8:1::1"}}} {
+		if _, err = subnet.hosts(); err == nil {
+			t.Errorf("expected the candidates of %+v to be refused", subnet)
+		}
+	}
+
+	keaSubnets, err := kea.Read("../internal/kea/testdata/kea-dhcp6.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("scanner.kea_rules", []map[string]interface{}{{"option": "domain-search", "regex": `bmc\.example\.com$`}})
+	if subnets, err = LoadSubnetsFromKea(keaSubnets); err != nil || len(subnets) != 2 || !reflect.DeepEqual(subnets[0].Hosts, []string{"2001:db8:1::100", "2001:db8:1::101"}) {
+		t.Errorf("expected the reservations to be the candidates of the kea subnet, got %v: %v", subnets, err)
+	}
+}
+
+// all returns every address of the iterator
+func all(hosts hostIterator) (ips []string) {
+	for ip, ok := hosts.Next(); ok; ip, ok = hosts.Next() {
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+func TestAddressIterator(t *testing.T) {
+	tt := []struct {
+		cidr  string
+		first string
+		last  string
+		count int
+	}{
+		{"10.0.0.0/30", "10.0.0.1", "10.0.0.2", 2},
+		{"10.0.0.17/24", "10.0.0.1", "10.0.0.254", 254},
+		{"10.0.0.0/31", "10.0.0.0", "10.0.0.1", 2},
+		{"10.0.0.7/32", "10.0.0.7", "10.0.0.7", 1},
+		{"255.255.255.255/32", "255.255.255.255", "255.255.255.255", 1},
+		{"255.255.255.254/31", "255.255.255.254", "255.255.255.255", 2},
+		{"10.0.255.0/23", "10.0.254.1", "10.0.255.254", 510},
+		{"10.1.0.0/16", "10.1.0.1", "10.1.255.254", 65534},
+	}
+
+	for _, tc := range tt {
+		hosts, err := (&ToScan{CIDR: tc.cidr}).hosts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ips := all(hosts)
+		if len(ips) != tc.count || ips[0] != tc.first || ips[len(ips)-1] != tc.last {
+			t.Errorf("expected %d addresses from %s to %s in %s, got %d", tc.count, tc.first, tc.last, tc.cidr, len(ips))
+		}
+		if _, ok := hosts.Next(); ok {
+			t.Errorf("expected the addresses of %s to stay done", tc.cidr)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	defer viper.Reset()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := listener.Addr().(*net.TCPAddr).Port
+
+	dir, err := ioutil.TempDir("", "dora-scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	viper.Set("database_type", "sqlite3")
+	viper.Set("database_max_connections", 1)
+	db := storage.Connect(filepath.Join(dir, "dora.db"))
+	defer db.Close()
+	if _, err = storage.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("scanner.scanned_by", "test")
+	viper.Set("scanner.host_concurrency", 4)
+	// the results of a host stay together, the last batch is only written by the flush
+	viper.Set("scanner.batch_size", 3)
+	profiles := Profiles{DefaultProfile: {{Protocol: "tcp", Port: open, Timeout: 1, Service: ServiceWeb}, {Protocol: "tcp", Port: 1, Timeout: 1}}}
+
+	input := make(chan *ToScan, 1)
+	input <- &ToScan{CIDR: "127.0.0.0/29", Site: "lo"}
+	close(input)
+	scan(input, db, nil, profiles)
+
+	var ports []model.ScannedPort
+	if err = db.Order("ip, port").Find(&ports).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 12 {
+		t.Fatalf("expected the two ports of the six hosts, got %d", len(ports))
+	}
+
+	scannedAt := make(map[string]time.Time)
+	for _, port := range ports {
+		if expected := port.IP == "127.0.0.1" && port.Port == open; (port.State == model.PortOpen) != expected {
+			t.Errorf("expected %s:%d to be open %t, got %s", port.IP, port.Port, expected, port.State)
+		}
+		last := port.LastClosedAt
+		if port.State == model.PortOpen {
+			last = port.LastOpenAt
+		}
+		if at, ok := scannedAt[port.IP]; last == nil || (ok && !at.Equal(*last)) {
+			t.Errorf("expected the ports of %s to be scanned at the same time, got %s and %v", port.IP, at, last)
+		} else {
+			scannedAt[port.IP] = *last
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package scanner
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Subnet sources of scanner.subnet_source
+const (
+	SourceKea   = "kea"
+	SourceFile  = "file"
+	SourceDhcpd = "dhcpd"
+)
+
+// SubnetSource gives the subnets to scan with their site
+type SubnetSource interface {
+	Subnets() ([]*ToScan, error)
+}
+
+// NewSubnetSource returns the source of the given name, configured by its scanner keys
+func NewSubnetSource(name string) (SubnetSource, error) {
+	switch name {
+	case SourceKea:
+		return &keaSource{path: viper.GetString("scanner.kea_config")}, nil
+	case SourceFile:
+		return &fileSource{path: viper.GetString("scanner.subnet_file")}, nil
+	case SourceDhcpd:
+		return &dhcpdSource{path: viper.GetString("scanner.dhcpd_config")}, nil
+	}
+	return nil, fmt.Errorf("unknown subnet source %q", name)
+}
+
+// Sources returns the sources of scanner.subnet_source, a list or a single value with the names split by commas
+func Sources() (sources []SubnetSource, err error) {
+	for _, value := range viper.GetStringSlice("scanner.subnet_source") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+				continue
+			}
+
+			source, err := NewSubnetSource(name)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no subnet source configured")
+	}
+	return sources, nil
+}
+
+// combine returns the subnets of all sources, a subnet given by several sources is kept as the first one gives it
+func combine(sources []SubnetSource) (subnets []*ToScan, err error) {
+	seen := make(map[string]*ToScan)
+	for _, source := range sources {
+		list, err := source.Subnets()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, subnet := range list {
+			if first, ok := seen[subnet.CIDR]; ok {
+				if first.Site != subnet.Site {
+					log.WithFields(log.Fields{"operation": "loading subnets", "subnet": subnet.CIDR, "site": first.Site, "ignored": subnet.Site}).Warn("subnet given with different sites")
+				}
+				continue
+			}
+			seen[subnet.CIDR] = subnet
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets, nil
+}
+
+// SubnetRule selects the subnets to scan of a dhcp config and gives their site. The value of the option or of
+// the user context key must match the regex, the site is its first group or the whole value when the regex has
+// none
+type SubnetRule struct {
+	Option      string `json:"option" mapstructure:"option"`
+	UserContext string `json:"user_context" mapstructure:"user_context"`
+	Regex       string `json:"regex" mapstructure:"regex"`
+	regex       *regexp.Regexp
+}
+
+// SubnetRules are tried in order, the first one selecting a subnet gives its site
+type SubnetRules []SubnetRule
+
+// site returns the site of the subnet with the options and user context when a rule selects it
+func (r SubnetRules) site(options map[string]string, userContext map[string]interface{}) (site string, ok bool) {
+	for i := range r {
+		if site, ok = r[i].site(options, userContext); ok {
+			return site, true
+		}
+	}
+	return site, false
+}
+
+// site returns the site of the subnet with the options and user context when the rule selects it
+func (r *SubnetRule) site(options map[string]string, userContext map[string]interface{}) (site string, ok bool) {
+	var value string
+	if r.Option != "" {
+		if value, ok = options[r.Option]; !ok {
+			return site, false
+		}
+	} else {
+		var v interface{}
+		if v, ok = userContext[r.UserContext]; !ok {
+			return site, false
+		}
+		value = fmt.Sprint(v)
+	}
+
+	match := r.regex.FindStringSubmatch(value)
+	if match == nil {
+		return site, false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return value, true
+}
+
+// subnetRules returns the rules of the key, without them the subnets with a domain-name ending with
+// scanner.kea_domain_name_suffix are scanned and their site is what comes before it
+func subnetRules(key string, withUserContext bool) (rules SubnetRules, err error) {
+	if !viper.IsSet(key) {
+		suffix := regexp.QuoteMeta(viper.GetString("scanner.kea_domain_name_suffix"))
+		return SubnetRules{{Option: "domain-name", regex: regexp.MustCompile("^(.*)" + suffix + "$")}}, nil
+	}
+
+	if err = viper.UnmarshalKey(key, &rules); err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		if !withUserContext && rules[i].UserContext != "" {
+			return nil, fmt.Errorf("%s rule %d: only options can be matched", key, i)
+		}
+		if (rules[i].Option == "") == (rules[i].UserContext == "") {
+			return nil, fmt.Errorf("%s rule %d needs either an option or a user_context", key, i)
+		}
+		if rules[i].regex, err = regexp.Compile(rules[i].Regex); err != nil {
+			return nil, fmt.Errorf("%s rule %d: %s", key, i, err)
+		}
+	}
+	return rules, nil
+}
+
+// fileSource reads a static list of subnets, a csv file of cidr,site[,profile] or a yaml, json or toml file
+// with a subnets list of cidr, site, profile and the candidate hosts of the ipv6 ones
+type fileSource struct {
+	path string
+}
+
+// fileSubnet is a subnet of a yaml, json or toml subnet file
+type fileSubnet struct {
+	CIDR    string   `mapstructure:"cidr"`
+	Site    string   `mapstructure:"site"`
+	Profile string   `mapstructure:"profile"`
+	Hosts   []string `mapstructure:"hosts"`
+}
+
+// Subnets returns the subnets of the file
+func (s *fileSource) Subnets() (subnets []*ToScan, err error) {
+	var list []fileSubnet
+	if strings.ToLower(filepath.Ext(s.path)) == ".csv" {
+		list, err = readCSV(s.path)
+	} else {
+		v := viper.New()
+		v.SetConfigFile(s.path)
+		if err = v.ReadInConfig(); err == nil {
+			err = v.UnmarshalKey("subnets", &list)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", s.path, err)
+	}
+
+	for i, subnet := range list {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(subnet.CIDR))
+		if err != nil {
+			return nil, fmt.Errorf("%s: subnet %d: invalid cidr %q", s.path, i+1, subnet.CIDR)
+		}
+		if subnet.Site == "" {
+			return nil, fmt.Errorf("%s: subnet %s has no site", s.path, subnet.CIDR)
+		}
+
+		subnets = append(subnets, &ToScan{CIDR: network.String(), Site: subnet.Site, Profile: subnet.Profile, Hosts: subnet.Hosts})
+	}
+	return subnets, nil
+}
+
+// readCSV reads the subnets of a csv file, the lines starting with # and a cidr,site header are skipped
+func readCSV(path string) (list []fileSubnet, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("%q: expected cidr,site[,profile]", strings.Join(record, ","))
+		}
+		if len(list) == 0 && strings.EqualFold(record[0], "cidr") {
+			continue
+		}
+
+		subnet := fileSubnet{CIDR: record[0], Site: strings.TrimSpace(record[1])}
+		if len(record) == 3 {
+			subnet.Profile = strings.TrimSpace(record[2])
+		}
+		list = append(list, subnet)
+	}
+}
+
+// LoadSubnets returns the subnets of the sources of scanner.subnet_source. With all the subnets of the sites are
+// returned, or all of them when the site is all too, otherwise the given subnets the sources know of. The ipv6
+// subnets get the candidate hosts of scanner.ipv6_host_files, the ones left without candidates aren't returned
+func LoadSubnets(subnetsToScan []string, site []string) (subnets []*ToScan, err error) {
+	sources, err := Sources()
+	if err != nil {
+		return nil, err
+	}
+
+	if subnets, err = combine(sources); err != nil {
+		return nil, err
+	}
+
+	hosts, err := readHostFiles()
+	if err != nil {
+		return nil, err
+	}
+	addHosts(subnets, hosts)
+
+	scannable := make([]*ToScan, 0, len(subnets))
+	for _, subnet := range subnets {
+		if _, network, _ := net.ParseCIDR(subnet.CIDR); network.IP.To4() == nil && len(subnet.Hosts) == 0 {
+			log.WithFields(log.Fields{"operation": "loading subnets", "subnet": subnet.CIDR}).Debug("ipv6 subnet without candidate hosts, it isn't scanned")
+			continue
+		}
+		scannable = append(scannable, subnet)
+	}
+	subnets = scannable
+
+	if subnetsToScan[0] == "all" && site[0] == "all" {
+		return subnets, nil
+	}
+
+	filteredSubnets := make([]*ToScan, 0)
+	for _, subnet := range subnets {
+		if subnetsToScan[0] == "all" {
+			for _, s := range site {
+				if subnet.Site == s {
+					filteredSubnets = append(filteredSubnets, subnet)
+				}
+			}
+			continue
+		}
+
+		for _, s := range subnetsToScan {
+			// the ipv6 subnets can be given in any of the ways to write them
+			if _, network, err := net.ParseCIDR(s); err == nil {
+				s = network.String()
+			}
+			if s == subnet.CIDR {
+				filteredSubnets = append(filteredSubnets, subnet)
+			}
+		}
+	}
+
+	return filteredSubnets, nil
+}
+
+// ListSubnets all or a list of given subnets
+func ListSubnets(subnetsToQuery []string, site []string) (subnets []*ToScan, err error) {
+	return LoadSubnets(subnetsToQuery, site)
+}
@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/jinzhu/gorm"
 
@@ -20,32 +24,23 @@ var (
 	err  error
 )
 
-// InitDB creates and migrates the database
+// InitDB creates the database handler and makes sure the schema is at the version we expect, it's only
+// migrated here when database_auto_migrate is set, otherwise dora db migrate up must be run first
 func InitDB() *gorm.DB {
 	if db != nil {
 		return db
 	}
 
-	db, err = gorm.Open(viper.GetString("database_type"), viper.GetString("database_options"))
-	if err != nil {
+	db = Connect(viper.GetString("database_options"))
+	if viper.GetBool("database_auto_migrate") {
+		if _, err = MigrateUp(db); err != nil {
+			panic(err)
+		}
+	}
+
+	if err = CheckSchema(db); err != nil {
 		panic(err)
 	}
-	db.DB().SetMaxIdleConns(viper.GetInt("database_max_connections") / 2)
-	db.DB().SetMaxOpenConns(viper.GetInt("database_max_connections"))
-
-	db.LogMode(viper.GetBool("debug"))
-	db.SingularTable(true)
-	db.AutoMigrate(
-		&model.Blade{},
-		&model.Discrete{},
-		&model.Chassis{},
-		&model.Nic{},
-		&model.StorageBlade{},
-		&model.ScannedPort{},
-		&model.Psu{},
-		&model.Disk{},
-		&model.Fan{},
-	)
 
 	return db
 }
@@ -56,15 +51,175 @@ func InitRODB() *gorm.DB {
 		return rodb
 	}
 
-	rodb, err = gorm.Open(viper.GetString("database_type"), viper.GetString("ro_database_options"))
+	rodb = Connect(viper.GetString("ro_database_options"))
+	if err = CheckSchema(rodb); err != nil {
+		panic(err)
+	}
+
+	return rodb
+}
+
+// Connect opens a database handler without looking at the schema, it's meant for the commands managing it
+func Connect(options string) *gorm.DB {
+	conn, err := gorm.Open(viper.GetString("database_type"), options)
 	if err != nil {
 		panic(err)
 	}
-	rodb.DB().SetMaxIdleConns(viper.GetInt("database_max_connections") / 2)
-	rodb.DB().SetMaxOpenConns(viper.GetInt("database_max_connections"))
+	conn.DB().SetMaxIdleConns(viper.GetInt("database_max_connections") / 2)
+	conn.DB().SetMaxOpenConns(viper.GetInt("database_max_connections"))
 
-	rodb.LogMode(viper.GetBool("debug"))
-	rodb.SingularTable(true)
+	conn.LogMode(viper.GetBool("debug"))
+	conn.SingularTable(true)
 
-	return rodb
+	return conn
+}
+
+// transaction runs fn inside a database transaction, it's committed only if fn succeeds and rolled back otherwise
+func transaction(db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	tx := db.Begin()
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// revive clears removed_at on the components a collection reported again, saving them would fail otherwise
+// as gorm doesn't see the removed rows and tries to create them again
+func revive(db *gorm.DB, value interface{}, column string, ids []string) (err error) {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Unscoped().Model(value).Where(fmt.Sprintf("%s in (?) and removed_at is not null", column), ids).UpdateColumn("removed_at", gorm.Expr("NULL")).Error
+}
+
+// maxParameters is how many parameters a statement of the dialect takes, sqlite is built with the lowest limit
+func maxParameters(db *gorm.DB) int {
+	if db.Dialect().GetName() == "sqlite3" {
+		return 999
+	}
+	return 65535
+}
+
+// chunks splits the ids in lists of at most size
+func chunks(ids []string, size int) (list [][]string) {
+	for len(ids) > size {
+		list = append(list, ids[:size])
+		ids = ids[size:]
+	}
+	return append(list, ids)
+}
+
+// upsert writes the rows with multi row inserts updating the rows already there, the key is the first column
+func upsert(db *gorm.DB, table string, columns []string, rows [][]interface{}) error {
+	dialect := db.Dialect()
+	key := dialect.Quote(columns[0])
+
+	var update []string
+	for _, column := range columns[1:] {
+		column = dialect.Quote(column)
+		if dialect.GetName() == "mysql" {
+			update = append(update, fmt.Sprintf("%s = VALUES(%s)", column, column))
+		} else {
+			update = append(update, fmt.Sprintf("%s = excluded.%s", column, column))
+		}
+	}
+
+	if dialect.GetName() == "mysql" {
+		return insertRows(db, table, columns, rows, " ON DUPLICATE KEY UPDATE "+strings.Join(update, ", "))
+	}
+	return insertRows(db, table, columns, rows, fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(update, ", ")))
+}
+
+// insertRows writes the rows with multi row inserts ended by suffix, with as many rows per statement as the
+// dialect takes parameters. The statements go straight to the driver, gorm is slow at binding that many values
+func insertRows(db *gorm.DB, table string, columns []string, rows [][]interface{}, suffix string) error {
+	dialect := db.Dialect()
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = dialect.Quote(column)
+	}
+
+	perStatement := maxParameters(db) / len(columns)
+	for len(rows) > 0 {
+		n := perStatement
+		if n > len(rows) {
+			n = len(rows)
+		}
+
+		values := make([]string, 0, n)
+		args := make([]interface{}, 0, n*len(columns))
+		placeholders := make([]string, len(columns))
+		for _, row := range rows[:n] {
+			for i := range placeholders {
+				placeholders[i] = "?"
+				if dialect.GetName() == "postgres" {
+					placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+				}
+			}
+			values = append(values, "("+strings.Join(placeholders, ", ")+")")
+			args = append(args, row...)
+		}
+
+		statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s%s", dialect.Quote(table), strings.Join(quoted, ", "), strings.Join(values, ", "), suffix)
+		if _, err := db.CommonDB().Exec(statement, args...); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// PurgeRemoved deletes for good the components marked as removed before the given time, the removed blades go
+// with their nics and disks as those aren't marked when the blade stops being reported
+func PurgeRemoved(db *gorm.DB, before time.Time) (purged int64, err error) {
+	for {
+		var blades []string
+		if err = db.Unscoped().Model(&model.Blade{}).Where("removed_at < ?", before).Order("serial").Limit(exportBatch).Pluck("serial", &blades).Error; err != nil {
+			return purged, err
+		}
+		if len(blades) == 0 {
+			break
+		}
+
+		err = transaction(db, func(tx *gorm.DB) error {
+			tx = tx.Unscoped()
+			for _, component := range []interface{}{&model.Nic{}, &model.Disk{}, &model.Blade{}} {
+				where := "blade_serial in (?)"
+				if _, ok := component.(*model.Blade); ok {
+					where = "serial in (?)"
+				}
+				q := tx.Where(where, blades).Delete(component)
+				if q.Error != nil {
+					return q.Error
+				}
+				purged += q.RowsAffected
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+	}
+
+	for _, component := range []interface{}{&model.StorageBlade{}, &model.Nic{}, &model.Psu{}, &model.Disk{}, &model.Fan{}} {
+		q := db.Unscoped().Where("removed_at < ?", before).Delete(component)
+		if q.Error != nil {
+			return purged, q.Error
+		}
+		purged += q.RowsAffected
+	}
+	return purged, nil
 }
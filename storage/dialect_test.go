@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+)
+
+type counter interface {
+	Count(*filter.Filters) (int, error)
+}
+
+// sqliteDB opens an empty sqlite database in a temporary directory
+func sqliteDB(t *testing.T) (conn *gorm.DB, cleanup func()) {
+	dir, err := ioutil.TempDir("", "dora-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("database_type", "sqlite3")
+	viper.Set("database_max_connections", 2)
+	conn = Connect(filepath.Join(dir, "dora.db"))
+
+	return conn, func() {
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// dialectDB opens the database of the dialect, postgres and mysql are only tested when DORA_TEST_POSTGRES or
+// DORA_TEST_MYSQL have their connection options, eg: a local container started with
+//
+//	docker run -e POSTGRES_PASSWORD=dora -p 5432:5432 postgres
+//	DORA_TEST_POSTGRES="host=127.0.0.1 user=postgres password=dora sslmode=disable"
+//	docker run -e MYSQL_ROOT_PASSWORD=dora -e MYSQL_DATABASE=dora -p 3306:3306 mysql:5.7
+//	DORA_TEST_MYSQL="root:dora@tcp(127.0.0.1:3306)/dora?parseTime=true"
+//
+// The schema is dropped at the end, don't point them to a database you care about
+func dialectDB(t *testing.T, dialect string) (conn *gorm.DB, cleanup func()) {
+	if dialect == "sqlite3" {
+		return sqliteDB(t)
+	}
+
+	env := map[string]string{"postgres": "DORA_TEST_POSTGRES", "mysql": "DORA_TEST_MYSQL"}[dialect]
+	options := os.Getenv(env)
+	if options == "" {
+		t.Skipf("%s isn't set", env)
+	}
+
+	viper.Set("database_type", dialect)
+	viper.Set("database_max_connections", 2)
+	conn = Connect(options)
+
+	return conn, func() {
+		for version, _ := SchemaVersion(conn); version > 0; version, _ = SchemaVersion(conn) {
+			if _, err := MigrateDown(conn); err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		conn.DropTableIfExists(&SchemaMigration{})
+		conn.Close()
+	}
+}
+
+func TestDialects(t *testing.T) {
+	for _, dialect := range []string{"sqlite3", "postgres", "mysql"} {
+		t.Run(dialect, func(t *testing.T) {
+			conn, cleanup := dialectDB(t, dialect)
+			defer cleanup()
+
+			if _, err := MigrateUp(conn); err != nil {
+				t.Fatal(err)
+			}
+			testModels(t, conn)
+			testRecordBatch(t, conn)
+		})
+	}
+}
+
+// testModels stores every model and reads it back through the storages, the filters and the stats queries
+func testModels(t *testing.T, conn *gorm.DB) {
+	chassis := &model.Chassis{
+		Serial:      "ez3605020d",
+		Vendor:      "HP",
+		TempC:       24,
+		FaultySlots: model.Slots{3, 12},
+		Blades: []*model.Blade{
+			{Serial: "cz3605020e", ChassisSerial: "ez3605020d", BladePosition: 1, Vendor: "HP", TempC: 30, Nics: []*model.Nic{{MacAddress: "a0:36:9f:00:00:01", BladeSerial: "cz3605020e"}}},
+		},
+		Psus: []*model.Psu{{Serial: "psu1", ChassisSerial: "ez3605020d", PowerKw: 0.3}},
+		Fans: []*model.Fan{{Serial: "fan1", ChassisSerial: "ez3605020d", CurrentRPM: 6000}},
+	}
+	if _, err := NewChassisStorage(conn).ApplySnapshot(chassis); err != nil {
+		t.Fatal(err)
+	}
+
+	discrete := &model.Discrete{Serial: "s34900710912345", Vendor: "Supermicro", DataSource: "ipmi", Psus: []*model.Psu{{Serial: "psu2", DiscreteSerial: "s34900710912345"}}}
+	if _, err := NewDiscreteStorage(conn).ApplySnapshot(discrete); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, value := range []interface{}{
+		&model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: "open"},
+		&model.CollectionAttempt{IP: "10.0.0.1", Site: "ams4", StartedAt: now, FinishedAt: now, Outcome: model.CollectionSucceeded},
+		&model.AssetChange{AssetType: "chassis", AssetSerial: "ez3605020d", ChangedAt: now, Changes: model.FieldChanges{{Field: "temp_c", Before: "23", After: "24"}}},
+		&model.PortStateChange{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", PreviousState: "open", State: "closed", ChangedAt: now},
+	} {
+		if err := conn.Create(value).Error; err != nil {
+			t.Fatalf("%T: %s", value, err)
+		}
+	}
+	if err := NewReadingStorage(conn).Append(chassis.Readings(now)); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := NewChassisStorage(conn).GetOne("ez3605020d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.FaultySlots) != 2 || stored.FaultySlots[0] != 3 || stored.FaultySlots[1] != 12 {
+		t.Errorf("expected faulty slots [3 12], got %v", stored.FaultySlots)
+	}
+	if len(stored.Blades) != 1 || len(stored.Psus) != 1 || len(stored.Fans) != 1 {
+		t.Errorf("expected the components of the chassis, got %d blades, %d psus and %d fans", len(stored.Blades), len(stored.Psus), len(stored.Fans))
+	}
+
+	var change model.AssetChange
+	if err = conn.First(&change).Error; err != nil || len(change.Changes) != 1 || change.Changes[0].After != "24" {
+		t.Errorf("expected the asset change to be read back, got %+v: %v", change, err)
+	}
+
+	counts := []struct {
+		name     string
+		storage  counter
+		field    string
+		operator string
+		value    string
+		expected int
+	}{
+		{"chassis by vendor", NewChassisStorage(conn), "vendor", "eq", "HP", 1},
+		{"chassis by other vendor", NewChassisStorage(conn), "vendor", "ne", "HP", 0},
+		{"blades by temperature", NewBladeStorage(conn), "temp_c", "gt", "25", 1},
+		{"discretes by source", NewDiscreteStorage(conn), "data_source", "eq", "ipmi", 1},
+		{"psus by power", NewPsuStorage(conn), "power_kw", "ge", "0.3", 1},
+		{"fans by speed", NewFanStorage(conn), "current_rpm", "lt", "6000", 0},
+		{"ports by site", NewScannedPortStorage(conn), "site", "eq", "ams4", 1},
+		{"ports that went dark", NewPortStateChangeStorage(conn), "state", "eq", "closed", 1},
+		{"chassis updated recently", NewChassisStorage(conn), "updated_at", "gt", now.Add(-time.Hour).Format(time.RFC3339), 1},
+		{"chassis not updated in a day", NewChassisStorage(conn), "updated_at", "lt", now.AddDate(0, 0, -1).Format(time.RFC3339), 0},
+		{"nics", NewNicStorage(conn), "mac_address", "eq", "a0:36:9f:00:00:01", 1},
+	}
+
+	for _, c := range counts {
+		filters := &filter.Filters{}
+		filters.Add(c.field, []string{c.value}, c.operator)
+		count, err := c.storage.Count(filters)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+		} else if count != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.name, c.expected, count)
+		}
+	}
+
+	// the blade reported no more is marked as removed and hidden from the counts
+	chassis.Blades = nil
+	if _, err = NewChassisStorage(conn).ApplySnapshot(chassis); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := NewBladeStorage(conn).Count(&filter.Filters{}); err != nil || count != 0 {
+		t.Errorf("expected the blade to be removed, got %d: %v", count, err)
+	}
+	if purged, err := PurgeRemoved(conn, time.Now().Add(time.Minute)); err != nil || purged == 0 {
+		t.Errorf("expected the removed components to be purged, got %d: %v", purged, err)
+	}
+	var nics int
+	if err := conn.Unscoped().Model(&model.Nic{}).Where("blade_serial = ?", "cz3605020e").Count(&nics).Error; err != nil || nics != 0 {
+		t.Errorf("expected the nics of the purged blade to be purged, got %d: %v", nics, err)
+	}
+}
+
+// testRecordBatch writes more scan results than a statement of the dialect takes, twice so the second batch
+// updates the ports of the first one
+func testRecordBatch(t *testing.T, conn *gorm.DB) {
+	scannedAt := time.Now().Truncate(time.Second)
+	for i, state := range []string{model.PortOpen, model.PortClosed} {
+		var results []ScanResult
+		for host := 1; host <= 1000; host++ {
+			port := &model.ScannedPort{Site: "fra1", CIDR: "10.1.0.0/22", IP: fmt.Sprintf("10.1.%d.%d", host/256, host%256), Port: 623, Protocol: "ipmi", ScannedBy: "test", State: state}
+			results = append(results, ScanResult{Port: port, ScannedAt: scannedAt.Add(time.Duration(i) * time.Hour)})
+		}
+
+		changes, err := NewScannedPortStorage(conn).RecordBatch(results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 1000 {
+			t.Errorf("batch %d: expected a change for every port, got %d", i+1, len(changes))
+		}
+	}
+
+	var ports []model.ScannedPort
+	if err := conn.Where("site = ?", "fra1").Find(&ports).Error; err != nil || len(ports) != 1000 {
+		t.Fatalf("expected the 1000 ports of the batches, got %d: %v", len(ports), err)
+	}
+	for _, port := range ports {
+		if port.State != model.PortClosed || port.FirstSeenAt == nil || !port.FirstSeenAt.Equal(scannedAt) || port.LastClosedAt == nil || !port.LastClosedAt.Equal(scannedAt.Add(time.Hour)) {
+			t.Fatalf("expected the port to be first seen with the first batch and closed with the second, got %+v", port)
+		}
+	}
+
+	var changes int
+	if conn.Model(&model.PortStateChange{}).Where("site = ?", "fra1").Count(&changes); changes != 2000 {
+		t.Errorf("expected the 2000 changes of the batches, got %d", changes)
+	}
+}
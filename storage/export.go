@@ -0,0 +1,457 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+/* The export is a stream of json lines, one record per line: {"type": "chassis", "data": {...}}
+
+The first record is the header with the version of the format, assets are written with their components nested
+in them and the other tables one row per line. Bump ExportFormat whenever a record changes in a way the previous
+imports can't read, Import refuses formats newer than the one it knows
+
+*/
+
+// ExportFormat is the version of the export format written by Export
+const ExportFormat = 2
+
+// exportBatch is how many rows are read or written at once
+const exportBatch = 500
+
+// Record types of the export
+const (
+	recordHeader            = "header"
+	recordChassis           = "chassis"
+	recordBlade             = "blade"
+	recordDiscrete          = "discrete"
+	recordScannedPort       = "scanned_port"
+	recordCollectionAttempt = "collection_attempt"
+	recordReading           = "reading"
+	recordAssetChange       = "asset_change"
+	recordPortStateChange   = "port_state_change"
+)
+
+type exportRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type exportHeader struct {
+	Format     int       `json:"format"`
+	Schema     int       `json:"schema"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// The models hide their relationships and foreign keys from the api, the records bring them back
+
+type chassisRecord struct {
+	model.Chassis
+	Blades        []*bladeRecord        `json:"blades"`
+	StorageBlades []*storageBladeRecord `json:"storage_blades"`
+	Nics          []*model.Nic          `json:"nics"`
+	Psus          []*model.Psu          `json:"psus"`
+	Fans          []*model.Fan          `json:"fans"`
+}
+
+type bladeRecord struct {
+	model.Blade
+	ChassisSerial string        `json:"chassis_serial"`
+	Nics          []*model.Nic  `json:"nics"`
+	Disks         []*model.Disk `json:"disks"`
+}
+
+type storageBladeRecord struct {
+	model.StorageBlade
+	BladeSerial string `json:"blade_serial"`
+}
+
+type discreteRecord struct {
+	model.Discrete
+	Nics  []*model.Nic  `json:"nics"`
+	Disks []*model.Disk `json:"disks"`
+	Psus  []*model.Psu  `json:"psus"`
+}
+
+// unscoped makes preloads see the components marked as removed too
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// exporter writes records to an export and counts them by type
+type exporter struct {
+	encoder *json.Encoder
+	counts  map[string]int
+}
+
+func newExporter(w io.Writer) *exporter {
+	return &exporter{encoder: json.NewEncoder(w), counts: make(map[string]int)}
+}
+
+func (e *exporter) encode(recordType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return e.encoder.Encode(exportRecord{Type: recordType, Data: raw})
+}
+
+func (e *exporter) write(recordType string, data interface{}) error {
+	e.counts[recordType]++
+	return e.encode(recordType, data)
+}
+
+// header writes the first record of the export
+func (e *exporter) header(db *gorm.DB) error {
+	schema, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	return e.encode(recordHeader, exportHeader{Format: ExportFormat, Schema: schema, ExportedAt: time.Now()})
+}
+
+// chassis writes the chassis matched by q with all their components
+func (e *exporter) chassis(q *gorm.DB) (err error) {
+	for offset := 0; ; offset += exportBatch {
+		var chassis []*model.Chassis
+		err = q.Order("serial").Limit(exportBatch).Offset(offset).
+			Preload("Blades", unscoped).Preload("Blades.Nics", unscoped).Preload("Blades.Disks", unscoped).
+			Preload("StorageBlades", unscoped).Preload("Nics", unscoped).Preload("Psus", unscoped).Preload("Fans", unscoped).
+			Find(&chassis).Error
+		if err != nil {
+			return err
+		}
+
+		for _, c := range chassis {
+			if err = e.write(recordChassis, newChassisRecord(c)); err != nil {
+				return err
+			}
+		}
+
+		if len(chassis) < exportBatch {
+			return nil
+		}
+	}
+}
+
+// blades writes the blades matched by q, removed ones included, with their nics and disks
+func (e *exporter) blades(q *gorm.DB) (err error) {
+	for offset := 0; ; offset += exportBatch {
+		var blades []*model.Blade
+		err = q.Unscoped().Order("serial").Limit(exportBatch).Offset(offset).
+			Preload("Nics", unscoped).Preload("Disks", unscoped).
+			Find(&blades).Error
+		if err != nil {
+			return err
+		}
+
+		for _, b := range blades {
+			if err = e.write(recordBlade, newBladeRecord(b)); err != nil {
+				return err
+			}
+		}
+
+		if len(blades) < exportBatch {
+			return nil
+		}
+	}
+}
+
+// discretes writes the discretes matched by q with all their components
+func (e *exporter) discretes(q *gorm.DB) (err error) {
+	for offset := 0; ; offset += exportBatch {
+		var discretes []*model.Discrete
+		err = q.Order("serial").Limit(exportBatch).Offset(offset).
+			Preload("Nics", unscoped).Preload("Disks", unscoped).Preload("Psus", unscoped).
+			Find(&discretes).Error
+		if err != nil {
+			return err
+		}
+
+		for _, d := range discretes {
+			if err = e.write(recordDiscrete, &discreteRecord{Discrete: *d, Nics: d.Nics, Disks: d.Disks, Psus: d.Psus}); err != nil {
+				return err
+			}
+		}
+
+		if len(discretes) < exportBatch {
+			return nil
+		}
+	}
+}
+
+// table streams the rows matched by q one record each, value returns an empty row of the table
+func (e *exporter) table(q *gorm.DB, recordType string, value func() interface{}) (err error) {
+	rows, err := q.Model(value()).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := value()
+		if err = q.ScanRows(rows, row); err != nil {
+			return err
+		}
+		if err = e.write(recordType, row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func newScannedPort() interface{}       { return &model.ScannedPort{} }
+func newCollectionAttempt() interface{} { return &model.CollectionAttempt{} }
+func newReading() interface{}           { return &model.Reading{} }
+func newAssetChange() interface{}       { return &model.AssetChange{} }
+func newPortStateChange() interface{}   { return &model.PortStateChange{} }
+
+// Export writes the whole inventory to w and returns how many records of each type it wrote
+func Export(db *gorm.DB, w io.Writer) (counts map[string]int, err error) {
+	e := newExporter(w)
+	if err = e.header(db); err != nil {
+		return e.counts, err
+	}
+
+	if err = e.chassis(db); err != nil {
+		return e.counts, err
+	}
+
+	// blades collected on their own don't always have their chassis stored
+	if err = e.blades(db.Where("chassis_serial = '' or chassis_serial is null or chassis_serial not in (select serial from chassis)")); err != nil {
+		return e.counts, err
+	}
+
+	if err = e.discretes(db); err != nil {
+		return e.counts, err
+	}
+
+	if err = e.table(db, recordScannedPort, newScannedPort); err != nil {
+		return e.counts, err
+	}
+	if err = e.table(db, recordCollectionAttempt, newCollectionAttempt); err != nil {
+		return e.counts, err
+	}
+	if err = e.table(db, recordReading, newReading); err != nil {
+		return e.counts, err
+	}
+	if err = e.table(db, recordAssetChange, newAssetChange); err != nil {
+		return e.counts, err
+	}
+	return e.counts, e.table(db, recordPortStateChange, newPortStateChange)
+}
+
+func newChassisRecord(c *model.Chassis) *chassisRecord {
+	record := &chassisRecord{Chassis: *c, Nics: c.Nics, Psus: c.Psus, Fans: c.Fans}
+	for _, b := range c.Blades {
+		record.Blades = append(record.Blades, newBladeRecord(b))
+	}
+	for _, s := range c.StorageBlades {
+		record.StorageBlades = append(record.StorageBlades, &storageBladeRecord{StorageBlade: *s, BladeSerial: s.BladeSerial})
+	}
+	return record
+}
+
+func newBladeRecord(b *model.Blade) *bladeRecord {
+	return &bladeRecord{Blade: *b, ChassisSerial: b.ChassisSerial, Nics: b.Nics, Disks: b.Disks}
+}
+
+// Import reads an export from r into the database and returns how many records of each type it restored. Records
+// replace the rows with the same keys, the rest of the database is left alone
+func Import(db *gorm.DB, r io.Reader) (counts map[string]int, err error) {
+	counts = make(map[string]int)
+	decoder := json.NewDecoder(r)
+
+	var header exportHeader
+	var record exportRecord
+	if err = decoder.Decode(&record); err != nil {
+		return counts, fmt.Errorf("reading the header: %s", err)
+	}
+	if record.Type != recordHeader {
+		return counts, fmt.Errorf("expected the header as first record, got %s", record.Type)
+	}
+	if err = json.Unmarshal(record.Data, &header); err != nil {
+		return counts, err
+	}
+	if header.Format > ExportFormat {
+		return counts, fmt.Errorf("export format %d is newer than the %d we know, use a newer dora", header.Format, ExportFormat)
+	}
+
+	// line of the first record of the batch, the header is line 1
+	line := 2
+	batch := make([]exportRecord, 0, exportBatch)
+	flush := func() error {
+		err := transaction(db, func(tx *gorm.DB) error {
+			for i, record := range batch {
+				if err := importRecord(tx, record); err != nil {
+					return fmt.Errorf("line %d, %s record: %s", line+i, record.Type, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, record := range batch {
+			counts[record.Type]++
+		}
+		line += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		var record exportRecord
+		if err = decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return counts, err
+		}
+
+		if batch = append(batch, record); len(batch) == exportBatch {
+			if err = flush(); err != nil {
+				return counts, err
+			}
+		}
+	}
+
+	return counts, flush()
+}
+
+// importRecord restores the rows of a record
+func importRecord(tx *gorm.DB, record exportRecord) (err error) {
+	var rows []interface{}
+
+	switch record.Type {
+	case recordChassis:
+		var c chassisRecord
+		if err = json.Unmarshal(record.Data, &c); err != nil {
+			return err
+		}
+
+		rows = append(rows, &c.Chassis)
+		for _, b := range c.Blades {
+			b.ChassisSerial = c.Serial
+			rows = append(rows, bladeRows(b)...)
+		}
+		for _, s := range c.StorageBlades {
+			s.StorageBlade.ChassisSerial, s.StorageBlade.BladeSerial = c.Serial, s.BladeSerial
+			rows = append(rows, &s.StorageBlade)
+		}
+		for _, n := range c.Nics {
+			n.ChassisSerial = c.Serial
+			rows = append(rows, n)
+		}
+		for _, p := range c.Psus {
+			p.ChassisSerial = c.Serial
+			rows = append(rows, p)
+		}
+		for _, f := range c.Fans {
+			f.ChassisSerial = c.Serial
+			rows = append(rows, f)
+		}
+	case recordBlade:
+		var b bladeRecord
+		if err = json.Unmarshal(record.Data, &b); err != nil {
+			return err
+		}
+		rows = bladeRows(&b)
+	case recordDiscrete:
+		var d discreteRecord
+		if err = json.Unmarshal(record.Data, &d); err != nil {
+			return err
+		}
+
+		rows = append(rows, &d.Discrete)
+		for _, n := range d.Nics {
+			n.DiscreteSerial = d.Serial
+			rows = append(rows, n)
+		}
+		// model.Discrete links its disks through blade_serial
+		for _, disk := range d.Disks {
+			disk.BladeSerial = d.Serial
+			rows = append(rows, disk)
+		}
+		for _, p := range d.Psus {
+			p.DiscreteSerial = d.Serial
+			rows = append(rows, p)
+		}
+	case recordScannedPort:
+		port := &model.ScannedPort{}
+		if err = json.Unmarshal(record.Data, port); err != nil {
+			return err
+		}
+		port.ID = port.GenID()
+		rows = append(rows, port)
+	case recordCollectionAttempt:
+		attempt := &model.CollectionAttempt{}
+		if err = json.Unmarshal(record.Data, attempt); err != nil {
+			return err
+		}
+		attempt.ID = attempt.GenID()
+		rows = append(rows, attempt)
+	case recordReading:
+		reading := &model.Reading{}
+		if err = json.Unmarshal(record.Data, reading); err != nil {
+			return err
+		}
+		reading.ID = reading.GenID()
+		rows = append(rows, reading)
+	case recordAssetChange:
+		change := &model.AssetChange{}
+		if err = json.Unmarshal(record.Data, change); err != nil {
+			return err
+		}
+		change.ID = change.GenID()
+		rows = append(rows, change)
+	case recordPortStateChange:
+		change := &model.PortStateChange{}
+		if err = json.Unmarshal(record.Data, change); err != nil {
+			return err
+		}
+		change.ID = change.GenID()
+		rows = append(rows, change)
+	default:
+		return fmt.Errorf("unknown record type")
+	}
+
+	for _, row := range rows {
+		if err = restore(tx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bladeRows(b *bladeRecord) (rows []interface{}) {
+	b.Blade.ChassisSerial = b.ChassisSerial
+	rows = append(rows, &b.Blade)
+	for _, n := range b.Nics {
+		n.BladeSerial = b.Serial
+		rows = append(rows, n)
+	}
+	for _, d := range b.Disks {
+		d.BladeSerial = b.Serial
+		rows = append(rows, d)
+	}
+	return rows
+}
+
+// restore replaces the row with the same primary key by value, as it is in the export
+func restore(tx *gorm.DB, value interface{}) (err error) {
+	if tx.NewScope(value).PrimaryKeyZero() {
+		return fmt.Errorf("%T without primary key", value)
+	}
+
+	if err = tx.Unscoped().Delete(value).Error; err != nil {
+		return err
+	}
+	return tx.Set("gorm:save_associations", false).Set(model.Restoring, true).Create(value).Error
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestExportImport(t *testing.T) {
+	source, cleanup := sqliteDB(t)
+	defer cleanup()
+	if _, err := MigrateUp(source); err != nil {
+		t.Fatal(err)
+	}
+
+	chassis := &model.Chassis{
+		Serial:      "ez3605020d",
+		Vendor:      "HP",
+		FaultySlots: model.Slots{7},
+		Blades: []*model.Blade{
+			{Serial: "cz3605020e", ChassisSerial: "ez3605020d", BladePosition: 1, Nics: []*model.Nic{{MacAddress: "a0:36:9f:00:00:01", BladeSerial: "cz3605020e"}}},
+		},
+		StorageBlades: []*model.StorageBlade{{Serial: "sb1", ChassisSerial: "ez3605020d", BladeSerial: "cz3605020e", BladePosition: 2}},
+		Psus:          []*model.Psu{{Serial: "psu1", ChassisSerial: "ez3605020d"}, {Serial: "psu2", ChassisSerial: "ez3605020d"}},
+	}
+	if _, err := NewChassisStorage(source).ApplySnapshot(chassis); err != nil {
+		t.Fatal(err)
+	}
+
+	// a removed psu travels with its removed_at
+	chassis.Psus = chassis.Psus[:1]
+	if _, err := NewChassisStorage(source).ApplySnapshot(chassis); err != nil {
+		t.Fatal(err)
+	}
+
+	discrete := &model.Discrete{Serial: "s34900710912345", DataSource: "ipmi", Disks: []*model.Disk{{Serial: "disk1"}}}
+	if _, err := NewDiscreteStorage(source).ApplySnapshot(discrete); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, value := range []interface{}{
+		&model.Blade{Serial: "orphan", ChassisSerial: "unknown"},
+		&model.CollectionAttempt{IP: "10.0.0.1", StartedAt: now, FinishedAt: now, Outcome: model.CollectionSucceeded},
+		&model.Reading{AssetSerial: "ez3605020d", AssetType: "chassis", Metric: model.MetricTempC, Value: 21, Samples: 1, CollectedAt: now},
+		&model.AssetChange{AssetType: "chassis", AssetSerial: "ez3605020d", ChangedAt: now, Changes: model.FieldChanges{{Field: "psus[psu2]", Before: "psu2"}}},
+	} {
+		if err := source.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	port := &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 623, Protocol: "ipmi", ScannedBy: "test", State: model.PortOpen}
+	if _, err := NewScannedPortStorage(source).Record(port, now); err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	counts, err := Export(source, &exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"chassis": 1, "blade": 1, "discrete": 1, "scanned_port": 1, "collection_attempt": 1, "reading": 1, "asset_change": 1, "port_state_change": 1}
+	for recordType, count := range expected {
+		if counts[recordType] != count {
+			t.Errorf("expected %d %s records exported, got %d", count, recordType, counts[recordType])
+		}
+	}
+
+	target, cleanupTarget := sqliteDB(t)
+	defer cleanupTarget()
+	if _, err = MigrateUp(target); err != nil {
+		t.Fatal(err)
+	}
+
+	// importing twice must replace the rows instead of failing on them
+	for i := 0; i < 2; i++ {
+		if counts, err = Import(target, bytes.NewReader(exported.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts["chassis"] != 1 || counts["reading"] != 1 {
+		t.Errorf("unexpected import counts %v", counts)
+	}
+
+	var reexported bytes.Buffer
+	if _, err = Export(target, &reexported); err != nil {
+		t.Fatal(err)
+	}
+
+	// everything but the header must come back the same
+	before, after := strings.SplitN(exported.String(), "\n", 2), strings.SplitN(reexported.String(), "\n", 2)
+	if before[1] != after[1] {
+		t.Errorf("the import doesn't match the export:\n%s\n%s", before[1], after[1])
+	}
+
+	var psu model.Psu
+	if err = target.Unscoped().Where("serial = ?", "psu2").First(&psu).Error; err != nil || psu.DeletedAt == nil || psu.ChassisSerial != "ez3605020d" {
+		t.Errorf("expected psu2 to be imported as removed, got %+v: %v", psu, err)
+	}
+
+	var storageBlade model.StorageBlade
+	if err = target.Where("serial = ?", "sb1").First(&storageBlade).Error; err != nil || storageBlade.BladeSerial != "cz3605020e" {
+		t.Errorf("expected the storage blade to keep its blade, got %+v: %v", storageBlade, err)
+	}
+
+	if _, err = Import(target, strings.NewReader(`{"type":"header","data":{"format":99}}`)); err == nil {
+		t.Error("expected a newer format to be refused")
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/hashicorp/go-multierror"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+// RemovalRefusedError is returned when the removal guard kept components a collection didn't report anymore,
+// everything else of the snapshot is stored
+type RemovalRefusedError struct {
+	Serial   string
+	Reasons  []string
+	Removals []model.PendingRemoval
+}
+
+func (e *RemovalRefusedError) Error() string {
+	return fmt.Sprintf("refused to remove components of %s: %s", e.Serial, strings.Join(e.Reasons, ", "))
+}
+
+// Refused tells whether the removal of the components of the parent was refused, they are still stored
+func (e *RemovalRefusedError) Refused(parent string, component string) bool {
+	for _, removal := range e.Removals {
+		if removal.ParentSerial == parent && removal.Component == component {
+			return true
+		}
+	}
+	return false
+}
+
+// refusal is what guardRemoval returns for each rule that trips
+type refusal struct {
+	removal model.PendingRemoval
+	reason  string
+}
+
+func (r refusal) Error() string {
+	return r.reason
+}
+
+// guardRemoval decides whether removing components of a parent looks like a broken collection rather than hardware
+// going away. It refuses dropping to no components at all and, for parents with at least min_components, removing
+// more than max_percent of them. A refused removal goes through when the next collection reports exactly the same
+func guardRemoval(db *gorm.DB, parent string, component string, reported int, removing int) (err error) {
+	pending := model.PendingRemoval{ParentSerial: parent, Component: component}
+	pending.ID = pending.GenID()
+
+	var reason string
+	if viper.GetBool("collector.removal_guard.enabled") && removing > 0 {
+		total := reported + removing
+		if reported == 0 {
+			reason = fmt.Sprintf("%s would drop from %d to none", component, removing)
+		} else if total >= viper.GetInt("collector.removal_guard.min_components") && removing*100 > viper.GetInt("collector.removal_guard.max_percent")*total {
+			reason = fmt.Sprintf("%s would lose %d of %d", component, removing, total)
+		}
+	}
+
+	previous := model.PendingRemoval{}
+	err = db.Where("id = ?", pending.ID).First(&previous).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	found := err == nil
+
+	if reason == "" || (found && previous.Removing == removing) {
+		if found {
+			return db.Delete(&previous).Error
+		}
+		return nil
+	}
+
+	pending.Removing = removing
+	pending.RefusedAt = time.Now()
+	if err = db.Save(&pending).Error; err != nil {
+		return err
+	}
+
+	return refusal{removal: pending, reason: reason}
+}
+
+// splitRefusals separates the removals the guard refused from the actual errors, the former must not roll back the snapshot
+func splitRefusals(serial string, err error) (refused error, other error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	errs := []error{err}
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	}
+
+	var reasons []string
+	var removals []model.PendingRemoval
+	var merror *multierror.Error
+	for _, e := range errs {
+		if r, ok := e.(refusal); ok {
+			reasons = append(reasons, r.reason)
+			removals = append(removals, r.removal)
+		} else {
+			merror = multierror.Append(merror, e)
+		}
+	}
+
+	if len(reasons) > 0 {
+		refused = &RemovalRefusedError{Serial: serial, Reasons: reasons, Removals: removals}
+	}
+	return refused, merror.ErrorOrNil()
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// migrationLock is the key of the advisory lock taken while migrating, so workers starting at once don't race
+const migrationLock = 4242
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+	Version   int       `gorm:"primary_key;auto_increment:false" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName keeps the name every migration tool uses, it's not one of our models
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus tells whether a migration was applied to the database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// SchemaOutdatedError is returned when the database misses migrations this binary knows about
+type SchemaOutdatedError struct {
+	Current int
+	Latest  int
+}
+
+func (e *SchemaOutdatedError) Error() string {
+	return fmt.Sprintf("database schema is at version %d, this binary needs %d: run dora db migrate up", e.Current, e.Latest)
+}
+
+// SchemaTooNewError is returned when the database was migrated by a newer binary
+type SchemaTooNewError struct {
+	Current int
+	Latest  int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema is at version %d, this binary only knows up to %d", e.Current, e.Latest)
+}
+
+// migration is a versioned change of the schema, up and down run inside a transaction where the dialect allows it
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+	down    func(tx *gorm.DB) error
+}
+
+// LatestVersion returns the version of the last migration this binary knows about
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the version of the last migration applied to the database, 0 when there's none
+func SchemaVersion(db *gorm.DB) (version int, err error) {
+	if !db.HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+
+	row := db.Model(&SchemaMigration{}).Select("coalesce(max(version), 0)").Row()
+	if err = row.Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// CheckSchema makes sure the database is exactly at the version this binary expects
+func CheckSchema(db *gorm.DB) (err error) {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest := LatestVersion()
+	if current < latest {
+		return &SchemaOutdatedError{Current: current, Latest: latest}
+	} else if current > latest {
+		return &SchemaTooNewError{Current: current, Latest: latest}
+	}
+	return nil
+}
+
+// MigrateUp applies all the pending migrations in order and returns the ones it applied
+func MigrateUp(db *gorm.DB) (applied []string, err error) {
+	err = locked(db, func(tx *gorm.DB) error {
+		done, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.version]; ok {
+				continue
+			}
+
+			if err = m.up(tx); err != nil {
+				return fmt.Errorf("migration %d %s: %s", m.version, m.name, err)
+			}
+
+			if err = tx.Create(&SchemaMigration{Version: m.version, Name: m.name, AppliedAt: time.Now()}).Error; err != nil {
+				return err
+			}
+			applied = append(applied, fmt.Sprintf("%d %s", m.version, m.name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the last applied migration and returns it, it returns an empty string when there's nothing to revert
+func MigrateDown(db *gorm.DB) (reverted string, err error) {
+	err = locked(db, func(tx *gorm.DB) error {
+		done, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if _, ok := done[m.version]; !ok {
+				continue
+			}
+
+			if err = m.down(tx); err != nil {
+				return fmt.Errorf("migration %d %s: %s", m.version, m.name, err)
+			}
+
+			if err = tx.Delete(&SchemaMigration{}, "version = ?", m.version).Error; err != nil {
+				return err
+			}
+			reverted = fmt.Sprintf("%d %s", m.version, m.name)
+			return nil
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Migrations returns every migration this binary knows about and whether it was applied
+func Migrations(db *gorm.DB) (status []MigrationStatus, err error) {
+	done := make(map[int]SchemaMigration)
+	if db.HasTable(&SchemaMigration{}) {
+		if done, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.version, Name: m.name}
+		if applied, ok := done[m.version]; ok {
+			s.Applied, s.AppliedAt = true, applied.AppliedAt
+		}
+		status = append(status, s)
+	}
+
+	// migrations applied by a newer binary are listed too, we can't revert them
+	var unknown []int
+	for version := range done {
+		if version > LatestVersion() {
+			unknown = append(unknown, version)
+		}
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		status = append(status, MigrationStatus{Version: version, Name: done[version].Name, Applied: true, AppliedAt: done[version].AppliedAt})
+	}
+
+	return status, nil
+}
+
+// appliedMigrations returns the migrations recorded in the database by version
+func appliedMigrations(db *gorm.DB) (done map[int]SchemaMigration, err error) {
+	var rows []SchemaMigration
+	if err = db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	done = make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+// locked runs fn in a transaction holding the migration lock. Postgres releases its lock with the transaction,
+// mysql needs it released by hand and commits the ddl implicitly, sqlite locks the whole database on the first write
+func locked(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return transaction(db, func(tx *gorm.DB) (err error) {
+		switch tx.Dialect().GetName() {
+		case "postgres":
+			if err = tx.Exec("select pg_advisory_xact_lock(?)", migrationLock).Error; err != nil {
+				return err
+			}
+		case "mysql":
+			var got int
+			if err = tx.Raw("select get_lock(?, 60)", fmt.Sprint(migrationLock)).Row().Scan(&got); err != nil {
+				return err
+			}
+			if got != 1 {
+				return fmt.Errorf("timed out waiting for the migration lock")
+			}
+			defer tx.Exec("select release_lock(?)", fmt.Sprint(migrationLock))
+		}
+
+		if err = tx.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestMigrations(t *testing.T) {
+	conn, cleanup := sqliteDB(t)
+	defer cleanup()
+
+	if err := CheckSchema(conn); err == nil {
+		t.Fatal("an empty database must be reported as outdated")
+	}
+
+	applied, err := MigrateUp(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != LatestVersion() {
+		t.Errorf("expected %d migrations applied, got %v", LatestVersion(), applied)
+	}
+	if err = CheckSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if applied, err = MigrateUp(conn); err != nil || len(applied) != 0 {
+		t.Fatalf("a second run must not apply anything, got %v: %v", applied, err)
+	}
+
+	psu := &model.Psu{Serial: "psu1", Status: "OK", DiscreteSerial: "discrete1"}
+	if err = conn.Create(psu).Error; err != nil {
+		t.Fatal(err)
+	}
+	port := &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: model.PortOpen}
+	if err = conn.Create(port).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// reverting down to the columns a component had at the start must keep its rows
+	for version := LatestVersion(); version > 5; version-- {
+		if _, err = MigrateDown(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conn.Dialect().HasColumn("psu", "removed_at") || conn.Dialect().HasColumn("psu", "last_seen_at") {
+		t.Error("expected the removal columns to be dropped")
+	}
+
+	var status string
+	if err = conn.Table("psu").Where("serial = ?", "psu1").Select("status").Row().Scan(&status); err != nil || status != "OK" {
+		t.Errorf("expected the psu to survive the migration, got %q: %v", status, err)
+	}
+
+	if current, _ := SchemaVersion(conn); current != 5 {
+		t.Errorf("expected version 5, got %d", current)
+	}
+	if _, ok := CheckSchema(conn).(*SchemaOutdatedError); !ok {
+		t.Error("expected the schema to be outdated")
+	}
+
+	// the ports scanned before the state was tracked start from their last scan
+	if _, err = MigrateUp(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Where("id = ?", port.GenID()).First(port).Error; err != nil {
+		t.Fatal(err)
+	}
+	if port.FirstSeenAt == nil || !port.FirstSeenAt.Equal(port.UpdatedAt) || port.LastOpenAt == nil || port.LastClosedAt != nil {
+		t.Errorf("expected the open port to be first and last seen open on its last scan, got %+v", port)
+	}
+
+	for version := LatestVersion(); version > 0; version-- {
+		if _, err = MigrateDown(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reverted, err := MigrateDown(conn); err != nil || reverted != "" {
+		t.Errorf("expected nothing left to revert, got %q: %v", reverted, err)
+	}
+	if conn.HasTable("blade") {
+		t.Error("expected the inventory to be dropped")
+	}
+
+	if _, err = MigrateUp(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,462 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* READ THIS BEFORE ADDING A MIGRATION
+
+Migrations are applied in order and never change once released: add a new one at the end instead of editing
+an existing one. They use their own copy of the structs as they were at that version, the models keep changing
+and the old migrations must create the same schema they always did. The first migrations use AutoMigrate, so a
+database created before we had versions is picked up as it is.
+
+*/
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_inventory",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&bladeV1{}, &discreteV1{}, &chassisV1{}, &nicV1{}, &storageBladeV1{}, &scannedPortV1{}, &psuV1{}, &diskV1{}, &fanV1{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&bladeV1{}, &discreteV1{}, &chassisV1{}, &nicV1{}, &storageBladeV1{}, &scannedPortV1{}, &psuV1{}, &diskV1{}, &fanV1{}).Error
+		},
+	},
+	{
+		version: 2,
+		name:    "create_collection_attempt",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&collectionAttemptV2{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&collectionAttemptV2{}).Error
+		},
+	},
+	{
+		version: 3,
+		name:    "add_discrete_data_source",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&discreteV3{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &discreteV1{}, "data_source")
+		},
+	},
+	{
+		version: 4,
+		name:    "create_reading",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&readingV4{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&readingV4{}).Error
+		},
+	},
+	{
+		version: 5,
+		name:    "create_asset_change",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&assetChangeV5{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&assetChangeV5{}).Error
+		},
+	},
+	{
+		version: 6,
+		name:    "add_component_removal",
+		up: func(tx *gorm.DB) (err error) {
+			for _, table := range componentTablesV6 {
+				if err = tx.Table(table).AutoMigrate(&componentRemovalV6{}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(tx *gorm.DB) (err error) {
+			previous := []interface{}{&bladeV1{}, &storageBladeV1{}, &nicV1{}, &psuV1{}, &diskV1{}, &fanV1{}}
+			for i, table := range componentTablesV6 {
+				if err = tx.Table(table).RemoveIndex(fmt.Sprintf("idx_%s_removed_at", table)).Error; err != nil {
+					return err
+				}
+				if err = dropColumns(tx, previous[i], "last_seen_at", "removed_at"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 7,
+		name:    "create_pending_removal",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&pendingRemovalV7{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&pendingRemovalV7{}).Error
+		},
+	},
+	{
+		version: 8,
+		name:    "add_port_state_tracking",
+		up: func(tx *gorm.DB) (err error) {
+			if err = tx.AutoMigrate(&scannedPortV8{}, &portStateChangeV8{}).Error; err != nil {
+				return err
+			}
+
+			// the last scan is all we know of the ports scanned before
+			if err = tx.Exec("UPDATE scanned_port SET first_seen_at = updated_at, last_open_at = updated_at WHERE state = ?", "open").Error; err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE scanned_port SET last_closed_at = updated_at WHERE state = ?", "closed").Error
+		},
+		down: func(tx *gorm.DB) error {
+			if err := tx.DropTableIfExists(&portStateChangeV8{}).Error; err != nil {
+				return err
+			}
+			return dropColumns(tx, &scannedPortV1{}, "first_seen_at", "last_open_at", "last_closed_at")
+		},
+	},
+	{
+		version: 9,
+		name:    "add_collection_attempt_serial",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&collectionAttemptV9{}).Error
+		},
+		down: func(tx *gorm.DB) error {
+			if err := tx.Table("collection_attempt").RemoveIndex("idx_collection_attempt_serial").Error; err != nil {
+				return err
+			}
+			return dropColumns(tx, &collectionAttemptV2{}, "serial")
+		},
+	},
+}
+
+// dropColumns removes columns from the table of previous, which must be the struct as it was without them.
+// sqlite can't drop columns, so the table is rebuilt from previous and the remaining columns copied over
+func dropColumns(tx *gorm.DB, previous interface{}, columns ...string) (err error) {
+	scope := tx.NewScope(previous)
+	table := scope.TableName()
+
+	if tx.Dialect().GetName() != "sqlite3" {
+		for _, column := range columns {
+			if err = tx.Table(table).DropColumn(column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	old := table + "_old"
+	if err = tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", scope.Quote(table), scope.Quote(old))).Error; err != nil {
+		return err
+	}
+
+	// the indexes follow the renamed table, but their names must be free to create them again
+	var indexes []string
+	if err = tx.Table("sqlite_master").Where("type = 'index' AND tbl_name = ? AND sql IS NOT NULL", old).Pluck("name", &indexes).Error; err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err = tx.Exec(fmt.Sprintf("DROP INDEX %s", scope.Quote(index))).Error; err != nil {
+			return err
+		}
+	}
+
+	if err = tx.AutoMigrate(previous).Error; err != nil {
+		return err
+	}
+
+	var kept string
+	for _, field := range scope.Fields() {
+		if !field.IsNormal || field.IsIgnored {
+			continue
+		}
+		if kept != "" {
+			kept += ", "
+		}
+		kept += scope.Quote(field.DBName)
+	}
+
+	if err = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", scope.Quote(table), kept, kept, scope.Quote(old))).Error; err != nil {
+		return err
+	}
+	return tx.DropTable(old).Error
+}
+
+// The schema as it was when we started versioning it
+
+type bladeV1 struct {
+	Serial               string `gorm:"primary_key"`
+	Name                 string
+	BiosVersion          string
+	BmcType              string
+	BmcAddress           string
+	BmcVersion           string
+	BmcSSHReachable      bool
+	BmcWEBReachable      bool
+	BmcIpmiReachable     bool
+	BmcLicenceType       string
+	BmcLicenceStatus     string
+	BmcAuth              bool
+	BladePosition        int
+	Model                string
+	TempC                int
+	PowerKw              float64
+	PowerState           string
+	Status               string
+	Vendor               string
+	ChassisSerial        string
+	Processor            string
+	ProcessorCount       int
+	ProcessorCoreCount   int
+	ProcessorThreadCount int
+	Memory               int
+	UpdatedAt            time.Time
+}
+
+func (bladeV1) TableName() string { return "blade" }
+
+type discreteV1 struct {
+	Serial               string `gorm:"primary_key"`
+	Name                 string
+	BiosVersion          string
+	BmcType              string
+	BmcAddress           string
+	BmcVersion           string
+	BmcSSHReachable      bool
+	BmcWEBReachable      bool
+	BmcIpmiReachable     bool
+	BmcLicenceType       string
+	BmcLicenceStatus     string
+	BmcAuth              bool
+	Model                string
+	TempC                int
+	PowerKw              float64
+	PowerState           string
+	Status               string
+	Vendor               string
+	Processor            string
+	ProcessorCount       int
+	ProcessorCoreCount   int
+	ProcessorThreadCount int
+	Memory               int
+	UpdatedAt            time.Time
+}
+
+func (discreteV1) TableName() string { return "discrete" }
+
+type chassisV1 struct {
+	Serial            string `gorm:"primary_key"`
+	Name              string
+	BmcAddress        string
+	BmcSSHReachable   bool
+	BmcWEBReachable   bool
+	BmcAuth           bool
+	FaultySlots       slotsV1
+	PsuRedundancyMode string
+	IsPsuRedundant    bool
+	TempC             int
+	PassThru          string
+	Status            string
+	PowerKw           float64
+	Model             string
+	Vendor            string
+	FwVersion         string
+	UpdatedAt         time.Time
+	Managed           bool
+}
+
+func (chassisV1) TableName() string { return "chassis" }
+
+// slotsV1 is an integer array where the dialect has them, postgres databases created before we had versions
+// have it as integer[2], postgres doesn't enforce the size so they're left as they are
+type slotsV1 []int64
+
+func (slotsV1) GormDataType(dialect gorm.Dialect) string {
+	if dialect.GetName() == "postgres" {
+		return "integer[]"
+	}
+	return "varchar(255)"
+}
+
+type nicV1 struct {
+	MacAddress     string `gorm:"primary_key"`
+	Name           string
+	Speed          string
+	UpdatedAt      time.Time
+	BladeSerial    string
+	DiscreteSerial string
+	ChassisSerial  string
+}
+
+func (nicV1) TableName() string { return "nic" }
+
+type storageBladeV1 struct {
+	Serial        string `gorm:"primary_key"`
+	FwVersion     string
+	BladePosition int
+	Model         string
+	TempC         int
+	PowerKw       float64
+	Status        string
+	Vendor        string
+	ChassisSerial string
+	BladeSerial   string
+	UpdatedAt     time.Time
+}
+
+func (storageBladeV1) TableName() string { return "storage_blade" }
+
+type scannedPortV1 struct {
+	ID        string `gorm:"primary_key"`
+	Site      string `gorm:"unique_index:scanned_result"`
+	CIDR      string `gorm:"unique_index:scanned_result;column:cidr"`
+	IP        string `gorm:"unique_index:scanned_result"`
+	Port      int    `gorm:"unique_index:scanned_result"`
+	Protocol  string `gorm:"unique_index:scanned_result"`
+	ScannedBy string `gorm:"unique_index:scanned_result"`
+	State     string
+	UpdatedAt time.Time
+}
+
+func (scannedPortV1) TableName() string { return "scanned_port" }
+
+type psuV1 struct {
+	Serial         string `gorm:"primary_key"`
+	CapacityKw     float64
+	PowerKw        float64
+	Status         string
+	PartNumber     string
+	UpdatedAt      time.Time
+	DiscreteSerial string
+	ChassisSerial  string
+}
+
+func (psuV1) TableName() string { return "psu" }
+
+type diskV1 struct {
+	Serial         string `gorm:"primary_key"`
+	Status         string
+	Type           string
+	Size           string
+	Model          string
+	Location       string
+	FwVersion      string
+	UpdatedAt      time.Time
+	BladeSerial    string
+	DiscreteSerial string
+}
+
+func (diskV1) TableName() string { return "disk" }
+
+type fanV1 struct {
+	Serial        string `gorm:"primary_key"`
+	Status        string
+	Position      int
+	Model         string
+	CurrentRPM    int64
+	PowerKw       float64
+	ChassisSerial string
+	UpdatedAt     time.Time
+}
+
+func (fanV1) TableName() string { return "fan" }
+
+type collectionAttemptV2 struct {
+	ID           string `gorm:"primary_key"`
+	IP           string `gorm:"index"`
+	Site         string `gorm:"index"`
+	Source       string
+	StartedAt    time.Time `gorm:"index"`
+	FinishedAt   time.Time
+	Outcome      string `gorm:"index"`
+	Error        string `gorm:"type:text"`
+	Vendor       string
+	HardwareType string
+	Credential   string
+}
+
+func (collectionAttemptV2) TableName() string { return "collection_attempt" }
+
+type discreteV3 struct {
+	DataSource string
+}
+
+func (discreteV3) TableName() string { return "discrete" }
+
+type readingV4 struct {
+	ID          string `gorm:"primary_key"`
+	AssetSerial string `gorm:"index"`
+	AssetType   string `gorm:"index"`
+	Metric      string `gorm:"index"`
+	Value       float64
+	Samples     int
+	Resolution  int       `gorm:"index"`
+	CollectedAt time.Time `gorm:"index"`
+}
+
+func (readingV4) TableName() string { return "reading" }
+
+type assetChangeV5 struct {
+	ID          string `gorm:"primary_key"`
+	AssetType   string `gorm:"index"`
+	AssetSerial string `gorm:"index"`
+	Source      string
+	ChangedAt   time.Time `gorm:"index"`
+	Changes     string    `gorm:"type:text"`
+}
+
+func (assetChangeV5) TableName() string { return "asset_change" }
+
+var componentTablesV6 = []string{"blade", "storage_blade", "nic", "psu", "disk", "fan"}
+
+type componentRemovalV6 struct {
+	LastSeenAt time.Time
+	RemovedAt  *time.Time `gorm:"index"`
+}
+
+type pendingRemovalV7 struct {
+	ID           string `gorm:"primary_key"`
+	ParentSerial string `gorm:"index"`
+	Component    string
+	Removing     int
+	RefusedAt    time.Time
+}
+
+func (pendingRemovalV7) TableName() string { return "pending_removal" }
+
+type scannedPortV8 struct {
+	FirstSeenAt  *time.Time
+	LastOpenAt   *time.Time
+	LastClosedAt *time.Time
+}
+
+func (scannedPortV8) TableName() string { return "scanned_port" }
+
+type portStateChangeV8 struct {
+	ID            string `gorm:"primary_key"`
+	ScannedPortID string `gorm:"index"`
+	Site          string `gorm:"index"`
+	CIDR          string `gorm:"column:cidr"`
+	IP            string `gorm:"index"`
+	Port          int
+	Protocol      string
+	ScannedBy     string
+	PreviousState string
+	State         string    `gorm:"index"`
+	ChangedAt     time.Time `gorm:"index"`
+}
+
+func (portStateChangeV8) TableName() string { return "port_state_change" }
+
+type collectionAttemptV9 struct {
+	Serial string `gorm:"index"`
+}
+
+func (collectionAttemptV9) TableName() string { return "collection_attempt" }
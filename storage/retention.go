@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// Resources the retention policies apply to
+const (
+	RetentionScannedPorts = "scanned_ports"
+	RetentionChassis      = "chassis"
+	RetentionBlades       = "blades"
+	RetentionDiscretes    = "discretes"
+)
+
+// RetentionResources lists the resources in the order their policies are applied, chassis go before their blades
+var RetentionResources = []string{RetentionScannedPorts, RetentionChassis, RetentionBlades, RetentionDiscretes}
+
+// retentionTables maps the resources to their tables and primary keys
+var retentionTables = map[string]struct{ table, key string }{
+	RetentionScannedPorts: {"scanned_port", "id"},
+	RetentionChassis:      {"chassis", "serial"},
+	RetentionBlades:       {"blade", "serial"},
+	RetentionDiscretes:    {"discrete", "serial"},
+}
+
+// StaleCount is how many rows of a resource weren't updated since a given time for a site and vendor
+type StaleCount struct {
+	Site   string
+	Vendor string
+	Total  int
+}
+
+// CountStale returns how many rows of the resource weren't updated since before, by site and vendor. Assets
+// don't know their site, it's the one where their bmc address was scanned
+func CountStale(db *gorm.DB, resource string, before time.Time) (counts []StaleCount, err error) {
+	t, ok := retentionTables[resource]
+	if !ok {
+		return counts, fmt.Errorf("unknown resource %s", resource)
+	}
+
+	if resource == RetentionScannedPorts {
+		err = db.Raw("SELECT site, '' AS vendor, count(*) AS total FROM scanned_port WHERE updated_at < ? GROUP BY site ORDER BY site", before).Scan(&counts).Error
+		return counts, err
+	}
+
+	err = db.Raw(fmt.Sprintf(`SELECT coalesce(sp.site, '') AS site, a.vendor AS vendor, count(*) AS total
+		FROM %s a LEFT JOIN (SELECT ip, min(site) AS site FROM scanned_port GROUP BY ip) sp ON sp.ip = a.bmc_address
+		WHERE a.updated_at < ? GROUP BY coalesce(sp.site, ''), a.vendor ORDER BY 1, 2`, t.table), before).Scan(&counts).Error
+	return counts, err
+}
+
+// PurgeStale deletes the rows of the resource that weren't updated since before, assets go with all their
+// components and scanned ports with their state changes. When archive isn't nil they are written to it first in the format of Export, so dora import can
+// bring them back. It returns how many rows of the resource it deleted
+func PurgeStale(db *gorm.DB, resource string, before time.Time, archive io.Writer) (purged int, err error) {
+	t, ok := retentionTables[resource]
+	if !ok {
+		return purged, fmt.Errorf("unknown resource %s", resource)
+	}
+
+	var e *exporter
+	if archive != nil {
+		e = newExporter(archive)
+		if err = e.header(db); err != nil {
+			return purged, err
+		}
+	}
+
+	for {
+		var keys []string
+		if err = db.Table(t.table).Where("updated_at < ?", before).Order(t.key).Limit(exportBatch).Pluck(t.key, &keys).Error; err != nil {
+			return purged, err
+		}
+		if len(keys) == 0 {
+			return purged, nil
+		}
+
+		if e != nil {
+			if err = archiveStale(db, e, resource, keys); err != nil {
+				return purged, err
+			}
+		}
+
+		if err = transaction(db, func(tx *gorm.DB) error { return deleteStale(tx.Unscoped(), resource, keys) }); err != nil {
+			return purged, err
+		}
+		purged += len(keys)
+	}
+}
+
+// archiveStale writes the rows about to be purged to the archive
+func archiveStale(db *gorm.DB, e *exporter, resource string, keys []string) error {
+	switch resource {
+	case RetentionScannedPorts:
+		if err := e.table(db.Where("id in (?)", keys), recordScannedPort, newScannedPort); err != nil {
+			return err
+		}
+		return e.table(db.Where("scanned_port_id in (?)", keys), recordPortStateChange, newPortStateChange)
+	case RetentionChassis:
+		return e.chassis(db.Where("serial in (?)", keys))
+	case RetentionBlades:
+		return e.blades(db.Where("serial in (?)", keys))
+	default:
+		return e.discretes(db.Where("serial in (?)", keys))
+	}
+}
+
+// deleteStale deletes the rows with their components, tx must be unscoped to delete the removed components for good
+func deleteStale(tx *gorm.DB, resource string, keys []string) (err error) {
+	var components []interface{}
+	var column string
+
+	switch resource {
+	case RetentionScannedPorts:
+		if err = tx.Where("scanned_port_id in (?)", keys).Delete(&model.PortStateChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id in (?)", keys).Delete(&model.ScannedPort{}).Error
+	case RetentionChassis:
+		var blades []string
+		if err = tx.Model(&model.Blade{}).Where("chassis_serial in (?)", keys).Pluck("serial", &blades).Error; err != nil {
+			return err
+		}
+		if len(blades) > 0 {
+			if err = deleteStale(tx, RetentionBlades, blades); err != nil {
+				return err
+			}
+		}
+		components, column = []interface{}{&model.StorageBlade{}, &model.Nic{}, &model.Psu{}, &model.Fan{}, &model.Chassis{}}, "chassis_serial"
+	case RetentionBlades:
+		components, column = []interface{}{&model.Nic{}, &model.Disk{}, &model.Blade{}}, "blade_serial"
+	default:
+		// model.Discrete links its disks through blade_serial
+		if err = tx.Where("blade_serial in (?) or discrete_serial in (?)", keys, keys).Delete(&model.Disk{}).Error; err != nil {
+			return err
+		}
+		components, column = []interface{}{&model.Nic{}, &model.Psu{}, &model.Discrete{}}, "discrete_serial"
+	}
+
+	// the asset itself is the last one and is found by its serial
+	for i, component := range components {
+		where := column + " in (?)"
+		if i == len(components)-1 {
+			where = "serial in (?)"
+		}
+		if err = tx.Where(where, keys).Delete(component).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestRetention(t *testing.T) {
+	db, cleanup := sqliteDB(t)
+	defer cleanup()
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chassis := range []*model.Chassis{
+		{Serial: "stale", Vendor: "HP", BmcAddress: "10.0.0.1", Psus: []*model.Psu{{Serial: "psu1", ChassisSerial: "stale"}},
+			Blades: []*model.Blade{{Serial: "blade1", ChassisSerial: "stale", Nics: []*model.Nic{{MacAddress: "a0:36:9f:00:00:01", BladeSerial: "blade1"}}}}},
+		{Serial: "fresh", Vendor: "HP", BmcAddress: "10.0.0.2"},
+	} {
+		if _, err := NewChassisStorage(db).ApplySnapshot(chassis); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	port := &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: "open"}
+	if _, err := NewScannedPortStorage(db).Record(port, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().AddDate(0, 0, -100)
+	db.Model(&model.Chassis{}).Where("serial = ?", "stale").UpdateColumn("updated_at", old)
+	db.Model(&model.ScannedPort{}).Where("id = ?", port.ID).UpdateColumn("updated_at", old)
+	before := time.Now().AddDate(0, 0, -90)
+
+	counts, err := CountStale(db, RetentionChassis, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0] != (StaleCount{Site: "ams4", Vendor: "HP", Total: 1}) {
+		t.Errorf("expected one stale hp chassis in ams4, got %+v", counts)
+	}
+
+	if counts, err = CountStale(db, RetentionScannedPorts, before); err != nil || len(counts) != 1 || counts[0].Total != 1 {
+		t.Errorf("expected one stale scanned port, got %+v: %v", counts, err)
+	}
+
+	var archive bytes.Buffer
+	purged, err := PurgeStale(db, RetentionChassis, before, &archive)
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one chassis purged, got %d: %v", purged, err)
+	}
+
+	for _, value := range []interface{}{&model.Chassis{Serial: "stale"}, &model.Blade{Serial: "blade1"}, &model.Psu{Serial: "psu1"}, &model.Nic{MacAddress: "a0:36:9f:00:00:01"}} {
+		var count int
+		db.Unscoped().Model(value).Where(value).Count(&count)
+		if count != 0 {
+			t.Errorf("expected %T to be purged", value)
+		}
+	}
+
+	if count, _ := NewChassisStorage(db).Count(&filter.Filters{}); count != 1 {
+		t.Errorf("expected the fresh chassis to be kept, got %d chassis", count)
+	}
+
+	// the archive brings the purged chassis back with its components
+	imported, err := Import(db, &archive)
+	if err != nil || imported["chassis"] != 1 {
+		t.Fatalf("expected the archived chassis to be imported, got %v: %v", imported, err)
+	}
+	chassis, err := NewChassisStorage(db).GetOne("stale")
+	if err != nil || len(chassis.Blades) != 1 || len(chassis.Blades[0].Nics) != 1 || len(chassis.Psus) != 1 {
+		t.Errorf("expected the chassis to come back with its components, got %+v: %v", chassis, err)
+	}
+
+	if purged, err = PurgeStale(db, RetentionScannedPorts, before, nil); err != nil || purged != 1 {
+		t.Errorf("expected one scanned port purged, got %d: %v", purged, err)
+	}
+	var changes int
+	if db.Model(&model.PortStateChange{}).Count(&changes); changes != 0 {
+		t.Errorf("expected the state changes to go with their port, got %d", changes)
+	}
+}
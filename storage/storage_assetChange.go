@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewAssetChangeStorage initializes the storage
+func NewAssetChangeStorage(db *gorm.DB) *AssetChangeStorage {
+	return &AssetChangeStorage{db}
+}
+
+// AssetChangeStorage stores the changes found on each collection
+type AssetChangeStorage struct {
+	db *gorm.DB
+}
+
+// Count get AssetChanges count based on the filter
+func (a AssetChangeStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.AssetChange{}, a.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.AssetChange{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the AssetChanges, newest first
+func (a AssetChangeStorage) GetAll(offset string, limit string) (count int, changes []model.AssetChange, err error) {
+	if offset != "" && limit != "" {
+		if err = a.db.Limit(limit).Offset(offset).Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+		a.db.Model(&model.AssetChange{}).Count(&count)
+	} else {
+		if err = a.db.Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+	}
+	return count, changes, err
+}
+
+// GetAllByFilters get all AssetChanges based on the filter, newest first
+func (a AssetChangeStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, changes []model.AssetChange, err error) {
+	q, err := filters.BuildQuery(model.AssetChange{}, a.db)
+	if err != nil {
+		return count, changes, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+		q.Model(&model.AssetChange{}).Count(&count)
+	} else {
+		if err = q.Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+	}
+
+	return count, changes, err
+}
+
+// GetOne AssetChange
+func (a AssetChangeStorage) GetOne(id string) (change model.AssetChange, err error) {
+	if err := a.db.Where("id = ?", id).First(&change).Error; err != nil {
+		return change, err
+	}
+	return change, err
+}
+
+// Create stores a new AssetChange
+func (a *AssetChangeStorage) Create(change *model.AssetChange) (id string, err error) {
+	if err = a.db.Create(change).Error; err != nil {
+		return id, err
+	}
+	return change.ID, nil
+}
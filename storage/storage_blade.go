@@ -22,6 +22,11 @@ type BladeStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the blades marked as removed
+func (b BladeStorage) Unscoped() *BladeStorage {
+	return &BladeStorage{b.db.Unscoped()}
+}
+
 // Count get blades count based on the filter
 func (b BladeStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.Blade{}, b.db)
@@ -168,7 +173,7 @@ func (b *BladeStorage) UpdateOrCreate(blade *model.Blade) (serial string, err er
 	return blade.Serial, nil
 }
 
-// RemoveOldDiskRefs deletes all the old references from Nics that used to be inside of the chassis
+// RemoveOldDiskRefs marks as removed all the old references from Disks that used to be inside of the blade
 func (b *BladeStorage) RemoveOldDiskRefs(blade *model.Blade) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, disk := range blade.Disks {
@@ -179,6 +184,10 @@ func (b *BladeStorage) RemoveOldDiskRefs(blade *model.Blade) (count int, serials
 		return count, serials, err
 	}
 
+	if err = guardRemoval(b.db, blade.Serial, "disks", len(blade.Disks), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = b.db.Where("serial in (?) and blade_serial = ?", serials, blade.Serial).Delete(model.Disk{}).Error; err != nil {
 			return count, serials, err
@@ -188,7 +197,7 @@ func (b *BladeStorage) RemoveOldDiskRefs(blade *model.Blade) (count int, serials
 	return count, serials, err
 }
 
-// RemoveOldNicRefs deletes all the old references from Nics that used to be inside of the chassis
+// RemoveOldNicRefs marks as removed all the old references from Nics that used to be inside of the blade
 func (b *BladeStorage) RemoveOldNicRefs(blade *model.Blade) (count int, macAddresses []string, err error) {
 	var connectedMacAddresses []string
 	for _, nic := range blade.Nics {
@@ -199,6 +208,10 @@ func (b *BladeStorage) RemoveOldNicRefs(blade *model.Blade) (count int, macAddre
 		return count, macAddresses, err
 	}
 
+	if err = guardRemoval(b.db, blade.Serial, "nics", len(blade.Nics), count); err != nil {
+		return count, macAddresses, err
+	}
+
 	if count > 0 {
 		if err = b.db.Where("mac_address in (?) and blade_serial = ?", macAddresses, blade.Serial).Delete(model.Nic{}).Error; err != nil {
 			return count, macAddresses, err
@@ -208,7 +221,7 @@ func (b *BladeStorage) RemoveOldNicRefs(blade *model.Blade) (count int, macAddre
 	return count, macAddresses, err
 }
 
-// RemoveOldRefs deletes all the old references from all attached components
+// RemoveOldRefs marks as removed all the old references from all attached components
 func (b *BladeStorage) RemoveOldRefs(blade *model.Blade) (err error) {
 	var merror *multierror.Error
 	_, _, err = b.RemoveOldNicRefs(blade)
@@ -217,3 +230,47 @@ func (b *BladeStorage) RemoveOldRefs(blade *model.Blade) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// ReviveRefs clears removed_at on the blade and the components it reports again
+func (b *BladeStorage) ReviveRefs(blade *model.Blade) (err error) {
+	if err = revive(b.db, &model.Blade{}, "serial", []string{blade.Serial}); err != nil {
+		return err
+	}
+
+	var macAddresses, serials []string
+	for _, nic := range blade.Nics {
+		macAddresses = append(macAddresses, nic.MacAddress)
+	}
+	for _, disk := range blade.Disks {
+		serials = append(serials, disk.Serial)
+	}
+
+	if err = revive(b.db, &model.Nic{}, "mac_address", macAddresses); err != nil {
+		return err
+	}
+	return revive(b.db, &model.Disk{}, "serial", serials)
+}
+
+// ApplySnapshot stores the blade with all its components and removes the ones that are gone in a single transaction.
+// Removals refused by the guard are returned as a RemovalRefusedError once the rest is stored
+func (b *BladeStorage) ApplySnapshot(blade *model.Blade) (serial string, err error) {
+	var refused error
+	err = transaction(b.db, func(tx *gorm.DB) (err error) {
+		bladeStorage := NewBladeStorage(tx)
+		if err := bladeStorage.ReviveRefs(blade); err != nil {
+			return err
+		}
+
+		if _, err := bladeStorage.UpdateOrCreate(blade); err != nil {
+			return err
+		}
+
+		refused, err = splitRefusals(blade.Serial, bladeStorage.RemoveOldRefs(blade))
+		return err
+	})
+	if err != nil {
+		return serial, err
+	}
+
+	return blade.Serial, refused
+}
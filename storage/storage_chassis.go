@@ -102,7 +102,7 @@ func (c ChassisStorage) GetAllByPsusID(offset string, limit string, serials []st
 
 // GetOne Chassis
 func (c ChassisStorage) GetOne(serial string) (chassis model.Chassis, err error) {
-	if err = c.db.Where("serial = ?", serial).Preload("Blades").Preload("Blades.Nics").Preload("StorageBlades").Preload("Nics").Preload("Psus").First(&chassis).Error; err != nil {
+	if err = c.db.Where("serial = ?", serial).Preload("Blades").Preload("Blades.Nics").Preload("Blades.Disks").Preload("StorageBlades").Preload("Nics").Preload("Psus").Preload("Fans").First(&chassis).Error; err != nil {
 		return chassis, err
 	}
 	return chassis, err
@@ -182,7 +182,7 @@ func (c *ChassisStorage) UpdateOrCreate(chassis *model.Chassis) (serial string,
 	return chassis.Serial, nil
 }
 
-// RemoveOldBladesRefs deletes all the old references from StorageBlades that used to be inside of the chassis
+// RemoveOldBladesRefs marks as removed all the old references from Blades that used to be inside of the chassis
 func (c *ChassisStorage) RemoveOldBladesRefs(chassis *model.Chassis) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, blade := range chassis.Blades {
@@ -199,6 +199,10 @@ func (c *ChassisStorage) RemoveOldBladesRefs(chassis *model.Chassis) (count int,
 		}
 	}
 
+	if err = guardRemoval(c.db, chassis.Serial, "blades", len(chassis.Blades), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = c.db.Where("serial in (?) and chassis_serial = ?", serials, chassis.Serial).Delete(model.Blade{}).Error; err != nil {
 			return count, serials, err
@@ -208,7 +212,7 @@ func (c *ChassisStorage) RemoveOldBladesRefs(chassis *model.Chassis) (count int,
 	return count, serials, err
 }
 
-// RemoveOldStorageBladesRefs deletes all the old references from StorageBlades that used to be inside of the chassis
+// RemoveOldStorageBladesRefs marks as removed all the old references from StorageBlades that used to be inside of the chassis
 func (c *ChassisStorage) RemoveOldStorageBladesRefs(chassis *model.Chassis) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, blade := range chassis.StorageBlades {
@@ -225,6 +229,10 @@ func (c *ChassisStorage) RemoveOldStorageBladesRefs(chassis *model.Chassis) (cou
 		}
 	}
 
+	if err = guardRemoval(c.db, chassis.Serial, "storage_blades", len(chassis.StorageBlades), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = c.db.Where("serial in (?) and chassis_serial = ?", serials, chassis.Serial).Delete(model.StorageBlade{}).Error; err != nil {
 			return count, serials, err
@@ -234,7 +242,7 @@ func (c *ChassisStorage) RemoveOldStorageBladesRefs(chassis *model.Chassis) (cou
 	return count, serials, err
 }
 
-// RemoveOldNicRefs deletes all the old references from Nics that used to be inside of the chassis
+// RemoveOldNicRefs marks as removed all the old references from Nics that used to be inside of the chassis
 func (c *ChassisStorage) RemoveOldNicRefs(chassis *model.Chassis) (count int, macAddresses []string, err error) {
 	var connectedMacAddresses []string
 	for _, nic := range chassis.Nics {
@@ -251,6 +259,10 @@ func (c *ChassisStorage) RemoveOldNicRefs(chassis *model.Chassis) (count int, ma
 		}
 	}
 
+	if err = guardRemoval(c.db, chassis.Serial, "nics", len(chassis.Nics), count); err != nil {
+		return count, macAddresses, err
+	}
+
 	if count > 0 {
 		if err = c.db.Where("mac_address in (?) and chassis_serial = ?", macAddresses, chassis.Serial).Delete(model.Nic{}).Error; err != nil {
 			return count, macAddresses, err
@@ -260,7 +272,7 @@ func (c *ChassisStorage) RemoveOldNicRefs(chassis *model.Chassis) (count int, ma
 	return count, macAddresses, err
 }
 
-// RemoveOldPsuRefs deletes all the old references from Psus that used to be inside of the chassis
+// RemoveOldPsuRefs marks as removed all the old references from Psus that used to be inside of the chassis
 func (c *ChassisStorage) RemoveOldPsuRefs(chassis *model.Chassis) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, psu := range chassis.Psus {
@@ -277,6 +289,10 @@ func (c *ChassisStorage) RemoveOldPsuRefs(chassis *model.Chassis) (count int, se
 		}
 	}
 
+	if err = guardRemoval(c.db, chassis.Serial, "psus", len(chassis.Psus), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = c.db.Where("serial in (?) and chassis_serial = ?", serials, chassis.Serial).Delete(model.Psu{}).Error; err != nil {
 			return count, serials, err
@@ -286,11 +302,43 @@ func (c *ChassisStorage) RemoveOldPsuRefs(chassis *model.Chassis) (count int, se
 	return count, serials, err
 }
 
-// RemoveOldRefs deletes all the old references from all attached components
+// RemoveOldFanRefs marks as removed all the old references from Fans that used to be inside of the chassis
+func (c *ChassisStorage) RemoveOldFanRefs(chassis *model.Chassis) (count int, serials []string, err error) {
+	var connectedSerials []string
+	for _, fan := range chassis.Fans {
+		connectedSerials = append(connectedSerials, fan.Serial)
+	}
+
+	if len(chassis.Fans) == 0 {
+		if err = c.db.Model(&model.Fan{}).Where("serial is not null and chassis_serial = ?", chassis.Serial).Pluck("serial", &serials).Count(&count).Error; err != nil {
+			return count, serials, err
+		}
+	} else {
+		if err = c.db.Model(&model.Fan{}).Where("serial not in (?) and chassis_serial = ?", connectedSerials, chassis.Serial).Pluck("serial", &serials).Count(&count).Error; err != nil {
+			return count, serials, err
+		}
+	}
+
+	if err = guardRemoval(c.db, chassis.Serial, "fans", len(chassis.Fans), count); err != nil {
+		return count, serials, err
+	}
+
+	if count > 0 {
+		if err = c.db.Where("serial in (?) and chassis_serial = ?", serials, chassis.Serial).Delete(model.Fan{}).Error; err != nil {
+			return count, serials, err
+		}
+	}
+
+	return count, serials, err
+}
+
+// RemoveOldRefs marks as removed all the old references from all attached components
 func (c *ChassisStorage) RemoveOldRefs(chassis *model.Chassis) (err error) {
 	var merror *multierror.Error
 	_, _, err = c.RemoveOldPsuRefs(chassis)
 	merror = multierror.Append(merror, err)
+	_, _, err = c.RemoveOldFanRefs(chassis)
+	merror = multierror.Append(merror, err)
 	_, _, err = c.RemoveOldStorageBladesRefs(chassis)
 	merror = multierror.Append(merror, err)
 	_, _, err = c.RemoveOldNicRefs(chassis)
@@ -299,3 +347,69 @@ func (c *ChassisStorage) RemoveOldRefs(chassis *model.Chassis) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// ReviveRefs clears removed_at on the blades and components the chassis reports again
+func (c *ChassisStorage) ReviveRefs(chassis *model.Chassis) (err error) {
+	bladeStorage := NewBladeStorage(c.db)
+	for _, blade := range chassis.Blades {
+		if err = bladeStorage.ReviveRefs(blade); err != nil {
+			return err
+		}
+	}
+
+	var storageBlades, macAddresses, psus, fans []string
+	for _, storageBlade := range chassis.StorageBlades {
+		storageBlades = append(storageBlades, storageBlade.Serial)
+	}
+	for _, nic := range chassis.Nics {
+		macAddresses = append(macAddresses, nic.MacAddress)
+	}
+	for _, psu := range chassis.Psus {
+		psus = append(psus, psu.Serial)
+	}
+	for _, fan := range chassis.Fans {
+		fans = append(fans, fan.Serial)
+	}
+
+	if err = revive(c.db, &model.StorageBlade{}, "serial", storageBlades); err != nil {
+		return err
+	}
+	if err = revive(c.db, &model.Nic{}, "mac_address", macAddresses); err != nil {
+		return err
+	}
+	if err = revive(c.db, &model.Psu{}, "serial", psus); err != nil {
+		return err
+	}
+	return revive(c.db, &model.Fan{}, "serial", fans)
+}
+
+// ApplySnapshot stores the chassis with all its components and removes the ones that are gone in a single transaction.
+// Removals refused by the guard are returned as a RemovalRefusedError once the rest is stored
+func (c *ChassisStorage) ApplySnapshot(chassis *model.Chassis) (serial string, err error) {
+	var refused error
+	err = transaction(c.db, func(tx *gorm.DB) (err error) {
+		chassisStorage := NewChassisStorage(tx)
+		if err := chassisStorage.ReviveRefs(chassis); err != nil {
+			return err
+		}
+
+		if _, err := chassisStorage.UpdateOrCreate(chassis); err != nil {
+			return err
+		}
+
+		var merror *multierror.Error
+		bladeStorage := NewBladeStorage(tx)
+		for _, blade := range chassis.Blades {
+			merror = multierror.Append(merror, bladeStorage.RemoveOldRefs(blade))
+		}
+		merror = multierror.Append(merror, chassisStorage.RemoveOldRefs(chassis))
+
+		refused, err = splitRefusals(chassis.Serial, merror.ErrorOrNil())
+		return err
+	})
+	if err != nil {
+		return serial, err
+	}
+
+	return chassis.Serial, refused
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewCollectionAttemptStorage initializes the storage
+func NewCollectionAttemptStorage(db *gorm.DB) *CollectionAttemptStorage {
+	return &CollectionAttemptStorage{db}
+}
+
+// CollectionAttemptStorage stores the result of all collections
+type CollectionAttemptStorage struct {
+	db *gorm.DB
+}
+
+// Count get CollectionAttempts count based on the filter
+func (c CollectionAttemptStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.CollectionAttempt{}, c.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.CollectionAttempt{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the CollectionAttempts, newest first
+func (c CollectionAttemptStorage) GetAll(offset string, limit string) (count int, attempts []model.CollectionAttempt, err error) {
+	if offset != "" && limit != "" {
+		if err = c.db.Limit(limit).Offset(offset).Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+		c.db.Model(&model.CollectionAttempt{}).Count(&count)
+	} else {
+		if err = c.db.Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+	}
+	return count, attempts, err
+}
+
+// GetAllByFilters get all CollectionAttempts based on the filter, newest first
+func (c CollectionAttemptStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, attempts []model.CollectionAttempt, err error) {
+	q, err := filters.BuildQuery(model.CollectionAttempt{}, c.db)
+	if err != nil {
+		return count, attempts, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+		q.Model(&model.CollectionAttempt{}).Count(&count)
+	} else {
+		if err = q.Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+	}
+
+	return count, attempts, err
+}
+
+// GetOne CollectionAttempt
+func (c CollectionAttemptStorage) GetOne(id string) (attempt model.CollectionAttempt, err error) {
+	if err := c.db.Where("id = ?", id).First(&attempt).Error; err != nil {
+		return attempt, err
+	}
+	return attempt, err
+}
+
+// Create stores a new CollectionAttempt
+func (c *CollectionAttemptStorage) Create(attempt *model.CollectionAttempt) (id string, err error) {
+	if err = c.db.Create(attempt).Error; err != nil {
+		return id, err
+	}
+	return attempt.ID, nil
+}
+
+// LastWorkingCredential returns the name of the credential used in the last successful login to the asset with the
+// given serial, wherever its bmc was. Without a serial the logins to the ip that didn't read an asset are used, the
+// ones that did were to the asset the ip belonged to then
+func (c CollectionAttemptStorage) LastWorkingCredential(serial string, ip string) (name string, err error) {
+	query := c.db.Where("credential != ''")
+	if serial != "" {
+		query = query.Where("serial = ?", serial)
+	} else {
+		query = query.Where("ip = ? and serial = ''", ip)
+	}
+
+	attempt := model.CollectionAttempt{}
+	err = query.Order("started_at desc").First(&attempt).Error
+	if err == gorm.ErrRecordNotFound {
+		return name, nil
+	}
+	return attempt.Credential, err
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestLastWorkingCredential(t *testing.T) {
+	db, cleanup := sqliteDB(t)
+	defer cleanup()
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	attempts := []model.CollectionAttempt{
+		// the asset moved from 10.0.0.1 to 10.0.0.2 and another one took its address
+		{IP: "10.0.0.1", Source: "cli", StartedAt: start, Credential: "old", Serial: "cz3605020d"},
+		{IP: "10.0.0.2", Source: "cli", StartedAt: start.Add(time.Hour), Credential: "moved", Serial: "cz3605020d"},
+		{IP: "10.0.0.1", Source: "cli", StartedAt: start.Add(2 * time.Hour), Credential: "other", Serial: "cz3605020e"},
+		// a login that didn't read the asset
+		{IP: "10.0.0.3", Source: "cli", StartedAt: start, Credential: "unread"},
+		{IP: "10.0.0.3", Source: "cli", StartedAt: start.Add(time.Hour), Outcome: model.CollectionWrongCredentials},
+	}
+	attemptStorage := NewCollectionAttemptStorage(db)
+	for i := range attempts {
+		if _, err := attemptStorage.Create(&attempts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tt := []struct {
+		serial   string
+		ip       string
+		expected string
+	}{
+		{"cz3605020d", "10.0.0.2", "moved"},
+		{"cz3605020d", "10.0.0.1", "moved"},
+		{"", "10.0.0.1", ""},
+		{"", "10.0.0.3", "unread"},
+		{"unknown", "10.0.0.3", ""},
+	}
+	for _, tc := range tt {
+		name, err := attemptStorage.LastWorkingCredential(tc.serial, tc.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != tc.expected {
+			t.Errorf("serial %q ip %s: expected %q, got %q", tc.serial, tc.ip, tc.expected, name)
+		}
+	}
+}
@@ -153,7 +153,7 @@ func (d *DiscreteStorage) UpdateOrCreate(discrete *model.Discrete) (serial strin
 	return discrete.Serial, nil
 }
 
-// RemoveOldDiskRefs deletes all the old references from Nics that used to be inside of the chassis
+// RemoveOldDiskRefs marks as removed all the old references from Disks that used to be inside of the discrete
 func (d *DiscreteStorage) RemoveOldDiskRefs(discrete *model.Discrete) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, disk := range discrete.Disks {
@@ -164,6 +164,10 @@ func (d *DiscreteStorage) RemoveOldDiskRefs(discrete *model.Discrete) (count int
 		return count, serials, err
 	}
 
+	if err = guardRemoval(d.db, discrete.Serial, "disks", len(discrete.Disks), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = d.db.Where("serial in (?) and discrete_serial = ?", serials, discrete.Serial).Delete(model.Disk{}).Error; err != nil {
 			return count, serials, err
@@ -173,7 +177,7 @@ func (d *DiscreteStorage) RemoveOldDiskRefs(discrete *model.Discrete) (count int
 	return count, serials, err
 }
 
-// RemoveOldNicRefs deletes all the old references from Nics that used to be inside of the chassis
+// RemoveOldNicRefs marks as removed all the old references from Nics that used to be inside of the discrete
 func (d *DiscreteStorage) RemoveOldNicRefs(discrete *model.Discrete) (count int, macAddresses []string, err error) {
 	var connectedMacAddresses []string
 	for _, nic := range discrete.Nics {
@@ -184,6 +188,10 @@ func (d *DiscreteStorage) RemoveOldNicRefs(discrete *model.Discrete) (count int,
 		return count, macAddresses, err
 	}
 
+	if err = guardRemoval(d.db, discrete.Serial, "nics", len(discrete.Nics), count); err != nil {
+		return count, macAddresses, err
+	}
+
 	if count > 0 {
 		if err = d.db.Where("mac_address in (?) and discrete_serial = ?", macAddresses, discrete.Serial).Delete(model.Nic{}).Error; err != nil {
 			return count, macAddresses, err
@@ -193,7 +201,7 @@ func (d *DiscreteStorage) RemoveOldNicRefs(discrete *model.Discrete) (count int,
 	return count, macAddresses, err
 }
 
-// RemoveOldPsuRefs deletes all the old references from Psus that used to be inside of the chassis
+// RemoveOldPsuRefs marks as removed all the old references from Psus that used to be inside of the discrete
 func (d *DiscreteStorage) RemoveOldPsuRefs(discrete *model.Discrete) (count int, serials []string, err error) {
 	var connectedSerials []string
 	for _, psu := range discrete.Psus {
@@ -204,6 +212,10 @@ func (d *DiscreteStorage) RemoveOldPsuRefs(discrete *model.Discrete) (count int,
 		return count, serials, err
 	}
 
+	if err = guardRemoval(d.db, discrete.Serial, "psus", len(discrete.Psus), count); err != nil {
+		return count, serials, err
+	}
+
 	if count > 0 {
 		if err = d.db.Where("serial in (?) and discrete_serial = ?", serials, discrete.Serial).Delete(model.Psu{}).Error; err != nil {
 			return count, serials, err
@@ -213,7 +225,7 @@ func (d *DiscreteStorage) RemoveOldPsuRefs(discrete *model.Discrete) (count int,
 	return count, serials, err
 }
 
-// RemoveOldRefs deletes all the old references from all attached components
+// RemoveOldRefs marks as removed all the old references from all attached components
 func (d *DiscreteStorage) RemoveOldRefs(discrete *model.Discrete) (err error) {
 	var merror *multierror.Error
 	_, _, err = d.RemoveOldPsuRefs(discrete)
@@ -224,3 +236,49 @@ func (d *DiscreteStorage) RemoveOldRefs(discrete *model.Discrete) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// ReviveRefs clears removed_at on the components the discrete reports again
+func (d *DiscreteStorage) ReviveRefs(discrete *model.Discrete) (err error) {
+	var macAddresses, disks, psus []string
+	for _, nic := range discrete.Nics {
+		macAddresses = append(macAddresses, nic.MacAddress)
+	}
+	for _, disk := range discrete.Disks {
+		disks = append(disks, disk.Serial)
+	}
+	for _, psu := range discrete.Psus {
+		psus = append(psus, psu.Serial)
+	}
+
+	if err = revive(d.db, &model.Nic{}, "mac_address", macAddresses); err != nil {
+		return err
+	}
+	if err = revive(d.db, &model.Disk{}, "serial", disks); err != nil {
+		return err
+	}
+	return revive(d.db, &model.Psu{}, "serial", psus)
+}
+
+// ApplySnapshot stores the discrete with all its components and removes the ones that are gone in a single transaction.
+// Removals refused by the guard are returned as a RemovalRefusedError once the rest is stored
+func (d *DiscreteStorage) ApplySnapshot(discrete *model.Discrete) (serial string, err error) {
+	var refused error
+	err = transaction(d.db, func(tx *gorm.DB) (err error) {
+		discreteStorage := NewDiscreteStorage(tx)
+		if err := discreteStorage.ReviveRefs(discrete); err != nil {
+			return err
+		}
+
+		if _, err := discreteStorage.UpdateOrCreate(discrete); err != nil {
+			return err
+		}
+
+		refused, err = splitRefusals(discrete.Serial, discreteStorage.RemoveOldRefs(discrete))
+		return err
+	})
+	if err != nil {
+		return serial, err
+	}
+
+	return discrete.Serial, refused
+}
@@ -20,6 +20,11 @@ type DiskStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the disks marked as removed
+func (d DiskStorage) Unscoped() *DiskStorage {
+	return &DiskStorage{d.db.Unscoped()}
+}
+
 // Count get disks count based on the filter
 func (d DiskStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.Disk{}, d.db)
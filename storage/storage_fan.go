@@ -20,6 +20,11 @@ type FanStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the fans marked as removed
+func (f FanStorage) Unscoped() *FanStorage {
+	return &FanStorage{f.db.Unscoped()}
+}
+
 // Count get fans count based on the filter
 func (f FanStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.Fan{}, f.db)
@@ -20,6 +20,11 @@ type NicStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the nics marked as removed
+func (n NicStorage) Unscoped() *NicStorage {
+	return &NicStorage{n.db.Unscoped()}
+}
+
 // Count get nics count based on the filter
 func (n NicStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.Nic{}, n.db)
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewPortStateChangeStorage initializes the storage
+func NewPortStateChangeStorage(db *gorm.DB) *PortStateChangeStorage {
+	return &PortStateChangeStorage{db}
+}
+
+// PortStateChangeStorage stores the state changes found by the scanner
+type PortStateChangeStorage struct {
+	db *gorm.DB
+}
+
+// Count get PortStateChanges count based on the filter
+func (p PortStateChangeStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.PortStateChange{}, p.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.PortStateChange{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the PortStateChanges, newest first
+func (p PortStateChangeStorage) GetAll(offset string, limit string) (count int, changes []model.PortStateChange, err error) {
+	if offset != "" && limit != "" {
+		if err = p.db.Limit(limit).Offset(offset).Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+		p.db.Model(&model.PortStateChange{}).Count(&count)
+	} else {
+		if err = p.db.Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+	}
+	return count, changes, err
+}
+
+// GetAllByFilters get all PortStateChanges based on the filter, newest first
+func (p PortStateChangeStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, changes []model.PortStateChange, err error) {
+	q, err := filters.BuildQuery(model.PortStateChange{}, p.db)
+	if err != nil {
+		return count, changes, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+		q.Model(&model.PortStateChange{}).Count(&count)
+	} else {
+		if err = q.Order("changed_at desc").Find(&changes).Error; err != nil {
+			return count, changes, err
+		}
+	}
+
+	return count, changes, err
+}
+
+// GetOne PortStateChange
+func (p PortStateChangeStorage) GetOne(id string) (change model.PortStateChange, err error) {
+	if err := p.db.Where("id = ?", id).First(&change).Error; err != nil {
+		return change, err
+	}
+	return change, err
+}
@@ -20,6 +20,11 @@ type PsuStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the psus marked as removed
+func (p PsuStorage) Unscoped() *PsuStorage {
+	return &PsuStorage{p.db.Unscoped()}
+}
+
 // Count get psus count based on the filter
 func (p PsuStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.Psu{}, p.db)
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewReadingStorage initializes the storage
+func NewReadingStorage(db *gorm.DB) *ReadingStorage {
+	return &ReadingStorage{db}
+}
+
+// ReadingStorage stores the sensor history of the assets
+type ReadingStorage struct {
+	db *gorm.DB
+}
+
+// Count get Readings count based on the filter
+func (r ReadingStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.Reading{}, r.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.Reading{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the Readings, newest first
+func (r ReadingStorage) GetAll(offset string, limit string) (count int, readings []model.Reading, err error) {
+	if offset != "" && limit != "" {
+		if err = r.db.Limit(limit).Offset(offset).Order("collected_at desc").Find(&readings).Error; err != nil {
+			return count, readings, err
+		}
+		r.db.Model(&model.Reading{}).Count(&count)
+	} else {
+		if err = r.db.Order("collected_at desc").Find(&readings).Error; err != nil {
+			return count, readings, err
+		}
+	}
+	return count, readings, err
+}
+
+// GetAllByFilters get all Readings based on the filter, newest first
+func (r ReadingStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, readings []model.Reading, err error) {
+	q, err := filters.BuildQuery(model.Reading{}, r.db)
+	if err != nil {
+		return count, readings, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("collected_at desc").Find(&readings).Error; err != nil {
+			return count, readings, err
+		}
+		q.Model(&model.Reading{}).Count(&count)
+	} else {
+		if err = q.Order("collected_at desc").Find(&readings).Error; err != nil {
+			return count, readings, err
+		}
+	}
+
+	return count, readings, err
+}
+
+// GetOne Reading
+func (r ReadingStorage) GetOne(id string) (reading model.Reading, err error) {
+	if err := r.db.Where("id = ?", id).First(&reading).Error; err != nil {
+		return reading, err
+	}
+	return reading, err
+}
+
+// Append stores the readings of a collection in a single transaction
+func (r *ReadingStorage) Append(readings []*model.Reading) (err error) {
+	if len(readings) == 0 {
+		return nil
+	}
+
+	return transaction(r.db, func(tx *gorm.DB) error {
+		for _, reading := range readings {
+			if err := tx.Create(reading).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Series returns the readings of a metric of the asset collected between from and to, oldest first
+func (r ReadingStorage) Series(serial string, metric string, from time.Time, to time.Time) (readings []model.Reading, err error) {
+	err = r.db.Where("asset_serial = ? and metric = ? and collected_at >= ? and collected_at <= ?", serial, metric, from, to).Order("collected_at").Find(&readings).Error
+	return readings, err
+}
+
+// Downsample replaces the raw readings collected before the given time by their average over each resolution period,
+// one transaction per asset. It returns the number of raw readings replaced
+func (r *ReadingStorage) Downsample(before time.Time, resolution time.Duration) (replaced int, err error) {
+	// Only whole periods are averaged, otherwise a period would end up with an average and raw readings
+	before = before.Truncate(resolution)
+
+	var serials []string
+	if err = r.db.Model(&model.Reading{}).Where("resolution = 0 and collected_at < ?", before).Pluck("distinct(asset_serial)", &serials).Error; err != nil {
+		return replaced, err
+	}
+
+	for _, serial := range serials {
+		err = transaction(r.db, func(tx *gorm.DB) error {
+			var raw []model.Reading
+			q := tx.Where("asset_serial = ? and resolution = 0 and collected_at < ?", serial, before)
+			if err := q.Order("collected_at").Find(&raw).Error; err != nil {
+				return err
+			}
+
+			var averages []*model.Reading
+			periods := make(map[string]*model.Reading)
+			for _, reading := range raw {
+				start := reading.CollectedAt.Truncate(resolution)
+				key := fmt.Sprintf("%s-%s-%d", reading.AssetType, reading.Metric, start.UnixNano())
+				average, ok := periods[key]
+				if !ok {
+					average = &model.Reading{
+						AssetSerial: reading.AssetSerial,
+						AssetType:   reading.AssetType,
+						Metric:      reading.Metric,
+						Resolution:  int(resolution.Seconds()),
+						CollectedAt: start,
+					}
+					periods[key] = average
+					averages = append(averages, average)
+				}
+				average.Value += reading.Value * float64(reading.Samples)
+				average.Samples += reading.Samples
+			}
+
+			if err := q.Delete(model.Reading{}).Error; err != nil {
+				return err
+			}
+
+			for _, average := range averages {
+				average.Value = average.Value / float64(average.Samples)
+				if err := tx.Create(average).Error; err != nil {
+					return err
+				}
+			}
+
+			replaced += len(raw)
+			return nil
+		})
+		if err != nil {
+			return replaced, err
+		}
+	}
+
+	return replaced, nil
+}
+
+// Prune removes the readings collected before the given time
+func (r *ReadingStorage) Prune(before time.Time) (removed int64, err error) {
+	q := r.db.Where("collected_at < ?", before).Delete(model.Reading{})
+	return q.RowsAffected, q.Error
+}
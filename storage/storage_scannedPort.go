@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"net"
+	"time"
+
 	"github.com/bmc-toolbox/dora/filter"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/jinzhu/gorm"
@@ -70,3 +73,119 @@ func (s ScannedPortStorage) GetOne(id string) (scan model.ScannedPort, err error
 	}
 	return scan, err
 }
+
+// ScanResult is the state a scan found a port in
+type ScanResult struct {
+	Port      *model.ScannedPort
+	ScannedAt time.Time
+}
+
+// scannedPortColumns are the columns written by RecordBatch, the key first
+var scannedPortColumns = []string{"id", "site", "cidr", "ip", "port", "protocol", "scanned_by", "state", "first_seen_at", "last_open_at", "last_closed_at", "updated_at"}
+
+// portStateChangeColumns are the columns of the changes written by RecordBatch
+var portStateChangeColumns = []string{"id", "scanned_port_id", "site", "cidr", "ip", "port", "protocol", "scanned_by", "previous_state", "state", "changed_at"}
+
+// Record stores the result of a scan. It keeps when the port was first seen open and when it was last open or
+// closed, and appends a PortStateChange when the state differs from the previous scan, which it returns
+func (s ScannedPortStorage) Record(port *model.ScannedPort, scannedAt time.Time) (change *model.PortStateChange, err error) {
+	changes, err := s.RecordBatch([]ScanResult{{Port: port, ScannedAt: scannedAt}})
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	return changes[0], nil
+}
+
+// RecordBatch stores the results of many scans like Record does, in a transaction that reads the previous states
+// with one query and writes the ports and their changes with multi row upserts. The ports are updated with what
+// was stored and the changes are returned
+func (s ScannedPortStorage) RecordBatch(results []ScanResult) (changes []*model.PortStateChange, err error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		port := result.Port
+		// an ipv6 address can be written in several ways, it's always stored the same one
+		if ip := net.ParseIP(port.IP); ip != nil {
+			port.IP = ip.String()
+		}
+		if _, network, err := net.ParseCIDR(port.CIDR); err == nil {
+			port.CIDR = network.String()
+		}
+		port.ID = port.GenID()
+		ids = append(ids, port.ID)
+	}
+
+	err = transaction(s.db, func(tx *gorm.DB) error {
+		previous := make(map[string]model.ScannedPort, len(results))
+		for _, chunk := range chunks(ids, maxParameters(tx)) {
+			var stored []model.ScannedPort
+			if err := tx.Where("id in (?)", chunk).Find(&stored).Error; err != nil {
+				return err
+			}
+			for _, port := range stored {
+				previous[port.ID] = port
+			}
+		}
+
+		// a port scanned twice in the batch is written once, as the last scan left it
+		rows := make([][]interface{}, 0, len(results))
+		written := make(map[string]int, len(results))
+		for _, result := range results {
+			port := result.Port
+			if change := track(port, previous[port.ID], result.ScannedAt); change != nil {
+				changes = append(changes, change)
+			}
+			previous[port.ID] = *port
+
+			row := []interface{}{port.ID, port.Site, port.CIDR, port.IP, port.Port, port.Protocol, port.ScannedBy, port.State, port.FirstSeenAt, port.LastOpenAt, port.LastClosedAt, port.UpdatedAt}
+			if i, ok := written[port.ID]; ok {
+				rows[i] = row
+				continue
+			}
+			written[port.ID] = len(rows)
+			rows = append(rows, row)
+		}
+
+		if err := upsert(tx, tx.NewScope(&model.ScannedPort{}).TableName(), scannedPortColumns, rows); err != nil {
+			return err
+		}
+
+		rows = make([][]interface{}, 0, len(changes))
+		for _, c := range changes {
+			rows = append(rows, []interface{}{c.ID, c.ScannedPortID, c.Site, c.CIDR, c.IP, c.Port, c.Protocol, c.ScannedBy, c.PreviousState, c.State, c.ChangedAt})
+		}
+		return insertRows(tx, tx.NewScope(&model.PortStateChange{}).TableName(), portStateChangeColumns, rows, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// track carries the timestamps of the previous scan of the port over, updates them with the scan and returns the
+// change of state, nil when there's none
+func track(port *model.ScannedPort, previous model.ScannedPort, scannedAt time.Time) *model.PortStateChange {
+	port.FirstSeenAt, port.LastOpenAt, port.LastClosedAt = previous.FirstSeenAt, previous.LastOpenAt, previous.LastClosedAt
+	port.UpdatedAt = scannedAt
+	switch port.State {
+	case model.PortOpen:
+		if port.FirstSeenAt == nil {
+			port.FirstSeenAt = &scannedAt
+		}
+		port.LastOpenAt = &scannedAt
+	case model.PortClosed:
+		port.LastClosedAt = &scannedAt
+	}
+
+	// most of the addresses of a subnet are closed from the first scan on, that isn't a change
+	if port.State == previous.State || (previous.State == "" && port.State != model.PortOpen) {
+		return nil
+	}
+
+	change := model.NewPortStateChange(port, previous.State, scannedAt)
+	change.ID = change.GenID()
+	return change
+}
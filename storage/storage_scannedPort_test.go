@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestRecordScan(t *testing.T) {
+	db, cleanup := sqliteDB(t)
+	defer cleanup()
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScannedPortStorage(db)
+	start := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	scans := []struct {
+		state        string
+		changed      bool
+		previous     string
+		firstSeenAt  int
+		lastOpenAt   int
+		lastClosedAt int
+	}{
+		// nothing answered yet, it's not news
+		{state: model.PortClosed, lastClosedAt: 1},
+		{state: model.PortClosed, lastClosedAt: 2},
+		{state: model.PortOpen, changed: true, previous: model.PortClosed, firstSeenAt: 3, lastOpenAt: 3, lastClosedAt: 2},
+		{state: model.PortOpen, firstSeenAt: 3, lastOpenAt: 4, lastClosedAt: 2},
+		{state: model.PortClosed, changed: true, previous: model.PortOpen, firstSeenAt: 3, lastOpenAt: 4, lastClosedAt: 5},
+		{state: model.PortOpen, changed: true, previous: model.PortClosed, firstSeenAt: 3, lastOpenAt: 6, lastClosedAt: 5},
+	}
+
+	at := func(hour int) *time.Time {
+		if hour == 0 {
+			return nil
+		}
+		t := start.Add(time.Duration(hour) * time.Hour)
+		return &t
+	}
+	same := func(a, b *time.Time) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && a.Equal(*b))
+	}
+
+	for i, scan := range scans {
+		port := &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: scan.state}
+		change, err := s.Record(port, *at(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if scan.changed != (change != nil) {
+			t.Errorf("scan %d: expected a change %v, got %+v", i+1, scan.changed, change)
+		} else if change != nil && (change.PreviousState != scan.previous || change.State != scan.state || !change.ChangedAt.Equal(*at(i + 1))) {
+			t.Errorf("scan %d: expected a change from %s to %s, got %+v", i+1, scan.previous, scan.state, change)
+		}
+
+		stored, err := s.GetOne(port.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !same(stored.FirstSeenAt, at(scan.firstSeenAt)) || !same(stored.LastOpenAt, at(scan.lastOpenAt)) || !same(stored.LastClosedAt, at(scan.lastClosedAt)) {
+			t.Errorf("scan %d: expected first seen %v, last open %v and last closed %v, got %v, %v and %v", i+1,
+				at(scan.firstSeenAt), at(scan.lastOpenAt), at(scan.lastClosedAt), stored.FirstSeenAt, stored.LastOpenAt, stored.LastClosedAt)
+		}
+	}
+
+	// the bmcs that went dark since the fourth scan
+	filters := &filter.Filters{}
+	filters.Add("state", []string{model.PortClosed}, "eq")
+	filters.Add("changed_at", []string{at(4).Format(time.RFC3339)}, "ge")
+	count, changes, err := NewPortStateChangeStorage(db).GetAllByFilters("", "", filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].IP != "10.0.0.1" || !changes[0].ChangedAt.Equal(*at(5)) {
+		t.Errorf("expected the port closed on the fifth scan, got %d: %+v", count, changes)
+	}
+
+	// the ipv6 addresses are stored the way the scanner writes them, whatever way they are given
+	for _, ip := range []string{"2001:DB8:0::0:1", "2001:db8::1"} {
+		port := &model.ScannedPort{Site: "ams4", CIDR: "2001:DB8::/64", IP: ip, Port: 443, Protocol: "tcp", ScannedBy: "test", State: model.PortOpen}
+		if _, err = s.Record(port, *at(7)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filters.Clean()
+	filters.Add("ip", []string{"2001:0db8::0001"}, "eq")
+	if count, ports, err := s.GetAllByFilters("", "", filters); err != nil || len(ports) != 1 || ports[0].IP != "2001:db8::1" || ports[0].CIDR != "2001:db8::/64" {
+		t.Errorf("expected one port of 2001:db8::1, got %d: %+v: %v", count, ports, err)
+	}
+}
+
+func TestRecordBatch(t *testing.T) {
+	db, cleanup := sqliteDB(t)
+	defer cleanup()
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same port scanned twice in a batch is stored as the last scan left it, with both changes
+	start := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	results := []ScanResult{
+		{Port: &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: model.PortOpen}, ScannedAt: start},
+		{Port: &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.2", Port: 443, Protocol: "tcp", ScannedBy: "test", State: model.PortClosed}, ScannedAt: start},
+		{Port: &model.ScannedPort{Site: "ams4", CIDR: "10.0.0.0/24", IP: "10.0.0.1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: model.PortClosed}, ScannedAt: start.Add(time.Hour)},
+	}
+	changes, err := NewScannedPortStorage(db).RecordBatch(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].State != model.PortOpen || changes[1].PreviousState != model.PortOpen || changes[1].State != model.PortClosed {
+		t.Errorf("expected the port to open and close, got %+v", changes)
+	}
+
+	port, err := NewScannedPortStorage(db).GetOne(results[0].Port.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port.State != model.PortClosed || !port.FirstSeenAt.Equal(start) || !port.LastOpenAt.Equal(start) || !port.LastClosedAt.Equal(start.Add(time.Hour)) || !port.UpdatedAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the port closed after being seen open, got %+v", port)
+	}
+	if count, _ := NewScannedPortStorage(db).Count(&filter.Filters{}); count != 2 {
+		t.Errorf("expected two ports, got %d", count)
+	}
+
+	if changes, err = NewScannedPortStorage(db).RecordBatch(nil); err != nil || changes != nil {
+		t.Errorf("expected an empty batch to do nothing, got %v: %v", changes, err)
+	}
+}
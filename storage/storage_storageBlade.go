@@ -20,6 +20,11 @@ type StorageBladeStorage struct {
 	db *gorm.DB
 }
 
+// Unscoped returns a storage that also sees the storage blades marked as removed
+func (b StorageBladeStorage) Unscoped() *StorageBladeStorage {
+	return &StorageBladeStorage{b.db.Unscoped()}
+}
+
 // Count get blades count based on the filter
 func (b StorageBladeStorage) Count(filters *filter.Filters) (count int, err error) {
 	q, err := filters.BuildQuery(model.StorageBlade{}, b.db)
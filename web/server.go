@@ -20,6 +20,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/bmc-toolbox/dora/internal/retention"
 	"github.com/bmc-toolbox/dora/internal/stats"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/bmc-toolbox/dora/resource"
@@ -84,6 +85,10 @@ func RunGin(port int, rodb bool, debug bool) {
 	psuStorage := storage.NewPsuStorage(db)
 	diskStorage := storage.NewDiskStorage(db)
 	fanStorage := storage.NewFanStorage(db)
+	collectionAttemptStorage := storage.NewCollectionAttemptStorage(db)
+	readingStorage := storage.NewReadingStorage(db)
+	assetChangeStorage := storage.NewAssetChangeStorage(db)
+	portStateChangeStorage := storage.NewPortStateChangeStorage(db)
 
 	stats := stats.Stats{StartTime: time.Now()}
 
@@ -117,6 +122,11 @@ func RunGin(port int, rodb bool, debug bool) {
 		diskStorage,
 		fanStorage)
 
+	// The read only database can't purge anything
+	if interval := time.Duration(viper.GetInt("retention.interval")) * time.Hour; interval > 0 && !rodb {
+		go metrics.Scheduler(interval, retention.Scheduled, db)
+	}
+
 	api.AddResource(model.Chassis{}, resource.ChassisResource{ChassisStorage: chassisStorage})
 	api.AddResource(model.Blade{}, resource.BladeResource{BladeStorage: bladeStorage})
 	api.AddResource(model.Discrete{}, resource.DiscreteResource{DiscreteStorage: discreteStorage})
@@ -126,6 +136,10 @@ func RunGin(port int, rodb bool, debug bool) {
 	api.AddResource(model.Psu{}, resource.PsuResource{PsuStorage: psuStorage})
 	api.AddResource(model.Disk{}, resource.DiskResource{DiskStorage: diskStorage})
 	api.AddResource(model.Fan{}, resource.FanResource{FanStorage: fanStorage})
+	api.AddResource(model.CollectionAttempt{}, resource.CollectionAttemptResource{CollectionAttemptStorage: collectionAttemptStorage})
+	api.AddResource(model.Reading{}, resource.ReadingResource{ReadingStorage: readingStorage})
+	api.AddResource(model.AssetChange{}, resource.AssetChangeResource{AssetChangeStorage: assetChangeStorage})
+	api.AddResource(model.PortStateChange{}, resource.PortStateChangeResource{PortStateChangeStorage: portStateChangeStorage})
 
 	r.POST("/api/v1/collect", func(c *gin.Context) {
 		subject := "dora::collect"
@@ -179,9 +193,18 @@ func RunGin(port int, rodb bool, debug bool) {
 					return
 				}
 
-				subnets := scanner.LoadSubnets(viper.GetString("scanner.subnet_source"), []string{network}, viper.GetStringSlice("site"))
-				subnet := subnets[0]
-				s, err := json.Marshal(subnet)
+				subnets, err := scanner.LoadSubnets([]string{network}, viper.GetStringSlice("site"))
+				if err != nil {
+					log.WithFields(log.Fields{"operation": "loading subnets"}).Error(err)
+					c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+					return
+				}
+				if len(subnets) == 0 {
+					c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("network %s isn't known by the subnet sources", network)})
+					return
+				}
+
+				s, err := json.Marshal(subnets[0])
 				if err != nil {
 					log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": subject, "operation": "encoding subnet"}).Error(err)
 					c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
@@ -208,6 +231,33 @@ func RunGin(port int, rodb bool, debug bool) {
 		return
 	})
 
+	r.GET("/api/v1/series/:serial/:metric", func(c *gin.Context) {
+		var err error
+		to := time.Now()
+		if value := c.Query("to"); value != "" {
+			if to, err = time.Parse(time.RFC3339, value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid to: %s", value)})
+				return
+			}
+		}
+
+		from := to.Add(-24 * time.Hour)
+		if value := c.Query("from"); value != "" {
+			if from, err = time.Parse(time.RFC3339, value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid from: %s", value)})
+				return
+			}
+		}
+
+		readings, err := readingStorage.Series(c.Param("serial"), c.Param("metric"), from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"serial": c.Param("serial"), "metric": c.Param("metric"), "from": from, "to": to, "readings": readings})
+	})
+
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(200, "doc.tmpl", gin.H{})
 	})